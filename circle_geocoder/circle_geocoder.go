@@ -0,0 +1,34 @@
+package circle_geocoder
+
+import (
+	"log"
+	"time"
+
+	"github.com/dxe/adb/geocode"
+	"github.com/dxe/adb/model"
+	"github.com/jmoiron/sqlx"
+)
+
+// At most this many addresses are looked up per run, to stay within
+// the geocoding quota.
+const batchSize = 50
+
+func geocodeProspects(db *sqlx.DB) {
+	n, err := model.GeocodeCircleProspects(db, geocode.Address, batchSize)
+	if err != nil {
+		log.Println("ERROR: failed to geocode circle prospects:", err)
+	}
+	log.Printf("Geocoded %d circle prospect addresses", n)
+}
+
+// Looks up the addresses of activists interested in joining a circle,
+// a batch every hour, so circle matches don't have to wait for them.
+// Should be run in a goroutine.
+func StartCircleProspectGeocoding(db *sqlx.DB) {
+	for {
+		log.Println("Starting circle prospect geocoding")
+		geocodeProspects(db)
+		log.Println("Finished circle prospect geocoding")
+		time.Sleep(time.Hour)
+	}
+}
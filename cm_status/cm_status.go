@@ -0,0 +1,106 @@
+package cm_status
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dxe/adb/config"
+	"github.com/dxe/adb/model"
+	"github.com/dxe/adb/mpi"
+	"github.com/jmoiron/sqlx"
+	"github.com/sourcegraph/go-ses"
+)
+
+func warningsEnabled() bool {
+	return config.CMWarningFromEmail != "" && config.AWSAccessKey != "" && config.AWSSecretKey != "" && config.AWSSESEndpoint != ""
+}
+
+func retentionRule() model.CMRetentionRule {
+	return model.CMRetentionRule{
+		MinMonths: config.CMRetentionMinMonths,
+		Window:    config.CMRetentionWindowMonths,
+	}
+}
+
+// levelWithArticle returns "a Chapter Member" or "an Organizer".
+func levelWithArticle(level string) string {
+	if level != "" && strings.ContainsAny(level[:1], "AEIOU") {
+		return "an " + level
+	}
+	return "a " + level
+}
+
+func sendWarning(item model.CMStatusReviewItem, rule model.CMRetentionRule) error {
+	subject := "Your DxE " + strings.ToLower(item.CurrentLevel) + " status"
+	bodyText := fmt.Sprintf("Hi %s,\n\n"+
+		"To stay %s, you need to be MPI in at least %d of the past %d months. "+
+		"You haven't been MPI in enough of them, so you're at risk of losing your %s status. "+
+		"Please come to an event this month, or reply to this email if you have any questions.\n",
+		item.Name, levelWithArticle(item.CurrentLevel), rule.MinMonths, rule.Window, item.CurrentLevel)
+	bodyHtml := fmt.Sprintf("<p>Hi %s,</p>"+
+		"<p>To stay %s, you need to be MPI in at least %d of the past %d months. "+
+		"You haven't been MPI in enough of them, so you're at risk of losing your %s status. "+
+		"Please come to an event this month, or reply to this email if you have any questions.</p>",
+		html.EscapeString(item.Name), levelWithArticle(item.CurrentLevel), rule.MinMonths, rule.Window, item.CurrentLevel)
+	// EnvConfig uses the AWS credentials in the environment
+	// variables $AWS_ACCESS_KEY_ID and $AWS_SECRET_KEY.
+	_, err := ses.EnvConfig.SendEmailHTML(config.CMWarningFromEmail, item.Email, subject, bodyText, bodyHtml)
+	return err
+}
+
+func sendWarnings(db *sqlx.DB, review model.CMStatusReview, rule model.CMRetentionRule) {
+	for _, item := range review.Items {
+		if item.Status != model.CMStatusWarn || item.WarningSent {
+			continue
+		}
+		if item.Email == "" {
+			log.Printf("Not warning activist %d: no email address", item.ActivistID)
+			continue
+		}
+		if err := sendWarning(item, rule); err != nil {
+			log.Printf("ERROR: failed to send warning to activist %d: %v", item.ActivistID, err)
+			continue
+		}
+		if err := model.MarkCMWarningSent(db, item, time.Now()); err != nil {
+			log.Println("ERROR:", err)
+		}
+	}
+}
+
+func reviewThisMonth(db *sqlx.DB, rule model.CMRetentionRule) {
+	month := mpi.MonthOf(time.Now())
+	review, created, err := model.CreateCMStatusReview(db, month, rule)
+	if err != nil {
+		log.Println("ERROR: failed to review chapter members:", err)
+		return
+	}
+	if created {
+		log.Printf("Reviewed chapter members for %d: %d need attention", month, len(review.Items))
+	}
+	// Warnings that failed to send are retried on the next run.
+	if warningsEnabled() {
+		sendWarnings(db, review, rule)
+	}
+}
+
+// Reviews chapter members and organizers at the start of every month,
+// checking every day. Nothing is reviewed unless the retention rule is
+// configured. Warnings are only emailed if the warning sender is
+// configured too; level changes are left for an organizer to approve.
+// Should be run in a goroutine.
+func StartCMStatusReviews(db *sqlx.DB) {
+	rule := retentionRule()
+	if !rule.Enabled() {
+		log.Println("Not reviewing chapter members: no retention rule is configured")
+		return
+	}
+	for {
+		log.Println("Starting chapter member review")
+		reviewThisMonth(db, rule)
+		log.Println("Finished chapter member review")
+		time.Sleep(24 * time.Hour)
+	}
+}
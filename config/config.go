@@ -42,9 +42,33 @@ var (
 	SurveyMissingEmail = mustGetenv("SURVEY_MISSING_EMAIL", "", false)
 	SurveyFromEmail    = mustGetenv("SURVEY_FROM_EMAIL", "", false)
 
+	// For warning chapter members who stopped meeting MPI. Warnings
+	// are only sent if this is set, along with the AWS settings above.
+	CMWarningFromEmail = mustGetenv("CM_WARNING_FROM_EMAIL", "", false)
+
+	// Chapter members and organizers need MPI in at least
+	// CMRetentionMinMonths of the past CMRetentionWindowMonths full
+	// months to keep their level. This is chapter policy, so there's
+	// no default: chapter members aren't reviewed or warned unless
+	// both are set.
+	CMRetentionMinMonths    = mustGetenvInt("CM_RETENTION_MIN_MONTHS", 0)
+	CMRetentionWindowMonths = mustGetenvInt("CM_RETENTION_WINDOW_MONTHS", 0)
+
+	// For alerting SupportEmail when mailing list changes are held by
+	// a guardrail. Alerts are only sent if this is set, along with the
+	// AWS settings above.
+	MailingListAlertFromEmail = mustGetenv("MAILING_LIST_ALERT_FROM_EMAIL", "", false)
+
 	// for IP geolocation
 	IPGeolocationKey = mustGetenv("IPGEOLOCATION_KEY", "", false)
 
+	// For geocoding activist addresses when matching them to circles.
+	GoogleMapsKey = mustGetenv("GOOGLE_MAPS_API_KEY", "", false)
+
+	// /metrics requires this as a bearer token, and isn't served
+	// if it's empty.
+	MetricsToken = mustGetenv("METRICS_TOKEN", "", false)
+
 	// For members.dxesf.org
 	MembersClientID     = mustGetenv("MEMBERS_CLIENT_ID", "", false)
 	MembersClientSecret = mustGetenv("MEMBERS_CLIENT_SECRET", "", false)
@@ -54,6 +78,11 @@ var (
 	DiscordBotBaseUrl = mustGetenv("DISCORD_BOT_BASE_URL", "http://localhost:6070", false)
 	DiscordFromEmail  = mustGetenv("DISCORD_FROM_EMAIL", "", false)
 	SupportEmail      = mustGetenv("SUPPORT_EMAIL", "tech@dxe.io", false)
+
+	// Deleted events can be restored from the trash for this many
+	// days before they're purged. Must be positive, or events would
+	// be purged as soon as they're deleted.
+	ArchivedEventRetentionDays = mustGetenvPositiveInt("ARCHIVED_EVENT_RETENTION_DAYS", 30)
 )
 
 func mustGetenv(key, fallback string, mandatory bool) string {
@@ -68,6 +97,26 @@ func mustGetenv(key, fallback string, mandatory bool) string {
 	panic("Environment variable " + key + " cannot be empty")
 }
 
+func mustGetenvInt(key string, fallback int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		panic("Environment variable " + key + " must be a number")
+	}
+	return n
+}
+
+func mustGetenvPositiveInt(key string, fallback int) int {
+	n := mustGetenvInt(key, fallback)
+	if n <= 0 {
+		panic("Environment variable " + key + " must be greater than 0")
+	}
+	return n
+}
+
 func isEC2() bool {
 	// see http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/identify_ec2_instances.html
 	data, err := ioutil.ReadFile("/sys/hypervisor/uuid")
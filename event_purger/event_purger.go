@@ -0,0 +1,31 @@
+package event_purger
+
+import (
+	"log"
+	"time"
+
+	"github.com/dxe/adb/config"
+	"github.com/dxe/adb/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func purgeArchivedEvents(db *sqlx.DB) {
+	cutoff := time.Now().AddDate(0, 0, -config.ArchivedEventRetentionDays)
+	n, err := model.PurgeArchivedEvents(db, cutoff)
+	if err != nil {
+		log.Println("ERROR: failed to purge archived events:", err)
+		return
+	}
+	log.Printf("Purged %d events archived before %s", n, cutoff.Format(model.EventDateLayout))
+}
+
+// Permanently deletes events that have been in the trash for longer
+// than the retention period, once a day. Should be run in a goroutine.
+func StartEventPurger(db *sqlx.DB) {
+	for {
+		log.Println("Starting archived event purge")
+		purgeArchivedEvents(db)
+		log.Println("Finished archived event purge")
+		time.Sleep(24 * time.Hour)
+	}
+}
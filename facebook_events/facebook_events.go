@@ -7,11 +7,12 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/dxe/adb/metrics"
 	"github.com/dxe/adb/model"
 	"github.com/jmoiron/sqlx"
 )
 
-func getFacebookEvents(page model.FacebookPage) []model.FacebookEventJSON {
+func getFacebookEvents(run *metrics.JobRun, page model.FacebookPage) []model.FacebookEventJSON {
 	url := "https://graph.facebook.com/v4.0/" + strconv.Itoa(page.ID) + "/events?include_canceled=1&fields=name,start_time,end_time,cover,attending_count,description,place,interested_count,is_canceled,event_times,is_online&limit=50&access_token=" + page.Token
 
 	resp, err := http.Get(url)
@@ -22,6 +23,7 @@ func getFacebookEvents(page model.FacebookPage) []model.FacebookEventJSON {
 	// TODO: we should handle errors better so we don't stop all pages from syncing
 	if resp.StatusCode != http.StatusOK {
 		log.Println("ERROR getting events from", page.Name, err)
+		run.Fail()
 		return nil
 	}
 	// read the response & decode the json data
@@ -29,6 +31,7 @@ func getFacebookEvents(page model.FacebookPage) []model.FacebookEventJSON {
 	err = json.NewDecoder(resp.Body).Decode(&data)
 	if err != nil {
 		log.Println("ERROR getting events from", page.Name, err)
+		run.Fail()
 		return nil
 	}
 
@@ -57,9 +60,12 @@ func getFacebookEvent(page model.FacebookPage, eventID string) model.FacebookEve
 }
 
 func syncFacebookEvents(db *sqlx.DB) {
+	run := metrics.StartJobRun("facebook_sync")
+	defer run.Finish()
 	defer func() {
 		if r := recover(); r != nil {
 			log.Println("Recovered from panic in Facebook event sync", r)
+			run.Fail()
 		}
 	}()
 
@@ -67,6 +73,7 @@ func syncFacebookEvents(db *sqlx.DB) {
 	pages, err := model.GetFacebookPagesWithTokens(db)
 	if err != nil {
 		log.Println("ERROR:", err)
+		run.Fail()
 		return
 	}
 	if pages == nil {
@@ -80,7 +87,7 @@ func syncFacebookEvents(db *sqlx.DB) {
 		log.Println("Getting events from", page.Name, "(", page.ID, ")")
 
 		// make call to fb api
-		events := getFacebookEvents(page)
+		events := getFacebookEvents(run, page)
 
 		if len(events) > 0 {
 
@@ -96,7 +103,10 @@ func syncFacebookEvents(db *sqlx.DB) {
 						err = model.InsertFacebookEvent(db, subEventData, page)
 						if err != nil {
 							log.Println("ERROR:", err)
+							run.Fail()
+							continue
 						}
+						run.AddItems(1)
 					}
 					continue
 				}
@@ -104,7 +114,10 @@ func syncFacebookEvents(db *sqlx.DB) {
 				err = model.InsertFacebookEvent(db, event, page)
 				if err != nil {
 					log.Println("ERROR:", err)
+					run.Fail()
+					continue
 				}
+				run.AddItems(1)
 			}
 		} else {
 			log.Println("No events returned for", page.Name)
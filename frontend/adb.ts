@@ -3,6 +3,7 @@ import ActivistList from './ActivistList.vue';
 import CirclesList from './CirclesList.vue';
 import EventEdit from './EventEdit.vue';
 import EventList from './EventList.vue';
+import MyGroups from './MyGroups.vue';
 import UserList from './UserList.vue';
 import WorkingGroupList from './WorkingGroupList.vue';
 
@@ -13,6 +14,7 @@ new Vue({
     CirclesList,
     EventEdit,
     EventList,
+    MyGroups,
     UserList,
     WorkingGroupList,
   },
@@ -0,0 +1,67 @@
+// Package geocode looks up the coordinates of street addresses with
+// the Google Maps Geocoding API.
+package geocode
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dxe/adb/config"
+	"github.com/pkg/errors"
+)
+
+const geocodeURL = "https://maps.googleapis.com/maps/api/geocode/json"
+
+// ErrNotConfigured is returned if GOOGLE_MAPS_API_KEY isn't set.
+var ErrNotConfigured = errors.New("Geocoding API key not configured")
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Address returns the latitude and longitude of an address. found is
+// false if the address doesn't exist.
+func Address(address string) (lat, lng float64, found bool, err error) {
+	if config.GoogleMapsKey == "" {
+		return 0, 0, false, ErrNotConfigured
+	}
+
+	resp, err := client.Get(geocodeURL + "?" + url.Values{
+		"address": {address},
+		"key":     {config.GoogleMapsKey},
+	}.Encode())
+	if err != nil {
+		return 0, 0, false, errors.Wrap(err, "failed to geocode address")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, false, errors.Errorf("failed to geocode address: status %d", resp.StatusCode)
+	}
+
+	var data struct {
+		Status  string `json:"status"`
+		Results []struct {
+			Geometry struct {
+				Location struct {
+					Lat float64 `json:"lat"`
+					Lng float64 `json:"lng"`
+				} `json:"location"`
+			} `json:"geometry"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, 0, false, errors.Wrap(err, "failed to decode geocoding response")
+	}
+	switch data.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return 0, 0, false, nil
+	default:
+		return 0, 0, false, errors.Errorf("failed to geocode address: %s", data.Status)
+	}
+	if len(data.Results) == 0 {
+		return 0, 0, false, nil
+	}
+	location := data.Results[0].Geometry.Location
+	return location.Lat, location.Lng, true, nil
+}
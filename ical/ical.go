@@ -0,0 +1,146 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// Status values for VEVENT's STATUS property.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405Z"
+
+	// RFC 5545 section 3.1: lines SHOULD NOT be longer than 75
+	// octets, excluding the line break.
+	maxLineOctets = 75
+)
+
+type Calendar struct {
+	// Name is shown by most calendar clients as the name of the
+	// subscription.
+	Name   string
+	Events []Event
+}
+
+type Event struct {
+	// UID must be globally unique and must not change between
+	// fetches of the feed, otherwise clients will show duplicates.
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Status      string
+	Start       time.Time
+	End         time.Time
+	// AllDay events only use the date part of Start and End. End
+	// is exclusive, so a one day event ends on the following day.
+	AllDay       bool
+	LastModified time.Time
+}
+
+// Write encodes the calendar as an RFC 5545 iCalendar object.
+// Timed events are always written in UTC so that clients don't need
+// a VTIMEZONE definition to place them correctly.
+func Write(w io.Writer, cal Calendar, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Direct Action Everywhere//ADB//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+
+	stamp := now.UTC().Format(dateTimeLayout)
+	for _, e := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		if e.AllDay {
+			end := e.End
+			if !end.After(e.Start) {
+				end = e.Start.AddDate(0, 0, 1)
+			}
+			line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+			line("DTEND;VALUE=DATE", end.Format(dateLayout))
+		} else {
+			line("DTSTART", e.Start.UTC().Format(dateTimeLayout))
+			if e.End.After(e.Start) {
+				line("DTEND", e.End.UTC().Format(dateTimeLayout))
+			}
+		}
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Location != "" {
+			line("LOCATION", escapeText(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		status := e.Status
+		if status == "" {
+			status = StatusConfirmed
+		}
+		line("STATUS", status)
+		if !e.LastModified.IsZero() {
+			line("LAST-MODIFIED", e.LastModified.UTC().Format(dateTimeLayout))
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT property value per RFC 5545 section
+// 3.3.11.
+func escapeText(s string) string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeFolded writes a content line terminated by CRLF, folding it
+// onto continuation lines so that no line exceeds maxLineOctets. It
+// never splits a multi-byte UTF-8 character.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts
+		// towards its length.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,69 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = Write(&buf, Calendar{
+		Name: "DxE, SF Bay",
+		Events: []Event{{
+			UID:     "fb-1@example.com",
+			Summary: "Protest; march, rally",
+			Start:   time.Date(2020, 5, 2, 10, 0, 0, 0, pacific),
+			End:     time.Date(2020, 5, 2, 12, 0, 0, 0, pacific),
+			Status:  StatusCancelled,
+		}, {
+			UID:     "event-2@example.com",
+			Summary: "Chapter Meeting",
+			Start:   time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC),
+			AllDay:  true,
+		}},
+	}, now)
+	require.NoError(t, err)
+
+	out := buf.String()
+	require.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	require.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	require.Contains(t, out, "X-WR-CALNAME:DxE\\, SF Bay\r\n")
+	require.Contains(t, out, "SUMMARY:Protest\\; march\\, rally\r\n")
+	// Timed events are converted to UTC.
+	require.Contains(t, out, "DTSTART:20200502T170000Z\r\n")
+	require.Contains(t, out, "DTEND:20200502T190000Z\r\n")
+	require.Contains(t, out, "STATUS:CANCELLED\r\n")
+	// All day events end on the following day.
+	require.Contains(t, out, "DTSTART;VALUE=DATE:20200503\r\n")
+	require.Contains(t, out, "DTEND;VALUE=DATE:20200504\r\n")
+	require.Contains(t, out, "STATUS:CONFIRMED\r\n")
+	require.Equal(t, 2, strings.Count(out, "DTSTAMP:20200501T120000Z\r\n"))
+}
+
+func TestWriteFolded(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, Calendar{
+		Events: []Event{{
+			UID:         "long@example.com",
+			Summary:     "x",
+			Description: strings.Repeat("é", 100),
+			Start:       time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC),
+			AllDay:      true,
+		}},
+	}, time.Now())
+	require.NoError(t, err)
+
+	for _, l := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		require.True(t, len(l) <= maxLineOctets, "line too long: %q", l)
+	}
+	unfolded := strings.Replace(buf.String(), "\r\n ", "", -1)
+	require.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat("é", 100)+"\r\n")
+}
@@ -0,0 +1,51 @@
+package mailinglist_sync
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"github.com/dxe/adb/config"
+	"github.com/dxe/adb/model"
+	"github.com/sourcegraph/go-ses"
+)
+
+func alertsEnabled() bool {
+	return config.MailingListAlertFromEmail != "" && config.AWSAccessKey != "" && config.AWSSecretKey != "" && config.AWSSESEndpoint != ""
+}
+
+func sendHoldAlert(hold model.MailingListHold) error {
+	subject := fmt.Sprintf("Mailing list changes held for %s", hold.ListEmail)
+	bodyText := fmt.Sprintf("The ADB didn't sync %s because the changes tripped a guardrail: %s.\n\n"+
+		"It would add %d and remove %d members. "+
+		"Review the changes at %s/admin/mailing_lists and approve or reject them.\n\n"+
+		"Removals:\n%s\n",
+		hold.ListEmail, hold.Reason, len(hold.Additions), len(hold.Removals),
+		config.UrlPath, strings.Join(hold.Removals, "\n"))
+	bodyHtml := fmt.Sprintf("<p>The ADB didn't sync %s because the changes tripped a guardrail: %s.</p>"+
+		"<p>It would add %d and remove %d members. "+
+		`Review the changes at <a href="%s/admin/mailing_lists">the mailing lists page</a> and approve or reject them.</p>`+
+		"<p>Removals:<br />%s</p>",
+		html.EscapeString(hold.ListEmail), html.EscapeString(hold.Reason), len(hold.Additions), len(hold.Removals),
+		config.UrlPath, html.EscapeString(strings.Join(hold.Removals, "\n")))
+	bodyHtml = strings.Replace(bodyHtml, "\n", "<br />", -1)
+	// EnvConfig uses the AWS credentials in the environment
+	// variables $AWS_ACCESS_KEY_ID and $AWS_SECRET_KEY.
+	_, err := ses.EnvConfig.SendEmailHTML(config.MailingListAlertFromEmail, config.SupportEmail, subject, bodyText, bodyHtml)
+	return err
+}
+
+// sendHoldAlerts tells SupportEmail about newly held changes. Holds are
+// always logged, so they're visible even if alerts aren't configured.
+func sendHoldAlerts(holds []model.MailingListHold) {
+	for _, hold := range holds {
+		log.Printf("WARNING: held changes to %v: %v", hold.ListEmail, hold.Reason)
+		if !alertsEnabled() {
+			continue
+		}
+		if err := sendHoldAlert(hold); err != nil {
+			log.Printf("ERROR: failed to send alert about held changes to %v: %v", hold.ListEmail, err)
+		}
+	}
+}
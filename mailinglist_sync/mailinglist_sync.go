@@ -1,66 +1,16 @@
 package mailinglist_sync
 
 import (
-	"context"
-	"io/ioutil"
 	"log"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/dxe/adb/config"
 	"github.com/dxe/adb/model"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
-
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/admin/directory/v1"
 )
 
-func getAdminService() (*admin.Service, error) {
-	key, err := ioutil.ReadFile(config.SyncMailingListsConfigFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "Could not read google auth key")
-	}
-	oauthConfig, err := google.JWTConfigFromJSON(key, "https://www.googleapis.com/auth/admin.directory.group")
-	if err != nil {
-		return nil, errors.Wrap(err, "Could not read JWT config from google auth key")
-	}
-	oauthConfig.Subject = config.SyncMailingListsOauthSubject
-
-	client := oauthConfig.Client(context.Background())
-	adminService, err := admin.New(client)
-	if err != nil {
-		return nil, errors.Wrap(err, "Could not construct admin service")
-	}
-
-	return adminService, nil
-}
-
-func listMembers(adminService *admin.Service, groupEmail string) ([]string, error) {
-	var memberEmails []string
-	call := adminService.Members.List(groupEmail)
-	err := call.Pages(context.Background(), func(members *admin.Members) error {
-		for _, m := range members.Members {
-			memberEmails = append(memberEmails, m.Email)
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, errors.Wrapf(err, "Could not page members for group %s", groupEmail)
-	}
-	return memberEmails, nil
-}
-
-func insertMember(adminService *admin.Service, groupEmail, memberEmail string) error {
-	_, err := adminService.Members.Insert(groupEmail, &admin.Member{Email: memberEmail}).Do()
-	return errors.Wrapf(err, "Could not insert member %s into group %s ", memberEmail, groupEmail)
-}
-
-func removeMember(adminService *admin.Service, groupEmail, memberEmail string) error {
-	err := adminService.Members.Delete(groupEmail, memberEmail).Do()
-	return errors.Wrapf(err, "Could not delete member %s from group %s", memberEmail, groupEmail)
-}
-
 func normalizeEmail(s string) string {
 	return strings.ToLower(strings.TrimSpace(s))
 }
@@ -98,166 +48,213 @@ func getInsertAndRemoveEmails(target, current []string) (insertEmails, removeEma
 	return insertEmails, removeEmails
 }
 
-func syncMailingList(adminService *admin.Service, groupEmail string, memberEmails []string) {
-	listEmails, err := listMembers(adminService, groupEmail)
+// syncMailingList makes the members of the list memberEmails, unless
+// that trips one of the list's guardrails. Changes that trip one are
+// held until an admin approves them.
+func syncMailingList(run *syncRun, provider MailingListProvider, groupEmail string, guardrails model.MailingListGuardrails, memberEmails []string) {
+	// Record the list even if nothing changes, so admins can see it
+	// synced.
+	run.list(groupEmail)
+
+	listEmails, err := provider.ListMembers(groupEmail)
 	if err != nil {
 		// Don't continue processing if we can't get
 		// the members list.
-		log.Printf("Failed to list members of %v: %v", groupEmail, err)
+		run.failed(groupEmail, "", err)
 		return
 	}
 
 	insertEmails, removeEmails := getInsertAndRemoveEmails(memberEmails, listEmails)
+	if reason := guardrails.Check(len(listEmails), len(insertEmails), len(removeEmails)); reason != "" {
+		hold, ok := run.holds[groupEmail]
+		approved := ok && hold.Status == model.MailingListHoldApproved && hold.SameChanges(insertEmails, removeEmails)
+		if !approved {
+			run.held(groupEmail, insertEmails, removeEmails, reason)
+			return
+		}
+	}
+	run.release(groupEmail)
+
 	if len(insertEmails) != 0 || len(removeEmails) != 0 {
 		log.Printf("Syncing %v: +%q, -%q", groupEmail, insertEmails, removeEmails)
 	}
 
 	for _, e := range removeEmails {
-		err := removeMember(adminService, groupEmail, e)
+		err := provider.RemoveMember(groupEmail, e)
 		if err != nil {
-			log.Printf("Failed to remove %v from group %v: %v", e, groupEmail, err)
+			run.failed(groupEmail, e, err)
 			// Continue processing.
+			continue
 		}
+		run.removed(groupEmail, e)
 	}
 	for _, e := range insertEmails {
-		err := insertMember(adminService, groupEmail, e)
+		err := provider.AddMember(groupEmail, e)
 		if err != nil {
-			log.Printf("Failed to add %v to group %v: %v", e, groupEmail, err)
+			run.failed(groupEmail, e, err)
 			// Continue processing.
+			continue
 		}
+		run.added(groupEmail, e)
 	}
 }
 
-func syncWorkingGroupMailingLists(db *sqlx.DB, adminService *admin.Service) {
-	wgs, err := model.GetWorkingGroups(db, model.WorkingGroupQueryOptions{})
+func syncWorkingGroupMailingLists(run *syncRun, db *sqlx.DB, provider MailingListProvider) {
+	wgs, err := model.GetGroups(db, model.GroupQueryOptions{
+		Types: []string{model.GroupTypeWorkingGroup, model.GroupTypeCommittee},
+	})
 	if err != nil {
 		log.Printf("Failed to query working groups: %v", err)
+		run.Fail()
 		return
 	}
 
-	var groupEmails []string
-
 	for _, wg := range wgs {
 		var memberEmails []string
 		for _, m := range wg.Members {
+			// Observers follow the group in the ADB but don't get
+			// its emails.
+			if !m.OnMailingList() {
+				continue
+			}
 			email := normalizeEmail(m.ActivistEmail)
 			if email == "" {
-				log.Printf("Activist has no email, will not be synced to mailing list: %s\n", m.ActivistName)
+				run.skipped(wg.GroupEmail, m.ActivistName, "Activist has no email")
 				continue
 			}
 			memberEmails = append(memberEmails, email)
 		}
-		syncMailingList(adminService, wg.GroupEmail, memberEmails)
-
-		groupEmails = append(groupEmails, wg.GroupEmail)
+		syncMailingList(run, provider, wg.GroupEmail, wg.MailingListGuardrails, memberEmails)
 	}
-
-	// manually adding almira since she is the owner of group to approve messages
-	groupEmails = append(groupEmails, "almira@directactioneverywhere.com")
-	syncMailingList(adminService, "all-working-groups@directactioneverywhere.com", groupEmails)
 }
 
-func syncCircleHostMailingList(db *sqlx.DB, adminService *admin.Service) {
-	// Sync circlehosts@directactioneverywhere.com to contain all
-	// circle hosts.
-
-	circles, err := model.GetCircleGroups(db, model.CircleGroupQueryOptions{})
+// syncDefinedMailingLists syncs the lists defined in the mailing_lists
+// table.
+func syncDefinedMailingLists(run *syncRun, db *sqlx.DB, provider MailingListProvider) {
+	lists, err := model.GetMailingLists(db)
 	if err != nil {
-		log.Printf("Failed to query circles: %v", err)
+		log.Printf("Failed to query mailing lists: %v", err)
+		run.Fail()
 		return
 	}
 
-	// The circle host's email is used as the circle's group email.
-	var emails []string
-	for _, c := range circles {
-		email := normalizeEmail(c.GroupEmail)
-		if email == "" {
-			log.Printf("Circle has no email, will not be synced to mailing list: %s", c.Name)
+	for _, list := range lists {
+		targets, err := model.GetMailingListTargets(db, list)
+		if err != nil {
+			run.failed(list.Email, "", err)
 			continue
 		}
-		emails = append(emails, email)
+		for _, t := range targets {
+			if normalizeEmail(t.Email) == "" {
+				run.skipped(list.Email, t.Name, "No email")
+			}
+		}
+		syncMailingList(run, provider, list.Email, list.MailingListGuardrails, model.ApplyMailingListOverrides(list, targets))
 	}
+}
 
-	syncMailingList(adminService, "circlehosts@directactioneverywhere.com", emails)
+// SyncOptions configures a pass of the mailing lists sync.
+type SyncOptions struct {
+	// Set if an admin asked for the sync.
+	TriggeredByEmail string
+	// Record stores the run and any changes it held in the database,
+	// and sends alerts about the held changes. Runs against dry-run or
+	// fake providers shouldn't be recorded.
+	Record bool
 }
 
-func syncChapterMemberMailingList(db *sqlx.DB, adminService *admin.Service) {
-	// Sync chaptermembers@directactioneverywhere.com to contain all
-	// activists that are considered a Chapter Member; i.e. Activists that
-	// that have activist_level of "Chapter Member".
+// SyncMailingLists runs one pass of the mailing lists sync against
+// provider. Errors syncing a list are logged, and the other lists are
+// still synced.
+func SyncMailingLists(db *sqlx.DB, provider MailingListProvider, options SyncOptions) (err error) {
+	run := newSyncRun(options.TriggeredByEmail)
+	defer run.Finish()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered from panic in syncMailingLists", r)
+			run.Fail()
+		}
+		if options.Record {
+			// Save the holds first, so the recorded run shows
+			// whether they were saved.
+			if holdErr := model.SaveMailingListHolds(db, run.newHolds, run.releasedHolds); holdErr != nil {
+				// Don't alert, since the next run will hold the
+				// same changes again as if they were new.
+				log.Println("Failed to save held mailing list changes:", holdErr)
+				run.Fail()
+			} else {
+				sendHoldAlerts(run.newHolds)
+			}
+			if _, recordErr := model.RecordMailingListSyncRun(db, run.record()); recordErr != nil {
+				log.Println("Failed to record mailing lists sync:", recordErr)
+				run.Fail()
+			}
+		}
+		if run.Failed() {
+			err = errors.New("Some mailing lists failed to sync")
+		}
+	}()
 
-	members, err := model.GetChapterMembers(db)
+	holds, err := model.GetMailingListHolds(db)
 	if err != nil {
-		log.Printf("Failed to query chapters: %v", err)
-		return
+		// Without the holds, approved changes can't be told apart
+		// from new ones.
+		log.Println("Failed to query held mailing list changes:", err)
+		run.Fail()
+		return nil
 	}
-
-	var emails []string
-	for _, m := range members {
-		email := normalizeEmail(m.Email)
-		if email == "" {
-			log.Printf("Activist has no email, will not be synced to mailing list: %s", m.Name)
-			continue
-		}
-
-		emails = append(emails, email)
+	for _, h := range holds {
+		run.holds[h.ListEmail] = h
 	}
 
-	syncMailingList(adminService, "chaptermembers@directactioneverywhere.com", emails)
+	syncWorkingGroupMailingLists(run, db, provider)
+	syncDefinedMailingLists(run, db, provider)
+	return nil
 }
 
-func syncOrganizersMailingList(db *sqlx.DB, adminService *admin.Service) {
-	// Sync sfbay-organizers@directactioneverywhere.com to contain all
-	// activists that have activist_level of "Organizer"
+// triggers carries the emails of admins who asked for a sync to run
+// now. Only one request is kept while a sync is running.
+var triggers = make(chan string, 1)
 
-	members, err := model.GetOrganizers(db)
-	if err != nil {
-		log.Printf("Failed to query chapters: %v", err)
-		return
-	}
+// started is set once StartMailingListsSync is running.
+var started int32
 
-	var emails []string
-	for _, m := range members {
-		email := normalizeEmail(m.Email)
-		if email == "" {
-			log.Printf("Activist has no email, will not be synced to mailing list: %s", m.Name)
-			continue
-		}
-
-		emails = append(emails, email)
+// TriggerMailingListsSync asks the background sync to run now rather
+// than at its next scheduled time.
+func TriggerMailingListsSync(userEmail string) error {
+	if atomic.LoadInt32(&started) == 0 {
+		return errors.New("Mailing lists sync isn't configured on this server")
 	}
-
-	syncMailingList(adminService, "sfbay-organizers@directactioneverywhere.com", emails)
-}
-
-func syncMailingListsWrapper(db *sqlx.DB, adminService *admin.Service) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Println("Recovered from panic in syncMailingLists", r)
-		}
-	}()
-
-	syncWorkingGroupMailingLists(db, adminService)
-	syncCircleHostMailingList(db, adminService)
-	syncChapterMemberMailingList(db, adminService)
-	syncOrganizersMailingList(db, adminService)
+	select {
+	case triggers <- userEmail:
+	default:
+		// A sync is already waiting to run.
+	}
+	return nil
 }
 
-// Syncs the mailing list every 5 minutes. Should be run in a
-// goroutine.
+// Syncs the mailing list every 5 minutes, or when an admin triggers
+// it. Should be run in a goroutine.
 func StartMailingListsSync(db *sqlx.DB) {
-	adminService, err := getAdminService()
+	provider, err := NewGoogleProvider()
 	if err != nil {
 		// Just panic if we can't get an admin service so that
 		// we don't accidentally mess this up without
 		// realizing it.
 		panic(err)
 	}
+	atomic.StoreInt32(&started, 1)
 
+	triggeredBy := ""
 	for {
 		log.Println("Starting mailing lists sync")
-		syncMailingListsWrapper(db, adminService)
+		SyncMailingLists(db, provider, SyncOptions{TriggeredByEmail: triggeredBy, Record: true})
 		log.Println("Finished mailing lists sync")
-		time.Sleep(5 * time.Minute)
+
+		triggeredBy = ""
+		select {
+		case <-time.After(5 * time.Minute):
+		case triggeredBy = <-triggers:
+		}
 	}
 }
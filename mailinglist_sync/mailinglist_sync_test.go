@@ -1,8 +1,10 @@
 package mailinglist_sync
 
 import (
+	"sort"
 	"testing"
 
+	"github.com/dxe/adb/model"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,3 +43,75 @@ func stringArrayToMap(a []string) map[string]struct{} {
 	}
 	return m
 }
+
+func TestSyncMailingList(t *testing.T) {
+	provider := NewFakeProvider(map[string][]string{
+		"wg@example.org": {"alice@example.org", "old@example.org"},
+	})
+	run := newSyncRun("")
+
+	syncMailingList(run, provider, "wg@example.org", model.DefaultMailingListGuardrails, []string{"Alice@example.org", "bob@example.org"})
+	require.Equal(t, []string{"alice@example.org", "bob@example.org"}, provider.Members("wg@example.org"))
+	require.False(t, run.Failed())
+
+	provider.FailingLists["broken@example.org"] = true
+	syncMailingList(run, provider, "broken@example.org", model.DefaultMailingListGuardrails, []string{"alice@example.org"})
+	require.True(t, run.Failed())
+
+	record := run.record()
+	require.Len(t, record.Lists, 2)
+	require.Equal(t, "broken@example.org", record.Lists[0].ListEmail)
+	require.True(t, record.Lists[0].Failed)
+	require.Equal(t, 1, record.Lists[0].Count(model.MailingListSyncFailed))
+	require.False(t, record.Lists[1].Failed)
+	require.Equal(t, 1, record.Lists[1].Count(model.MailingListSyncAdded))
+	require.Equal(t, 1, record.Lists[1].Count(model.MailingListSyncRemoved))
+}
+
+func TestDryRunProvider(t *testing.T) {
+	fake := NewFakeProvider(map[string][]string{
+		"wg@example.org": {"old@example.org"},
+	})
+	run := newSyncRun("")
+
+	syncMailingList(run, NewDryRunProvider(fake), "wg@example.org", model.DefaultMailingListGuardrails, []string{"alice@example.org"})
+	require.Equal(t, []string{"old@example.org"}, fake.Members("wg@example.org"))
+	require.False(t, run.Failed())
+}
+
+func TestSyncMailingListGuardrails(t *testing.T) {
+	provider := NewFakeProvider(map[string][]string{
+		"wg@example.org": {"alice@example.org", "bob@example.org"},
+	})
+	run := newSyncRun("")
+
+	// Emptying the list is held.
+	syncMailingList(run, provider, "wg@example.org", model.DefaultMailingListGuardrails, nil)
+	require.Equal(t, []string{"alice@example.org", "bob@example.org"}, provider.Members("wg@example.org"))
+	require.Len(t, run.newHolds, 1)
+	hold := run.newHolds[0]
+	require.Equal(t, model.MailingListHoldPending, hold.Status)
+	require.Equal(t, []string{"alice@example.org", "bob@example.org"}, sortedStrings(hold.Removals))
+	require.Equal(t, 1, run.record().Lists[0].Count(model.MailingListSyncHeld))
+
+	// The same changes aren't held again while they await approval.
+	run = newSyncRun("")
+	run.holds[hold.ListEmail] = hold
+	syncMailingList(run, provider, "wg@example.org", model.DefaultMailingListGuardrails, nil)
+	require.Len(t, run.newHolds, 0)
+	require.Len(t, provider.Members("wg@example.org"), 2)
+
+	// Once approved, they're made and the hold is released.
+	hold.Status = model.MailingListHoldApproved
+	run = newSyncRun("")
+	run.holds[hold.ListEmail] = hold
+	syncMailingList(run, provider, "wg@example.org", model.DefaultMailingListGuardrails, nil)
+	require.Len(t, provider.Members("wg@example.org"), 0)
+	require.Equal(t, []string{"wg@example.org"}, run.releasedHolds)
+}
+
+func sortedStrings(a []string) []string {
+	out := append([]string{}, a...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,186 @@
+package mailinglist_sync
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/dxe/adb/config"
+	"github.com/pkg/errors"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/admin/directory/v1"
+)
+
+// MailingListProvider manages the members of mailing lists, which are
+// identified by their email.
+type MailingListProvider interface {
+	ListMembers(listEmail string) ([]string, error)
+	AddMember(listEmail, memberEmail string) error
+	RemoveMember(listEmail, memberEmail string) error
+}
+
+// googleProvider manages Google Groups through the Directory API.
+type googleProvider struct {
+	adminService *admin.Service
+}
+
+// NewGoogleProvider returns a provider for our Google Groups, using
+// the service account key in config.SyncMailingListsConfigFile.
+func NewGoogleProvider() (MailingListProvider, error) {
+	key, err := ioutil.ReadFile(config.SyncMailingListsConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read google auth key")
+	}
+	oauthConfig, err := google.JWTConfigFromJSON(key, "https://www.googleapis.com/auth/admin.directory.group")
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not read JWT config from google auth key")
+	}
+	oauthConfig.Subject = config.SyncMailingListsOauthSubject
+
+	client := oauthConfig.Client(context.Background())
+	adminService, err := admin.New(client)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not construct admin service")
+	}
+
+	return googleProvider{adminService: adminService}, nil
+}
+
+func (p googleProvider) ListMembers(groupEmail string) ([]string, error) {
+	var memberEmails []string
+	call := p.adminService.Members.List(groupEmail)
+	err := call.Pages(context.Background(), func(members *admin.Members) error {
+		for _, m := range members.Members {
+			memberEmails = append(memberEmails, m.Email)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not page members for group %s", groupEmail)
+	}
+	return memberEmails, nil
+}
+
+func (p googleProvider) AddMember(groupEmail, memberEmail string) error {
+	_, err := p.adminService.Members.Insert(groupEmail, &admin.Member{Email: memberEmail}).Do()
+	return errors.Wrapf(err, "Could not insert member %s into group %s ", memberEmail, groupEmail)
+}
+
+func (p googleProvider) RemoveMember(groupEmail, memberEmail string) error {
+	err := p.adminService.Members.Delete(groupEmail, memberEmail).Do()
+	return errors.Wrapf(err, "Could not delete member %s from group %s", memberEmail, groupEmail)
+}
+
+// FakeProvider keeps mailing lists in memory. It's meant for tests.
+type FakeProvider struct {
+	mu    sync.Mutex
+	lists map[string]map[string]bool
+	// Lists that fail to be listed, or to have members added or
+	// removed.
+	FailingLists map[string]bool
+}
+
+// NewFakeProvider returns a fake provider with the given lists and
+// members. Lists that aren't given start out empty.
+func NewFakeProvider(lists map[string][]string) *FakeProvider {
+	p := &FakeProvider{
+		lists:        map[string]map[string]bool{},
+		FailingLists: map[string]bool{},
+	}
+	for list, members := range lists {
+		for _, m := range members {
+			p.list(list)[m] = true
+		}
+	}
+	return p
+}
+
+func (p *FakeProvider) list(listEmail string) map[string]bool {
+	if p.lists[listEmail] == nil {
+		p.lists[listEmail] = map[string]bool{}
+	}
+	return p.lists[listEmail]
+}
+
+// Lists returns the emails of the lists, sorted.
+func (p *FakeProvider) Lists() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lists := []string{}
+	for l := range p.lists {
+		lists = append(lists, l)
+	}
+	sort.Strings(lists)
+	return lists
+}
+
+// Members returns the members of a list, sorted.
+func (p *FakeProvider) Members(listEmail string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	members := []string{}
+	for m := range p.lists[listEmail] {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func (p *FakeProvider) ListMembers(listEmail string) ([]string, error) {
+	p.mu.Lock()
+	failing := p.FailingLists[listEmail]
+	p.mu.Unlock()
+	if failing {
+		return nil, errors.Errorf("Could not list members of %s", listEmail)
+	}
+	return p.Members(listEmail), nil
+}
+
+func (p *FakeProvider) AddMember(listEmail, memberEmail string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.FailingLists[listEmail] {
+		return errors.Errorf("Could not insert member %s into group %s", memberEmail, listEmail)
+	}
+	p.list(listEmail)[memberEmail] = true
+	return nil
+}
+
+func (p *FakeProvider) RemoveMember(listEmail, memberEmail string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.FailingLists[listEmail] {
+		return errors.Errorf("Could not delete member %s from group %s", memberEmail, listEmail)
+	}
+	delete(p.list(listEmail), memberEmail)
+	return nil
+}
+
+// dryRunProvider reads the members of lists from another provider, but
+// only logs the members it would add or remove.
+type dryRunProvider struct {
+	provider MailingListProvider
+}
+
+// NewDryRunProvider returns a provider that lists members using
+// provider and logs changes instead of making them.
+func NewDryRunProvider(provider MailingListProvider) MailingListProvider {
+	return dryRunProvider{provider: provider}
+}
+
+func (p dryRunProvider) ListMembers(listEmail string) ([]string, error) {
+	return p.provider.ListMembers(listEmail)
+}
+
+func (p dryRunProvider) AddMember(listEmail, memberEmail string) error {
+	log.Printf("Dry run: would add %v to %v", memberEmail, listEmail)
+	return nil
+}
+
+func (p dryRunProvider) RemoveMember(listEmail, memberEmail string) error {
+	log.Printf("Dry run: would remove %v from %v", memberEmail, listEmail)
+	return nil
+}
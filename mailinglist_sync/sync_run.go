@@ -0,0 +1,141 @@
+package mailinglist_sync
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/dxe/adb/metrics"
+	"github.com/dxe/adb/model"
+)
+
+// syncRun records what a pass of the sync does to each mailing list,
+// so that it can be stored and reviewed by admins.
+type syncRun struct {
+	*metrics.JobRun
+	started          time.Time
+	triggeredByEmail string
+	lists            map[string]*model.MailingListSyncList
+	// Held changes as of the start of the run, by list.
+	holds map[string]model.MailingListHold
+	// Holds that the run created, and lists whose holds it released.
+	newHolds      []model.MailingListHold
+	releasedHolds []string
+}
+
+func newSyncRun(triggeredByEmail string) *syncRun {
+	return &syncRun{
+		JobRun:           metrics.StartJobRun("mailing_list_sync"),
+		started:          time.Now(),
+		triggeredByEmail: triggeredByEmail,
+		lists:            map[string]*model.MailingListSyncList{},
+		holds:            map[string]model.MailingListHold{},
+	}
+}
+
+func (r *syncRun) list(listEmail string) *model.MailingListSyncList {
+	if r.lists[listEmail] == nil {
+		r.lists[listEmail] = &model.MailingListSyncList{ListEmail: listEmail}
+	}
+	return r.lists[listEmail]
+}
+
+func (r *syncRun) addItem(listEmail string, item model.MailingListSyncItem) {
+	l := r.list(listEmail)
+	item.ListEmail = listEmail
+	l.Items = append(l.Items, item)
+}
+
+// added records that memberEmail was added to the list.
+func (r *syncRun) added(listEmail, memberEmail string) {
+	r.addItem(listEmail, model.MailingListSyncItem{Action: model.MailingListSyncAdded, MemberEmail: memberEmail})
+	r.AddItems(1)
+}
+
+// removed records that memberEmail was removed from the list.
+func (r *syncRun) removed(listEmail, memberEmail string) {
+	r.addItem(listEmail, model.MailingListSyncItem{Action: model.MailingListSyncRemoved, MemberEmail: memberEmail})
+	r.AddItems(1)
+}
+
+// failed records that syncing the list failed. memberEmail is empty if
+// the list's members couldn't be listed.
+func (r *syncRun) failed(listEmail, memberEmail string, err error) {
+	log.Printf("Failed to sync %v to %v: %v", memberEmail, listEmail, err)
+	r.addItem(listEmail, model.MailingListSyncItem{
+		Action:      model.MailingListSyncFailed,
+		MemberEmail: memberEmail,
+		Reason:      err.Error(),
+	})
+	r.list(listEmail).Failed = true
+	r.Fail()
+}
+
+// skipped records that an activist who should be on the list couldn't
+// be synced.
+func (r *syncRun) skipped(listEmail, activistName, reason string) {
+	log.Printf("Skipped %v for %v: %v", activistName, listEmail, reason)
+	r.list(listEmail).Skipped++
+	r.addItem(listEmail, model.MailingListSyncItem{
+		Action:       model.MailingListSyncSkipped,
+		ActivistName: activistName,
+		Reason:       reason,
+	})
+}
+
+// held records that changes to the list tripped a guardrail and
+// weren't made. A new hold is created unless the list already has one
+// for the same changes.
+func (r *syncRun) held(listEmail string, additions, removals []string, reason string) {
+	hold, ok := r.holds[listEmail]
+	if ok && hold.SameChanges(additions, removals) {
+		if hold.Status == model.MailingListHoldRejected {
+			reason = "Rejected by an admin"
+		} else {
+			reason += ", awaiting approval"
+		}
+	} else {
+		log.Printf("Holding changes to %v: %v", listEmail, reason)
+		hold = model.MailingListHold{
+			ListEmail: listEmail,
+			Held:      time.Now(),
+			Reason:    reason,
+			Status:    model.MailingListHoldPending,
+			Additions: additions,
+			Removals:  removals,
+		}
+		r.holds[listEmail] = hold
+		r.newHolds = append(r.newHolds, hold)
+	}
+	r.addItem(listEmail, model.MailingListSyncItem{
+		Action: model.MailingListSyncHeld,
+		Reason: reason,
+	})
+}
+
+// release records that the list no longer needs its hold, because
+// its changes were approved or no longer trip a guardrail.
+func (r *syncRun) release(listEmail string) {
+	if _, ok := r.holds[listEmail]; !ok {
+		return
+	}
+	delete(r.holds, listEmail)
+	r.releasedHolds = append(r.releasedHolds, listEmail)
+}
+
+// record returns the run as it's stored in the database.
+func (r *syncRun) record() model.MailingListSyncRun {
+	run := model.MailingListSyncRun{
+		Started:          r.started,
+		Finished:         time.Now(),
+		TriggeredByEmail: r.triggeredByEmail,
+		Failed:           r.Failed(),
+	}
+	for _, l := range r.lists {
+		run.Lists = append(run.Lists, *l)
+	}
+	sort.Slice(run.Lists, func(i, j int) bool {
+		return run.Lists[i].ListEmail < run.Lists[j].ListEmail
+	})
+	return run
+}
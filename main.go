@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"database/sql"
 	"encoding/csv"
@@ -15,17 +16,26 @@ import (
 	"net/http/pprof"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	oidc "github.com/coreos/go-oidc"
+	"github.com/dxe/adb/circle_geocoder"
+	"github.com/dxe/adb/cm_status"
 	"github.com/dxe/adb/config"
 	"github.com/dxe/adb/discord"
+	"github.com/dxe/adb/event_purger"
 	"github.com/dxe/adb/facebook_events"
+	"github.com/dxe/adb/geocode"
+	"github.com/dxe/adb/ical"
 	"github.com/dxe/adb/mailinglist_sync"
+	"github.com/dxe/adb/metrics"
 	"github.com/dxe/adb/members"
 	"github.com/dxe/adb/model"
+	"github.com/dxe/adb/mpi_snapshots"
 	"github.com/dxe/adb/survey_mailer"
 	"github.com/getsentry/sentry-go"
 	"github.com/gorilla/csrf"
@@ -167,6 +177,8 @@ func router() (*mux.Router, *sqlx.DB) {
 	)
 
 	router := mux.NewRouter()
+	router.Use(metrics.Middleware)
+	metrics.Register(db)
 	members.Route(router.PathPrefix("/members").Subrouter(), db)
 
 	admin := router.PathPrefix("").Subrouter()
@@ -200,12 +212,20 @@ func router() (*mux.Router, *sqlx.DB) {
 	router.Handle("/leaderboard", alice.New(main.authOrganizerMiddleware).ThenFunc(main.LeaderboardHandler))
 	router.Handle("/list_working_groups", alice.New(main.authOrganizerMiddleware).ThenFunc(main.ListWorkingGroupsHandler))
 	router.Handle("/list_circles", alice.New(main.authOrganizerMiddleware).ThenFunc(main.ListCirclesHandler))
+	router.Handle("/my_groups", alice.New(main.authUserMiddleware).ThenFunc(main.MyGroupsHandler))
+	// On the admin router so its forms get a CSRF token.
+	admin.Handle("/chapter_member_review", alice.New(main.authOrganizerMiddleware).ThenFunc(main.CMStatusReviewHandler))
 
 	// Authed Admin pages
 	admin.Handle("/admin/users", alice.New(main.authAdminMiddleware).ThenFunc(main.ListUsersHandler))
 	admin.Handle("/list_chapters", alice.New(main.authAdminMiddleware).ThenFunc(main.ListChaptersHandler))
 	admin.Handle("/chapter/edit", alice.New(main.authAdminMiddleware).ThenFunc(main.EditChapterHandler))
 	admin.Handle("/chapter/new", alice.New(main.authAdminMiddleware).ThenFunc(main.NewChapterHandler))
+	admin.Handle("/admin/event_types", alice.New(main.authAdminMiddleware).ThenFunc(main.ListEventTypesHandler))
+	admin.Handle("/admin/trash", alice.New(main.authAdminMiddleware).ThenFunc(main.ListArchivedEventsHandler))
+	admin.Handle("/admin/attendance_roles", alice.New(main.authAdminMiddleware).ThenFunc(main.ListAttendanceRolesHandler))
+	admin.Handle("/admin/wallboards", alice.New(main.authAdminMiddleware).ThenFunc(main.ListWallboardTokensHandler))
+	admin.Handle("/admin/mailing_lists", alice.New(main.authAdminMiddleware).ThenFunc(main.ListMailingListSyncRunsHandler))
 
 	// Unauthed API
 	router.HandleFunc("/tokensignin", main.TokenSignInHandler)
@@ -213,11 +233,16 @@ func router() (*mux.Router, *sqlx.DB) {
 	router.HandleFunc("/fb_page/{lat:[0-9.\\-]+},{lng:[0-9.\\-]+}", main.FindNearestFacebookPagesHandler)
 	router.HandleFunc("/fb_pages", main.ListAllFBPages)
 	router.HandleFunc("/chapters", main.ListAllChapters)
+	router.HandleFunc("/groups", main.ListPublicGroups)
+	router.HandleFunc("/ical/chapter/{page_id:[0-9]+}.ics", main.ChapterCalendarHandler)
+	router.HandleFunc("/ical/online.ics", main.OnlineCalendarHandler)
+	router.HandleFunc("/ical/events/{token:[0-9a-f]+}.ics", main.EventsCalendarHandler)
+	router.HandleFunc("/ical/activist/{token:[0-9a-f]+}.ics", main.ActivistCalendarHandler)
+	router.HandleFunc("/wallboard/{token:[0-9a-f]+}", main.WallboardHandler)
+	router.Handle("/metrics", metrics.Handler(config.MetricsToken))
 
 	// Defunct Unauthed API
 	//router.HandleFunc(config.Route0, main.TransposedEventsDataJsonHandler)
-	//router.HandleFunc("/wallboard_mpi", main.newPowerWallboard)                    // new endpoint for arc tv to get mpi
-	//router.HandleFunc("/wallboard_chaptermembers", main.newChapterMemberWallboard) // new endpoint for arc tv to get chapter members
 	//router.HandleFunc(config.Route2, main.ActivistListHandler)                     // used for connections google sheet
 
 	// Authed API
@@ -225,8 +250,13 @@ func router() (*mux.Router, *sqlx.DB) {
 	router.Handle("/activist_names/get_organizers", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.AutocompleteOrganizersHandler))
 	router.Handle("/event/get/{event_id:[0-9]+}", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventGetHandler))
 	router.Handle("/event/save", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventSaveHandler))
+	router.Handle("/event/attendance_log/{event_id:[0-9]+}", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventAttendanceLogHandler))
+	router.Handle("/event/import_attendance/preview", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventImportAttendancePreviewHandler))
+	router.Handle("/event/import_attendance/commit", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventImportAttendanceCommitHandler))
 	router.Handle("/connection/save", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ConnectionSaveHandler))
 	router.Handle("/event/list", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventListHandler))
+	router.Handle("/event_type/list", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventTypeListHandler))
+	router.Handle("/attendance_role/list", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.AttendanceRoleListHandler))
 	router.Handle("/event/delete", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.EventDeleteHandler))
 	router.Handle("/activist/list", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistListHandler))
 	router.Handle("/activist/list_basic", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.ActivistListBasicHandler))
@@ -234,21 +264,54 @@ func router() (*mux.Router, *sqlx.DB) {
 	router.Handle("/activist/save", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistSaveHandler))
 	router.Handle("/activist/hide", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistHideHandler))
 	router.Handle("/activist/merge", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistMergeHandler))
-	router.Handle("/working_group/save", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.WorkingGroupSaveHandler))
-	router.Handle("/working_group/list", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.WorkingGroupListHandler))
-	router.Handle("/working_group/delete", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.WorkingGroupDeleteHandler))
-	router.Handle("/circle/save", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CircleGroupSaveHandler))
-	router.Handle("/circle/list", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CircleGroupListHandler))
-	router.Handle("/circle/delete", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CircleGroupDeleteHandler))
+	router.Handle("/activist/attendance_log/{activist_id:[0-9]+}", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistAttendanceLogHandler))
+	router.Handle("/activist/group_history/{activist_id:[0-9]+}", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistGroupHistoryHandler))
+	router.Handle("/group/save", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupSaveHandler))
+	router.Handle("/group/list", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupListHandler))
+	router.Handle("/group/members", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupMembersHandler))
+	router.Handle("/group/members/save", alice.New(main.apiUserAuthMiddleware).ThenFunc(main.GroupMembersSaveHandler))
+	router.Handle("/group/led", alice.New(main.apiUserAuthMiddleware).ThenFunc(main.GroupLedHandler))
+	router.Handle("/group/size_history", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupSizeHistoryHandler))
+	router.Handle("/group/attendance", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupAttendanceHandler))
+	router.Handle("/circle/nearest", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CircleNearestHandler))
+	router.Handle("/circle/prospect_matches", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CircleProspectMatchesHandler))
+	router.Handle("/group/delete", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupDeleteHandler))
+	router.Handle("/group/archive", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupArchiveHandler))
+	router.Handle("/group/unarchive", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.GroupUnarchiveHandler))
 	router.Handle("/csv/chapter_member_spoke", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ChapterMemberSpokeCSVHandler))
+	router.Handle("/report/leadership", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.LeadershipReportHandler))
+	router.Handle("/mpi/history", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.MPIHistoryHandler))
+	router.Handle("/mpi/evaluate", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.MPIEvaluateHandler))
+	router.Handle("/report/cohorts", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CohortReportHandler))
+	router.Handle("/csv/cohorts", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CohortCSVHandler))
+	router.Handle("/report/level_funnel", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.LevelFunnelReportHandler))
+	router.Handle("/leaderboard/list", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.LeaderboardListHandler))
+	router.Handle("/report/cm_status", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CMStatusReportHandler))
+	admin.Handle("/cm_status/decide", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.CMStatusDecideHandler))
+	router.Handle("/calendar/token", alice.New(main.apiAttendanceAuthMiddleware).ThenFunc(main.CalendarTokenHandler))
+	router.Handle("/calendar/activist_token", alice.New(main.apiOrganizerAuthMiddleware).ThenFunc(main.ActivistCalendarTokenHandler))
 
 	// Authed Admin API
+	admin.Handle("/mpi/backfill", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.MPIBackfillHandler))
+	admin.Handle("/level_changes/backfill", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.LevelChangesBackfillHandler))
 	admin.Handle("/user/list", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.UserListHandler))
 	admin.Handle("/user/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.UserSaveHandler))
 	admin.Handle("/user/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.UserDeleteHandler))
 	admin.Handle("/chapter/update", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.ChapterUpdateHandler))
 	admin.Handle("/chapter/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.ChapterDeleteHandler))
 	admin.Handle("/chapter/insert", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.ChapterInsertHandler))
+	admin.Handle("/event_type/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.EventTypeSaveHandler))
+	admin.Handle("/event_type/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.EventTypeDeleteHandler))
+	admin.Handle("/event/restore", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.EventRestoreHandler))
+	admin.Handle("/attendance_role/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.AttendanceRoleSaveHandler))
+	admin.Handle("/attendance_role/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.AttendanceRoleDeleteHandler))
+	admin.Handle("/wallboard_token/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.WallboardTokenSaveHandler))
+	admin.Handle("/wallboard_token/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.WallboardTokenDeleteHandler))
+	admin.Handle("/mailing_lists/sync", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.MailingListSyncTriggerHandler))
+	admin.Handle("/mailing_list/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.MailingListSaveHandler))
+	admin.Handle("/mailing_list/delete", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.MailingListDeleteHandler))
+	admin.Handle("/mailing_list/hold/review", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.MailingListHoldReviewHandler))
+	admin.Handle("/group/guardrails/save", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.GroupGuardrailsSaveHandler))
 	// Authed Admin API for managing Users Roles
 	admin.Handle("/users-roles/add", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.UsersRolesAddHandler))
 	admin.Handle("/users-roles/remove", alice.New(main.apiAdminAuthMiddleware).ThenFunc(main.UsersRolesRemoveHandler))
@@ -279,6 +342,8 @@ type MainController struct {
 	db *sqlx.DB
 }
 
+// authRoleMiddleware only lets through users with one of the allowed
+// roles. If allowedRoles is nil, any logged in user is let through.
 func (c MainController) authRoleMiddleware(h http.Handler, allowedRoles []string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user, authed := getAuthedADBUser(c.db, r)
@@ -297,7 +362,7 @@ func (c MainController) authRoleMiddleware(h http.Handler, allowedRoles []string
 			return
 		}
 
-		if !userIsAllowed(allowedRoles, user) {
+		if allowedRoles != nil && !userIsAllowed(allowedRoles, user) {
 			http.Redirect(w, r.WithContext(setUserContext(r, user)), "/403", http.StatusFound)
 			return
 		}
@@ -307,6 +372,10 @@ func (c MainController) authRoleMiddleware(h http.Handler, allowedRoles []string
 	})
 }
 
+func (c MainController) authUserMiddleware(h http.Handler) http.Handler {
+	return c.authRoleMiddleware(h, nil)
+}
+
 func (c MainController) authAttendanceMiddleware(h http.Handler) http.Handler {
 	return c.authRoleMiddleware(h, []string{"admin", "organizer", "attendance"})
 }
@@ -374,6 +443,7 @@ func getUserEmail(user model.ADBUser) string {
 	return userEmail
 }
 
+// apiRoleMiddleware is like authRoleMiddleware, but for API requests.
 func (c MainController) apiRoleMiddleware(h http.Handler, allowedRoles []string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user, authed := getAuthedADBUser(c.db, r)
@@ -383,7 +453,7 @@ func (c MainController) apiRoleMiddleware(h http.Handler, allowedRoles []string)
 			return
 		}
 
-		if !userIsAllowed(allowedRoles, user) {
+		if allowedRoles != nil && !userIsAllowed(allowedRoles, user) {
 			http.Error(w, http.StatusText(403), 403)
 			return
 		}
@@ -393,6 +463,10 @@ func (c MainController) apiRoleMiddleware(h http.Handler, allowedRoles []string)
 	})
 }
 
+func (c MainController) apiUserAuthMiddleware(h http.Handler) http.Handler {
+	return c.apiRoleMiddleware(h, nil)
+}
+
 func (c MainController) apiAttendanceAuthMiddleware(h http.Handler) http.Handler {
 	return c.apiRoleMiddleware(h, []string{"admin", "organizer", "attendance"})
 }
@@ -626,6 +700,10 @@ func (c MainController) ListCirclesHandler(w http.ResponseWriter, r *http.Reques
 	renderPage(w, r, "circles_list", PageData{PageName: "CirclesList"})
 }
 
+func (c MainController) MyGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	renderPage(w, r, "my_groups", PageData{PageName: "MyGroups"})
+}
+
 func (c MainController) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
 	renderPage(w, r, "user_list", PageData{PageName: "UserList"})
 }
@@ -731,6 +809,436 @@ func (c MainController) ChapterInsertHandler(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, "/list_chapters", http.StatusFound)
 }
 
+func (c MainController) ListEventTypesHandler(w http.ResponseWriter, r *http.Request) {
+	eventTypes, err := model.GetEventTypes(c.db)
+	if err != nil {
+		panic(err)
+	}
+	renderPage(w, r, "event_types_list", PageData{
+		PageName: "EventTypesList",
+		Data: map[string]interface{}{
+			"EventTypes": eventTypes,
+		}})
+}
+
+func (c MainController) EventTypeSaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	points, err := strconv.Atoi(r.FormValue("points"))
+	if err != nil {
+		flashMesssageError(w, "Points must be a number.")
+		http.Redirect(w, r, "/admin/event_types", http.StatusFound)
+		return
+	}
+	eventType, err := model.CleanEventTypeData(id, r.FormValue("name"), r.FormValue("mpi_category"), points)
+	if err == nil {
+		if eventType.ID == 0 {
+			_, err = model.CreateEventType(c.db, eventType)
+		} else {
+			err = model.UpdateEventType(c.db, eventType)
+		}
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Saved succesfully.")
+	}
+	http.Redirect(w, r, "/admin/event_types", http.StatusFound)
+}
+
+func (c MainController) EventTypeDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err == nil {
+		err = model.DeleteEventType(c.db, id)
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Deleted succesfully.")
+	}
+	http.Redirect(w, r, "/admin/event_types", http.StatusFound)
+}
+
+func (c MainController) ListArchivedEventsHandler(w http.ResponseWriter, r *http.Request) {
+	events, err := model.GetArchivedEvents(c.db)
+	if err != nil {
+		panic(err)
+	}
+	renderPage(w, r, "event_trash", PageData{
+		PageName: "EventTrash",
+		Data: map[string]interface{}{
+			"Events":        events,
+			"RetentionDays": config.ArchivedEventRetentionDays,
+		}})
+}
+
+func (c MainController) EventRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err == nil {
+		user, _ := getAuthedADBUser(c.db, r)
+		var eventID int
+		eventID, err = model.RestoreArchivedEvent(c.db, id, user)
+		if err == nil {
+			flashMessageSuccess(w, fmt.Sprintf("Restored event %d.", eventID))
+		}
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	}
+	http.Redirect(w, r, "/admin/trash", http.StatusFound)
+}
+
+func (c MainController) ListAttendanceRolesHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := model.GetAttendanceRoles(c.db)
+	if err != nil {
+		panic(err)
+	}
+	renderPage(w, r, "attendance_roles_list", PageData{
+		PageName: "AttendanceRolesList",
+		Data: map[string]interface{}{
+			"Roles": roles,
+		}})
+}
+
+func (c MainController) AttendanceRoleSaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	points, err := strconv.Atoi(r.FormValue("points"))
+	if err != nil {
+		flashMesssageError(w, "Points must be a number.")
+		http.Redirect(w, r, "/admin/attendance_roles", http.StatusFound)
+		return
+	}
+	role, err := model.CleanAttendanceRoleData(id, r.FormValue("name"), points)
+	if err == nil {
+		if role.ID == 0 {
+			_, err = model.CreateAttendanceRole(c.db, role)
+		} else {
+			err = model.UpdateAttendanceRole(c.db, role)
+		}
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Saved succesfully.")
+	}
+	http.Redirect(w, r, "/admin/attendance_roles", http.StatusFound)
+}
+
+func (c MainController) AttendanceRoleDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err == nil {
+		err = model.DeleteAttendanceRole(c.db, id)
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Deleted succesfully.")
+	}
+	http.Redirect(w, r, "/admin/attendance_roles", http.StatusFound)
+}
+
+func (c MainController) ListWallboardTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := model.GetWallboardTokens(c.db)
+	if err != nil {
+		panic(err)
+	}
+	chapters, err := model.GetAllChaptersWithoutTokens(c.db)
+	if err != nil {
+		panic(err)
+	}
+	chapterNames := map[int]string{}
+	for _, ch := range chapters {
+		chapterNames[ch.FacebookID] = ch.Name
+	}
+	renderPage(w, r, "wallboard_tokens_list", PageData{
+		PageName: "WallboardTokensList",
+		Data: map[string]interface{}{
+			"Tokens":       tokens,
+			"Chapters":     chapters,
+			"ChapterNames": chapterNames,
+			"URLPrefix":    config.UrlPath + "/wallboard/",
+		}})
+}
+
+// How many recent mailing list sync runs are shown to admins.
+const mailingListSyncRunsShown = 50
+
+func (c MainController) ListMailingListSyncRunsHandler(w http.ResponseWriter, r *http.Request) {
+	lists, err := model.GetMailingLists(c.db)
+	if err != nil {
+		panic(err)
+	}
+	groups, err := model.GetGroups(c.db, model.GroupQueryOptions{
+		Types: []string{model.GroupTypeWorkingGroup, model.GroupTypeCommittee},
+	})
+	if err != nil {
+		panic(err)
+	}
+	holds, err := model.GetMailingListHolds(c.db)
+	if err != nil {
+		panic(err)
+	}
+	runs, err := model.GetMailingListSyncRuns(c.db, mailingListSyncRunsShown)
+	if err != nil {
+		panic(err)
+	}
+	renderPage(w, r, "mailing_list_sync_runs", PageData{
+		PageName: "MailingListSyncRuns",
+		Data: map[string]interface{}{
+			"Lists":        lists,
+			"Rules":        model.MailingListRules,
+			"Groups":       groups,
+			"Holds":        holds,
+			"Defaults":     model.DefaultMailingListGuardrails,
+			"Runs":         runs,
+			"FailingLists": model.FailingMailingLists(runs),
+		}})
+}
+
+func (c MainController) MailingListSaveHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	guardrails := mailingListGuardrailsFormValues(r)
+	list, err := model.CleanMailingListData(c.db, id, r.FormValue("email"), r.FormValue("description"),
+		r.FormValue("rule_type"), r.FormValue("rule_value"), r.FormValue("always_include"), r.FormValue("always_exclude"),
+		guardrails)
+	if err == nil {
+		if list.ID == 0 {
+			_, err = model.CreateMailingList(c.db, list)
+		} else {
+			_, err = model.UpdateMailingList(c.db, list)
+		}
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Saved succesfully.")
+	}
+	http.Redirect(w, r, "/admin/mailing_lists", http.StatusFound)
+}
+
+func (c MainController) MailingListDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err == nil {
+		err = model.DeleteMailingList(c.db, id)
+	}
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Deleted succesfully. The list's members were left as they are.")
+	}
+	http.Redirect(w, r, "/admin/mailing_lists", http.StatusFound)
+}
+
+func (c MainController) MailingListSyncTriggerHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := getAuthedADBUser(c.db, r)
+	err := mailinglist_sync.TriggerMailingListsSync(user.Email)
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Mailing lists will sync in a moment. Reload this page to see the results.")
+	}
+	http.Redirect(w, r, "/admin/mailing_lists", http.StatusFound)
+}
+
+func mailingListGuardrailsFormValues(r *http.Request) model.MailingListGuardrails {
+	maxRemovals, _ := strconv.Atoi(r.FormValue("max_removals"))
+	maxRemovePercent, _ := strconv.Atoi(r.FormValue("max_remove_percent"))
+	return model.MailingListGuardrails{
+		MaxRemovals:      maxRemovals,
+		MaxRemovePercent: maxRemovePercent,
+		AllowEmpty:       r.FormValue("allow_empty") == "true",
+	}
+}
+
+// GroupGuardrailsSaveHandler sets the guardrails of a working group's
+// or committee's mailing list.
+func (c MainController) GroupGuardrailsSaveHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, _ := strconv.Atoi(r.FormValue("group_id"))
+	err := model.UpdateGroupGuardrails(c.db, groupID, mailingListGuardrailsFormValues(r))
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Saved succesfully.")
+	}
+	http.Redirect(w, r, "/admin/mailing_lists", http.StatusFound)
+}
+
+// MailingListHoldReviewHandler approves or rejects changes to a
+// mailing list that were held by a guardrail. Approved changes are made
+// by the sync it triggers.
+func (c MainController) MailingListHoldReviewHandler(w http.ResponseWriter, r *http.Request) {
+	status := model.MailingListHoldRejected
+	if r.FormValue("approve") == "true" {
+		status = model.MailingListHoldApproved
+	}
+	err := model.SetMailingListHoldStatus(c.db, r.FormValue("list_email"), status)
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else if status == model.MailingListHoldRejected {
+		flashMessageSuccess(w, "Rejected. The changes won't be made, and won't be held again unless they change.")
+	} else {
+		user, _ := getAuthedADBUser(c.db, r)
+		if err := mailinglist_sync.TriggerMailingListsSync(user.Email); err != nil {
+			flashMessageSuccess(w, "Approved. The changes will be made by the next sync.")
+		} else {
+			flashMessageSuccess(w, "Approved. The changes will be made in a moment.")
+		}
+	}
+	http.Redirect(w, r, "/admin/mailing_lists", http.StatusFound)
+}
+
+func (c MainController) WallboardTokenSaveHandler(w http.ResponseWriter, r *http.Request) {
+	pageID, _ := strconv.Atoi(r.FormValue("page_id"))
+	_, err := model.CreateWallboardToken(c.db, r.FormValue("name"), pageID)
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Saved succesfully.")
+	}
+	http.Redirect(w, r, "/admin/wallboards", http.StatusFound)
+}
+
+func (c MainController) WallboardTokenDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	err := model.DeleteWallboardToken(c.db, r.FormValue("token"))
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else {
+		flashMessageSuccess(w, "Deleted succesfully.")
+	}
+	http.Redirect(w, r, "/admin/wallboards", http.StatusFound)
+}
+
+func (c MainController) CMStatusReviewHandler(w http.ResponseWriter, r *http.Request) {
+	review, err := model.GetCMStatusReview(c.db, 0)
+	renderPage(w, r, "cm_status_review", PageData{
+		PageName: "CMStatusReview",
+		Data: map[string]interface{}{
+			"Review":   review,
+			"Reviewed": err == nil,
+			"Rule": model.CMRetentionRule{
+				MinMonths: config.CMRetentionMinMonths,
+				Window:    config.CMRetentionWindowMonths,
+			},
+		}})
+}
+
+// CMStatusDecideHandler approves or rejects a level change proposed
+// by a chapter member review.
+func (c MainController) CMStatusDecideHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	user, authed := getAuthedADBUser(c.db, r)
+	if !authed {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	itemID, _ := strconv.Atoi(r.FormValue("id"))
+	approve := r.FormValue("decision") == model.CMStatusDecisionApproved
+	err := model.DecideCMStatusChange(c.db, itemID, approve, user)
+	if err != nil {
+		flashMesssageError(w, err.Error())
+	} else if approve {
+		flashMessageSuccess(w, "Level change approved.")
+	} else {
+		flashMessageSuccess(w, "Level change rejected.")
+	}
+	http.Redirect(w, r, "/chapter_member_review", http.StatusFound)
+}
+
+func (c MainController) AttendanceRoleListHandler(w http.ResponseWriter, r *http.Request) {
+	roles, err := model.GetAttendanceRolesJSON(c.db)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status": "success",
+		"roles":  roles,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) LeadershipReportHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := model.GetLeadershipReport(c.db, r.URL.Query().Get("date_from"), r.URL.Query().Get("date_to"))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":    "success",
+		"activists": rows,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) MPIHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := model.GetMPISnapshotsJSON(c.db, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":    "success",
+		"snapshots": snapshots,
+	}
+	writeJSON(w, out)
+}
+
+// MPIEvaluateHandler applies the MPI and voting rules to a
+// batch of activists.
+func (c MainController) MPIEvaluateHandler(w http.ResponseWriter, r *http.Request) {
+	activistIDs, month, err := model.CleanMPIEvaluationRequest(r.Body, time.Now())
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	evaluations, err := model.EvaluateMPI(c.db, activistIDs, month)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":      "success",
+		"evaluations": evaluations,
+	}
+	writeJSON(w, out)
+}
+
+// MPIBackfillHandler reconstructs the MPI snapshots of past months
+// that were never recorded.
+func (c MainController) MPIBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := model.BackfillMPISnapshots(c.db, model.PreviousMonth(time.Now()))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":     "success",
+		"backfilled": n,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) EventTypeListHandler(w http.ResponseWriter, r *http.Request) {
+	eventTypes, err := model.GetEventTypesJSON(c.db)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":      "success",
+		"event_types": eventTypes,
+	}
+	writeJSON(w, out)
+}
+
 var templates = template.Must(template.New("").Funcs(
 	template.FuncMap{
 		"formatdate": func(date time.Time) string {
@@ -786,6 +1294,26 @@ func writeJSON(w io.Writer, v interface{}) {
 	}
 }
 
+// writeCacheableJSON writes v with an ETag, or responds with 304 Not
+// Modified if the client already has it.
+func writeCacheableJSON(w http.ResponseWriter, r *http.Request, v interface{}, maxAge time.Duration) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	for _, t := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if t = strings.TrimSpace(t); t == etag || t == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(body, '\n'))
+}
+
 /* Accepts a non-nil error and sends an error response */
 func sendErrorMessage(w io.Writer, err error) {
 	if err == nil {
@@ -947,7 +1475,8 @@ func (c MainController) ActivistMergeHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = model.MergeActivist(c.db, activistMergeData.CurrentActivistID, mergedActivist.ID)
+	user, _ := getAuthedADBUser(c.db, r)
+	err = model.MergeActivist(c.db, activistMergeData.CurrentActivistID, mergedActivist.ID, user)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
@@ -985,6 +1514,7 @@ func (c MainController) EventSaveHandler(w http.ResponseWriter, r *http.Request)
 		sendErrorMessage(w, err)
 		return
 	}
+	event.AttendanceChange.User, _ = getAuthedADBUser(c.db, r)
 
 	// Events with no event ID are new events.
 	isNewEvent := event.ID == 0
@@ -1012,29 +1542,150 @@ func (c MainController) EventSaveHandler(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, out)
 }
 
-func (c MainController) ConnectionSaveHandler(w http.ResponseWriter, r *http.Request) {
-	event, err := model.CleanEventData(c.db, r.Body)
+func (c MainController) EventAttendanceLogHandler(w http.ResponseWriter, r *http.Request) {
+	eventID, err := strconv.Atoi(mux.Vars(r)["event_id"])
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	// Events with no event ID are new events.
-	isNewEvent := event.ID == 0
-
-	eventID, err := model.InsertUpdateEvent(c.db, event)
+	entries, err := model.GetEventAttendanceLogJSON(c.db, eventID)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	attendees, err := model.GetEventAttendance(c.db, eventID)
+	out := map[string]interface{}{
+		"status":  "success",
+		"entries": entries,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) ActivistAttendanceLogHandler(w http.ResponseWriter, r *http.Request) {
+	activistID, err := strconv.Atoi(mux.Vars(r)["activist_id"])
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	out := map[string]interface{}{
+	entries, err := model.GetActivistAttendanceLogJSON(c.db, activistID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":  "success",
+		"entries": entries,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) ActivistGroupHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	activistID, err := strconv.Atoi(mux.Vars(r)["activist_id"])
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	memberships, err := model.GetActivistGroupHistory(c.db, activistID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status":      "success",
+		"memberships": model.GroupMembershipsToJSON(memberships),
+	})
+}
+
+// Largest CSV file accepted by the attendance import.
+const maxAttendanceImportSize = 10 << 20
+
+func (c MainController) EventImportAttendancePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxAttendanceImportSize); err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	eventID, err := strconv.Atoi(r.FormValue("event_id"))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	defer file.Close()
+
+	preview, err := model.PreviewAttendanceImport(c.db, eventID, file)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":  "success",
+		"preview": preview,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) EventImportAttendanceCommitHandler(w http.ResponseWriter, r *http.Request) {
+	preview, err := model.CleanAttendanceImportData(r.Body)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	user, _ := getAuthedADBUser(c.db, r)
+	imported, err := model.ImportAttendance(c.db, preview, user)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	attendees, err := model.GetEventAttendance(c.db, preview.EventID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":    "success",
+		"imported":  imported,
+		"attendees": attendees,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) ConnectionSaveHandler(w http.ResponseWriter, r *http.Request) {
+	event, err := model.CleanEventData(c.db, r.Body)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	event.AttendanceChange.User, _ = getAuthedADBUser(c.db, r)
+
+	// Events with no event ID are new events.
+	isNewEvent := event.ID == 0
+
+	eventID, err := model.InsertUpdateEvent(c.db, event)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	attendees, err := model.GetEventAttendance(c.db, eventID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
 		"status":    "success",
 		"redirect":  "",
 		"attendees": attendees,
@@ -1057,6 +1708,15 @@ func (c MainController) EventListHandler(w http.ResponseWriter, r *http.Request)
 	dateStart := r.PostFormValue("event_date_start")
 	dateEnd := r.PostFormValue("event_date_end")
 	eventType := r.PostFormValue("event_type")
+	attendeeRole := r.PostFormValue("attendee_role")
+	var groupID int
+	if g := r.PostFormValue("group_id"); g != "" {
+		groupID, err = strconv.Atoi(g)
+		if err != nil {
+			sendErrorMessage(w, err)
+			return
+		}
+	}
 
 	events, err := model.GetEventsJSON(c.db, model.GetEventOptions{
 		OrderBy:        "e.date DESC, e.id DESC",
@@ -1065,6 +1725,8 @@ func (c MainController) EventListHandler(w http.ResponseWriter, r *http.Request)
 		EventType:      eventType,
 		EventNameQuery: eventName,
 		EventActivist:  eventActivist,
+		AttendeeRole:   attendeeRole,
+		GroupID:        groupID,
 	})
 
 	if err != nil {
@@ -1085,7 +1747,8 @@ func (c MainController) EventDeleteHandler(w http.ResponseWriter, r *http.Reques
 		panic(err)
 	}
 
-	if err := model.DeleteEvent(c.db, eventID); err != nil {
+	user, _ := getAuthedADBUser(c.db, r)
+	if err := model.DeleteEvent(c.db, eventID, user); err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
@@ -1095,117 +1758,388 @@ func (c MainController) EventDeleteHandler(w http.ResponseWriter, r *http.Reques
 	})
 }
 
-func (c MainController) WorkingGroupSaveHandler(w http.ResponseWriter, r *http.Request) {
-	wg, err := model.CleanWorkingGroupData(c.db, r.Body)
+func (c MainController) GroupSaveHandler(w http.ResponseWriter, r *http.Request) {
+	group, err := model.CleanGroupData(c.db, r.Body)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	var wgID int
-	if wg.ID == 0 {
-		wgID, err = model.CreateWorkingGroup(c.db, wg)
+	user, _ := getAuthedADBUser(c.db, r)
+
+	var groupID int
+	if group.ID == 0 {
+		groupID, err = model.CreateGroup(c.db, group, user.Email)
 	} else {
-		wgID, err = model.UpdateWorkingGroup(c.db, wg)
+		groupID, err = model.UpdateGroup(c.db, group, user.Email)
 	}
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	wgJSON, err := model.GetWorkingGroupJSON(c.db, wgID)
+	groupJSON, err := model.GetGroupJSON(c.db, groupID)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
 	writeJSON(w, map[string]interface{}{
-		"status":        "success",
-		"working_group": wgJSON,
+		"status": "success",
+		"group":  groupJSON,
 	})
 }
 
-func (c MainController) WorkingGroupListHandler(w http.ResponseWriter, r *http.Request) {
-	wgs, err := model.GetWorkingGroupsJSON(c.db)
+// GroupMembersSaveHandler replaces the members of a group. Besides
+// organizers, the group's leads can save its members, but they can't
+// change its leads and co-leads.
+func (c MainController) GroupMembersSaveHandler(w http.ResponseWriter, r *http.Request) {
+	requestData, err := model.DecodeGroupMembersData(r.Body)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	groupID := requestData.GroupID
+
+	user, _ := getAuthedADBUser(c.db, r)
+	organizer := userIsAllowed([]string{"admin", "organizer"}, user)
+	if !organizer {
+		lead, err := model.IsGroupLead(c.db, groupID, user.Email)
+		if err != nil {
+			sendErrorMessage(w, err)
+			return
+		}
+		if !lead {
+			http.Error(w, http.StatusText(403), 403)
+			return
+		}
+	}
+
+	group, err := model.GetGroup(c.db, model.GroupQueryOptions{GroupID: groupID})
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	if group.Archived {
+		sendErrorMessage(w, errors.Errorf("%s is archived and can't be edited", group.Name))
+		return
+	}
+
+	members, err := model.CleanGroupMembersData(c.db, requestData)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
+	if !organizer {
+		if err := model.ValidateGroupMembersByLead(group.Members, members); err != nil {
+			sendErrorMessage(w, err)
+			return
+		}
+	}
+
+	if err := model.UpdateGroupMembers(c.db, groupID, members, user.Email); err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	groupJSON, err := model.GetGroupJSON(c.db, groupID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"group":  groupJSON,
+	})
+}
+
+// GroupLedHandler lists the groups led by the current user, along
+// with the activist names they can add as members. Only users who can
+// take attendance get every activist's name; other leads get the
+// names of their groups' current members.
+func (c MainController) GroupLedHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := getAuthedADBUser(c.db, r)
+	groups := []model.GroupJSON{}
+	if strings.TrimSpace(user.Email) != "" {
+		var err error
+		groups, err = model.GetGroupsJSON(c.db, model.GroupQueryOptions{LeadEmail: user.Email})
+		if err != nil {
+			sendErrorMessage(w, err)
+			return
+		}
+	}
+
+	names := []string{}
+	if len(groups) != 0 && userIsAllowed([]string{"admin", "organizer", "attendance"}, user) {
+		names = model.GetAutocompleteNames(c.db)
+	} else {
+		seen := map[string]bool{}
+		for _, g := range groups {
+			for _, m := range g.Members {
+				if !seen[m.Name] {
+					seen[m.Name] = true
+					names = append(names, m.Name)
+				}
+			}
+		}
+		sort.Strings(names)
+	}
 
 	writeJSON(w, map[string]interface{}{
 		"status":         "success",
-		"working_groups": wgs,
+		"groups":         groups,
+		"activist_names": names,
 	})
 }
 
-func (c MainController) WorkingGroupDeleteHandler(w http.ResponseWriter, r *http.Request) {
-	var requestData struct {
-		ID int `json:"working_group_id"`
+// GroupListHandler lists groups. The type query parameter can be
+// repeated to list groups of several types; without it, groups of all
+// types are listed. Archived groups are only listed if
+// include_archived is true.
+func (c MainController) GroupListHandler(w http.ResponseWriter, r *http.Request) {
+	groups, err := model.GetGroupsJSON(c.db, model.GroupQueryOptions{
+		Types:           r.URL.Query()["type"],
+		IncludeArchived: r.URL.Query().Get("include_archived") == "true",
+	})
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
 	}
-	err := json.NewDecoder(r.Body).Decode(&requestData)
+
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"groups": groups,
+	})
+}
+
+// GroupMembersHandler lists the members of a group as of the date
+// query parameter, formatted as YYYY-MM-DD. Without a date, it lists
+// the current members.
+func (c MainController) GroupMembersHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
+	if err != nil {
+		sendErrorMessage(w, errors.New("A valid group_id is required"))
+		return
+	}
+
+	asOf := time.Now()
+	if date := r.URL.Query().Get("date"); date != "" {
+		d, err := time.Parse(model.EventDateLayout, date)
+		if err != nil {
+			sendErrorMessage(w, errors.Errorf("Not a valid date: %s", date))
+			return
+		}
+		// Include changes made at any time on that day.
+		asOf = d.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	memberships, err := model.GetGroupMembersAsOf(c.db, groupID, asOf)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	err = model.DeleteWorkingGroup(c.db, requestData.ID)
+	writeJSON(w, map[string]interface{}{
+		"status":      "success",
+		"memberships": model.GroupMembershipsToJSON(memberships),
+	})
+}
+
+// GroupSizeHistoryHandler reports the size of a group at the end of
+// each month between the from and to query parameters, formatted as
+// YYYY-MM.
+func (c MainController) GroupSizeHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
+	if err != nil {
+		sendErrorMessage(w, errors.New("A valid group_id is required"))
+		return
+	}
+
+	sizes, err := model.GetGroupSizeHistory(c.db, groupID, r.URL.Query().Get("from"), r.URL.Query().Get("to"), time.Now())
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	writeJSON(w, map[string]string{
+	writeJSON(w, map[string]interface{}{
 		"status": "success",
+		"sizes":  sizes,
 	})
 }
 
-//start circle
-func (c MainController) CircleGroupSaveHandler(w http.ResponseWriter, r *http.Request) {
-	cir, err := model.CleanCircleGroupData(c.db, r.Body)
+// Defaults for GroupAttendanceHandler's weeks and inactive_weeks
+// query parameters.
+const (
+	groupAttendanceWeeks         = 12
+	groupAttendanceInactiveWeeks = 4
+)
+
+// GroupAttendanceHandler reports attendance at a group's meetings
+// over the last weeks weeks, members who haven't attended in
+// inactive_weeks weeks, and non-members who attend regularly.
+func (c MainController) GroupAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.Atoi(r.URL.Query().Get("group_id"))
+	if err != nil {
+		sendErrorMessage(w, errors.New("A valid group_id is required"))
+		return
+	}
+	weeks := groupAttendanceWeeks
+	if s := r.URL.Query().Get("weeks"); s != "" {
+		if weeks, err = strconv.Atoi(s); err != nil {
+			sendErrorMessage(w, errors.Errorf("Not a valid number of weeks: %s", s))
+			return
+		}
+	}
+	inactiveWeeks := groupAttendanceInactiveWeeks
+	if s := r.URL.Query().Get("inactive_weeks"); s != "" {
+		if inactiveWeeks, err = strconv.Atoi(s); err != nil {
+			sendErrorMessage(w, errors.Errorf("Not a valid number of weeks: %s", s))
+			return
+		}
+	}
+
+	report, err := model.GetGroupAttendanceReport(c.db, groupID, weeks, inactiveWeeks, time.Now())
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	var cirID int
-	if cir.ID == 0 {
-		cirID, err = model.CreateCircleGroup(c.db, cir)
-	} else {
-		cirID, err = model.UpdateCircleGroup(c.db, cir)
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"report": report,
+	})
+}
+
+func circleMatchOptions(r *http.Request) (model.CircleMatchOptions, error) {
+	options := model.CircleMatchOptions{
+		MeetingTime: r.URL.Query().Get("meeting_time"),
 	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil {
+			return options, errors.Errorf("Not a valid limit: %s", s)
+		}
+		options.Limit = limit
+	}
+	return options, nil
+}
+
+// CircleNearestHandler suggests the visible circles closest to an
+// activist (activist_id), an address (address) or a point (lat and
+// lng). meeting_time limits them to circles meeting at that time,
+// e.g. "tuesday".
+func (c MainController) CircleNearestHandler(w http.ResponseWriter, r *http.Request) {
+	options, err := circleMatchOptions(r)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
-	cirJSON, err := model.GetCircleGroupJSON(c.db, cirID)
+	query := r.URL.Query()
+	var circles []model.CircleMatch
+	switch {
+	case query.Get("activist_id") != "":
+		activistID, idErr := strconv.Atoi(query.Get("activist_id"))
+		if idErr != nil {
+			sendErrorMessage(w, errors.New("Not a valid activist_id"))
+			return
+		}
+		circles, err = model.FindNearestCirclesToActivist(c.db, geocode.Address, activistID, options)
+	case query.Get("address") != "":
+		circles, err = model.FindNearestCirclesToAddress(c.db, geocode.Address, query.Get("address"), options)
+	default:
+		lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(query.Get("lng"), 64)
+		if latErr != nil || lngErr != nil {
+			sendErrorMessage(w, errors.New("One of activist_id, address, or lat and lng is required"))
+			return
+		}
+		circles, err = model.FindNearestCircles(c.db, lat, lng, options)
+	}
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status":  "success",
+		"circles": circles,
+	})
+}
+
+// CircleProspectMatchesHandler suggests circles to every activist who
+// is interested in joining one.
+func (c MainController) CircleProspectMatchesHandler(w http.ResponseWriter, r *http.Request) {
+	options, err := circleMatchOptions(r)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	matches, err := model.GetCircleInterestMatches(c.db, options)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
 	writeJSON(w, map[string]interface{}{
+		"status":    "success",
+		"prospects": matches,
+	})
+}
+
+func (c MainController) GroupArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		ID int `json:"group_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&requestData)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	user, _ := getAuthedADBUser(c.db, r)
+	if err := model.ArchiveGroup(c.db, requestData.ID, user.Email); err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]string{
 		"status": "success",
-		"circle": cirJSON,
 	})
 }
 
-func (c MainController) CircleGroupListHandler(w http.ResponseWriter, r *http.Request) {
-	cirs, err := model.GetCircleGroupsJSON(c.db)
+func (c MainController) GroupUnarchiveHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		ID int `json:"group_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&requestData)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	if err := model.UnarchiveGroup(c.db, requestData.ID); err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	groupJSON, err := model.GetGroupJSON(c.db, requestData.ID)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
 	}
 
 	writeJSON(w, map[string]interface{}{
-		"status":         "success",
-		"working_groups": cirs,
+		"status": "success",
+		"group":  groupJSON,
 	})
 }
 
-func (c MainController) CircleGroupDeleteHandler(w http.ResponseWriter, r *http.Request) {
+func (c MainController) GroupDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
-		ID int `json:"circle_id"`
+		ID int `json:"group_id"`
 	}
 	err := json.NewDecoder(r.Body).Decode(&requestData)
 	if err != nil {
@@ -1213,7 +2147,7 @@ func (c MainController) CircleGroupDeleteHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err = model.DeleteCircleGroup(c.db, requestData.ID)
+	err = model.DeleteGroup(c.db, requestData.ID)
 	if err != nil {
 		sendErrorMessage(w, err)
 		return
@@ -1224,8 +2158,6 @@ func (c MainController) CircleGroupDeleteHandler(w http.ResponseWriter, r *http.
 	})
 }
 
-//end circle
-
 func (c MainController) ActivistListHandler(w http.ResponseWriter, r *http.Request) {
 	options, err := model.CleanGetActivistOptions(r.Body)
 	if err != nil {
@@ -1283,6 +2215,144 @@ func (c MainController) ChapterMemberSpokeCSVHandler(w http.ResponseWriter, r *h
 
 }
 
+func (c MainController) LeaderboardListHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	halfLifeDays, _ := strconv.Atoi(q.Get("half_life_days"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	options, err := model.CleanLeaderboardOptions(q.Get("window"), halfLifeDays, limit)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	entries, err := model.GetLeaderboard(c.db, options, time.Now())
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":      "success",
+		"leaderboard": entries,
+	}
+	writeJSON(w, out)
+}
+
+// CMStatusReportHandler returns the chapter member review of a month
+// (formatted as YYYY-MM), or the latest one.
+func (c MainController) CMStatusReportHandler(w http.ResponseWriter, r *http.Request) {
+	review, err := model.GetCMStatusReviewByMonth(c.db, r.URL.Query().Get("month"))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status": "success",
+		"review": review,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) LevelFunnelReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := model.GetLevelFunnel(c.db, r.URL.Query().Get("date_from"), r.URL.Query().Get("date_to"))
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status": "success",
+		"report": report,
+	}
+	writeJSON(w, out)
+}
+
+// LevelChangesBackfillHandler reconstructs level changes made before
+// they were recorded.
+func (c MainController) LevelChangesBackfillHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := model.BackfillLevelChanges(c.db)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":     "success",
+		"backfilled": n,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) getCohorts(r *http.Request) ([]model.Cohort, error) {
+	q := r.URL.Query()
+	options, err := model.CleanCohortOptions(q.Get("group_by"), q.Get("date_from"), q.Get("date_to"), q.Get("months"))
+	if err != nil {
+		return nil, err
+	}
+	return model.GetCohorts(c.db, options, time.Now())
+}
+
+func (c MainController) CohortReportHandler(w http.ResponseWriter, r *http.Request) {
+	cohorts, err := c.getCohorts(r)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":  "success",
+		"cohorts": cohorts,
+	}
+	writeJSON(w, out)
+}
+
+// CohortCSVHandler writes one row per cohort and curve, with a column
+// per month after the first event month.
+func (c MainController) CohortCSVHandler(w http.ResponseWriter, r *http.Request) {
+	cohorts, err := c.getCohorts(r)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=cohorts.csv")
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Transfer-Encoding", "chunked")
+
+	months := 0
+	for _, cohort := range cohorts {
+		if len(cohort.Active) > months {
+			months = len(cohort.Active)
+		}
+	}
+	header := []string{"cohort_month", "group", "size", "curve"}
+	for i := 0; i < months; i++ {
+		header = append(header, "month_"+strconv.Itoa(i))
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+	for _, cohort := range cohorts {
+		for _, curve := range []struct {
+			name   string
+			values []int
+		}{{"active", cohort.Active}, {"retained", cohort.Retained}} {
+			row := []string{cohort.Month, cohort.Group, strconv.Itoa(cohort.Size), curve.name}
+			for _, v := range curve.values {
+				row = append(row, strconv.Itoa(v))
+			}
+			if err := writer.Write(row); err != nil {
+				sendErrorMessage(w, err)
+				return
+			}
+		}
+	}
+	writer.Flush()
+}
+
 func (c MainController) UserListHandler(w http.ResponseWriter, r *http.Request) {
 	users, err := model.GetUsersJSON(c.db)
 
@@ -1456,7 +2526,7 @@ func (c MainController) ListFBEventsHandler(w http.ResponseWriter, r *http.Reque
 	localEventsFound := false
 
 	// run query to get local events
-	events, err := model.GetFacebookEvents(c.db, pageID, startTimeStr, endTimeStr)
+	events, err := model.GetFacebookEvents(c.db, pageID, startTimeStr, endTimeStr, false)
 	if err != nil {
 		panic(err)
 	}
@@ -1468,7 +2538,7 @@ func (c MainController) ListFBEventsHandler(w http.ResponseWriter, r *http.Reque
 
 	if !localEventsFound {
 		// get online SF Bay + ALOA events instead
-		events, err = model.GetOnlineFacebookEvents(c.db, startTimeStr, endTimeStr)
+		events, err = model.GetOnlineFacebookEvents(c.db, startTimeStr, endTimeStr, false)
 		if err != nil {
 			panic(err)
 		}
@@ -1555,6 +2625,46 @@ func (c MainController) ListAllFBPages(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, pages)
 }
 
+// How long clients may cache /groups without revalidating.
+const publicGroupsMaxAge = 5 * time.Minute
+
+// ListPublicGroups lists the visible groups for our website. The type
+// query parameter can be repeated to list groups of several types. If
+// lat and lng are given, only groups within radius miles (25 by
+// default) are listed, closest first.
+func (c MainController) ListPublicGroups(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := model.PublicGroupOptions{
+		Types:       query["type"],
+		RadiusMiles: 25,
+	}
+	if query.Get("lat") != "" || query.Get("lng") != "" {
+		lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+		lng, lngErr := strconv.ParseFloat(query.Get("lng"), 64)
+		if latErr != nil || lngErr != nil {
+			sendErrorMessage(w, errors.New("lat and lng must both be numbers"))
+			return
+		}
+		options.Near, options.Lat, options.Lng = true, lat, lng
+	}
+	if s := query.Get("radius"); s != "" {
+		radius, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			sendErrorMessage(w, errors.Errorf("Not a valid radius: %s", s))
+			return
+		}
+		options.RadiusMiles = radius
+	}
+
+	groups, err := model.GetPublicGroups(c.db, options)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeCacheableJSON(w, r, groups, publicGroupsMaxAge)
+}
+
 func (c MainController) ListAllChapters(w http.ResponseWriter, r *http.Request) {
 	// run query
 	chapters, err := model.GetAllChaptersWithoutTokens(c.db)
@@ -1566,6 +2676,260 @@ func (c MainController) ListAllChapters(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, chapters)
 }
 
+// How far back calendar feeds go. Calendar clients keep events
+// they've already seen, so there's no need to send the full history.
+const (
+	facebookCalendarHistory = 30 * 24 * time.Hour
+	eventCalendarHistory    = 180 * 24 * time.Hour
+)
+
+// calendarUIDDomain is appended to calendar event UIDs to make them
+// globally unique.
+func calendarUIDDomain() string {
+	u, err := url.Parse(config.UrlPath)
+	if err != nil || u.Host == "" {
+		return "adb.dxe.io"
+	}
+	return u.Host
+}
+
+func writeCalendar(w http.ResponseWriter, cal ical.Calendar) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := ical.Write(w, cal, time.Now()); err != nil {
+		panic(err)
+	}
+}
+
+func facebookEventsToCalendar(name string, events []model.FacebookEventOutput) ical.Calendar {
+	domain := calendarUIDDomain()
+	cal := ical.Calendar{Name: name}
+	for _, e := range events {
+		status := ical.StatusConfirmed
+		if e.IsCanceled {
+			status = ical.StatusCancelled
+		}
+		var location []string
+		for _, l := range []string{e.LocationName, e.LocationAddress, e.LocationCity, e.LocationState, e.LocationCountry} {
+			if l != "" {
+				location = append(location, l)
+			}
+		}
+		cal.Events = append(cal.Events, ical.Event{
+			UID:          fmt.Sprintf("fb-event-%d@%s", e.ID, domain),
+			Summary:      e.Name,
+			Description:  e.Description,
+			Location:     strings.Join(location, ", "),
+			URL:          fmt.Sprintf("https://www.facebook.com/events/%d", e.ID),
+			Status:       status,
+			Start:        e.StartTime,
+			End:          e.EndTime,
+			LastModified: e.LastUpdate,
+		})
+	}
+	return cal
+}
+
+func eventsToCalendar(name string, events []model.Event) ical.Calendar {
+	domain := calendarUIDDomain()
+	cal := ical.Calendar{Name: name}
+	for _, e := range events {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         fmt.Sprintf("event-%d@%s", e.ID, domain),
+			Summary:     e.EventName,
+			Description: string(e.EventType),
+			URL:         fmt.Sprintf("%s/update_event/%d", config.UrlPath, e.ID),
+			// ADB events only have a date, so they're
+			// all-day events.
+			Start:  e.EventDate,
+			AllDay: true,
+		})
+	}
+	return cal
+}
+
+// How long wallboard data is reused before querying the database
+// again, so that displays refreshing often don't overload it.
+const wallboardCacheDuration = time.Minute
+
+type wallboardCacheEntry struct {
+	data    model.WallboardData
+	fetched time.Time
+}
+
+// Wallboard data by chapter page ID.
+var wallboardCache = struct {
+	sync.Mutex
+	entries map[int]wallboardCacheEntry
+}{entries: map[int]wallboardCacheEntry{}}
+
+func getWallboardData(db *sqlx.DB, pageID int) (model.WallboardData, error) {
+	// Holding the lock while querying means that displays whose
+	// cache expired at the same time only query the database once.
+	wallboardCache.Lock()
+	defer wallboardCache.Unlock()
+
+	now := time.Now()
+	entry, ok := wallboardCache.entries[pageID]
+	if ok && now.Sub(entry.fetched) < wallboardCacheDuration {
+		return entry.data, nil
+	}
+	data, err := model.GetWallboardData(db, pageID, now)
+	if err != nil {
+		return model.WallboardData{}, err
+	}
+	wallboardCache.entries[pageID] = wallboardCacheEntry{data: data, fetched: now}
+	return data, nil
+}
+
+func (c MainController) WallboardHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := model.GetWallboardToken(c.db, mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	data, err := getWallboardData(c.db, token.PageID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	out := map[string]interface{}{
+		"status":    "success",
+		"wallboard": data,
+	}
+	writeJSON(w, out)
+}
+
+func (c MainController) ChapterCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	pageID, err := strconv.Atoi(mux.Vars(r)["page_id"])
+	if err != nil {
+		http.Error(w, http.StatusText(400), 400)
+		return
+	}
+	startTime := time.Now().Add(-facebookCalendarHistory).UTC().Format("2006-01-02")
+
+	// Unlike ListFBEventsHandler, don't fall back to online events
+	// if the chapter doesn't have any: subscribers expect the feed
+	// to only have the chapter's events. Online events have their
+	// own feed.
+	events, err := model.GetFacebookEvents(c.db, pageID, startTime, "", true)
+	if err != nil {
+		panic(err)
+	}
+
+	writeCalendar(w, facebookEventsToCalendar("DxE Events", events))
+}
+
+func (c MainController) OnlineCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	startTime := time.Now().Add(-facebookCalendarHistory).UTC().Format("2006-01-02")
+	events, err := model.GetOnlineFacebookEvents(c.db, startTime, "", true)
+	if err != nil {
+		panic(err)
+	}
+
+	writeCalendar(w, facebookEventsToCalendar("DxE Online Events", events))
+}
+
+func (c MainController) EventsCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := model.GetCalendarToken(c.db, mux.Vars(r)["token"])
+	if err != nil || token.ADBUserID == 0 {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+	// The feed is only valid for as long as its owner can still
+	// see events in the ADB.
+	user, err := model.GetADBUser(c.db, token.ADBUserID, "")
+	if err != nil || user.Disabled || !userIsAllowed([]string{"admin", "organizer", "attendance"}, user) {
+		http.Error(w, http.StatusText(403), 403)
+		return
+	}
+
+	options := model.GetEventOptions{
+		OrderBy:   "e.date DESC, e.id DESC",
+		DateFrom:  time.Now().Add(-eventCalendarHistory).Format(model.EventDateLayout),
+		EventType: r.URL.Query().Get("event_type"),
+	}
+	if wg := r.URL.Query().Get("working_group_id"); wg != "" {
+		options.WorkingGroupID, err = strconv.Atoi(wg)
+		if err != nil {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	if g := r.URL.Query().Get("group_id"); g != "" {
+		options.GroupID, err = strconv.Atoi(g)
+		if err != nil {
+			http.Error(w, http.StatusText(400), 400)
+			return
+		}
+	}
+	events, err := model.GetEvents(c.db, options)
+	if err != nil {
+		panic(err)
+	}
+
+	writeCalendar(w, eventsToCalendar("ADB Events", events))
+}
+
+func (c MainController) ActivistCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := model.GetCalendarToken(c.db, mux.Vars(r)["token"])
+	if err != nil || token.ActivistID == 0 {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+
+	// The ADB doesn't have RSVPs, so the feed has the upcoming
+	// events the activist is already on the attendance of, and the
+	// upcoming meetings of their groups.
+	events, err := model.GetEvents(c.db, model.GetEventOptions{
+		OrderBy:              "e.date DESC, e.id DESC",
+		DateFrom:             time.Now().Format(model.EventDateLayout),
+		PlannedForActivistID: token.ActivistID,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	writeCalendar(w, eventsToCalendar("My DxE Events", events))
+}
+
+func (c MainController) CalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	user, _ := getAuthedADBUser(c.db, r)
+
+	token, err := model.GetUserCalendarToken(c.db, user.ID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"url":    config.UrlPath + "/ical/events/" + token + ".ics",
+	})
+}
+
+func (c MainController) ActivistCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		ActivistID int `json:"activist_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&requestData)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	token, err := model.GetActivistCalendarToken(c.db, requestData.ActivistID)
+	if err != nil {
+		sendErrorMessage(w, err)
+		return
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"url":    config.UrlPath + "/ical/activist/" + token + ".ics",
+	})
+}
+
 func (c MainController) discordBotAuthMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -1837,6 +3201,21 @@ func main() {
 	// Start syncing Facebook events
 	go facebook_events.StartFacebookSync(db)
 
+	// Start purging deleted events once they're past retention
+	go event_purger.StartEventPurger(db)
+
+	// Start recording monthly MPI snapshots
+	go mpi_snapshots.StartMPISnapshots(db)
+
+	// Start reviewing chapter members' MPI status every month
+	go cm_status.StartCMStatusReviews(db)
+
+	// Start looking up circle prospects' addresses if we have the
+	// environment set up.
+	if config.GoogleMapsKey != "" {
+		go circle_geocoder.StartCircleProspectGeocoding(db)
+	}
+
 	// Set up server
 	n.UseHandler(r)
 
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"html/template"
 	"sort"
+	"time"
+
+	"github.com/dxe/adb/mpi"
 )
 
 // TODO(mdempsky): Use adb_users instead?
@@ -42,22 +45,19 @@ func (s *server) index() {
 		ChapterMember   int // boolean
 		VotingAgreement int // boolean
 
-		// Past3 and Past12 are how many of the past 3 and 12
-		// months, respectively, the activist fulfilled MPI
-		// requirements.
-		//
-		// Approved6 is whether the member was an approved
-		// Chapter Member for the prior 6 months.
-		ThisPast3, ThisPast12, ThisApproved6 int
-		NextPast3, NextPast12, NextApproved6 int
-		ThisMonth, NextMonth                 string // "Month Year"
+		CMApprovalEmail string // "YYYY-MM-DD"
+
+		// This and Next are the MPI and voting rules as of the
+		// start of this month and next month.
+		This, Next           mpi.Evaluation
+		ThisMonth, NextMonth string // "Month Year"
 
 		WorkingGroups []string
 
 		Total      int
 		Attendance []struct {
 			Month        int // YYYYMM
-			MPI          int // boolean
+			MPI          int // boolean, computed by the mpi package
 			Community    int // boolean
 			DirectAction int // boolean
 			Events       []struct {
@@ -88,32 +88,25 @@ select json_object(
   'ChapterMember',   x.activist_level in ('Chapter Member', 'Organizer'),
   'VotingAgreement', x.voting_agreement,
 
-  'ThisMonth',     date_format(now(), '%M %Y'),
-  'ThisPast3',     sum(x.mpi and x.month >= extract(year_month from date_add(now(), interval -3 month))
-                             and x.month <  extract(year_month from date_add(now(), interval 0 month))),
-  'ThisPast12',    sum(x.mpi and x.month >= extract(year_month from date_add(now(), interval -12 month))
-                             and x.month <  extract(year_month from date_add(now(), interval 0 month))),
-  'ThisApproved6', cm_approval_email < date_format(date_sub(now(), interval 6 month), '%Y-%m-01'),
+  'CMApprovalEmail', x.cm_approval_email,
 
+  'ThisMonth',     date_format(now(), '%M %Y'),
   'NextMonth',     date_format(date_add(now(), interval 1 month), '%M %Y'),
-  'NextPast3',     sum(x.mpi and x.month >= extract(year_month from date_add(now(), interval -2 month))
-                             and x.month <  extract(year_month from date_add(now(), interval 1 month))),
-  'NextPast12',    sum(x.mpi and x.month >= extract(year_month from date_add(now(), interval -11 month))
-                             and x.month <  extract(year_month from date_add(now(), interval 1 month))),
-  'NextApproved6', cm_approval_email < date_format(date_sub(now(), interval 5 month), '%Y-%m-01'),
 
   'WorkingGroups', (
     select json_arrayagg(w.name)
-    from working_groups w
-    join working_group_members m on (w.id = m.working_group_id)
+    from activist_groups w
+    join activist_group_members m on (w.id = m.group_id)
     where m.activist_id = x.id
+      and m.role in ('lead', 'co_lead', 'member')
+      and w.type in ('working_group', 'committee')
+      and w.archived = 0
   ),
 
   'Total', sum(x.subtotal),
   'Attendance', if(sum(x.subtotal) = 0, null,
     json_arrayagg(json_object(
       'Month', x.month,
-      'MPI', x.mpi,
       'Community', x.community,
       'DirectAction', x.direct_action,
       'Events', x.events
@@ -123,7 +116,6 @@ from (
   select a.id, a.name, a.email, a.phone, a.location, a.facebook, a.activist_level, a.dob, a.date_organizer, a.cm_approval_email, a.voting_agreement,
     e.month, count(e.id) as subtotal,
     max(e.community) as community, max(e.direct_action) as direct_action,
-    (max(e.direct_action) and (max(e.community) or e.month >= 202001)) as mpi,
     json_arrayagg(json_object(
       'Date', e.date,
       'Name', e.name,
@@ -133,12 +125,13 @@ from (
   from activists a
   left join event_attendance ea on (a.id = ea.activist_id)
   left join (
-          select id, date,
-                 concat(name, if(event_type = 'Connection', ' (Connection)', '')) as name,
+          select events.id, date,
+                 concat(events.name, if(event_type = 'Connection', ' (Connection)', '')) as name,
                  extract(year_month from date) as month,
-                 event_type in ('Circle', 'Community', 'Training') as community,
-                 event_type in ('Action', 'Campaign Action', 'Frontline Surveillance', 'Outreach', 'Sanctuary') as direct_action
+                 ifnull(et.mpi_category = 'community', 0) as community,
+                 ifnull(et.mpi_category = 'direct_action', 0) as direct_action
           from events
+          left join event_types et on (et.name = events.event_type)
         ) e on (e.id = ea.event_id)
   where a.email = ?
     and not a.hidden
@@ -156,6 +149,26 @@ group by x.id
 		return
 	}
 
+	activist := mpi.Activist{
+		Level:           data.ActivistLevel,
+		CMApprovalEmail: data.CMApprovalEmail,
+		VotingAgreement: data.VotingAgreement != 0,
+	}
+	for k, att := range data.Attendance {
+		month := mpi.Month{
+			Month:        att.Month,
+			DirectAction: att.DirectAction != 0,
+			Community:    att.Community != 0,
+		}
+		if mpi.Met(month) {
+			data.Attendance[k].MPI = 1
+		}
+		activist.Attendance = append(activist.Attendance, month)
+	}
+	thisMonth := mpi.MonthOf(time.Now())
+	data.This = mpi.Evaluate(activist, thisMonth)
+	data.Next = mpi.Evaluate(activist, mpi.AddMonths(thisMonth, 1))
+
 	// Manually sort in descending order by date, as MySQL doesn't
 	// allow control of json_arrayagg()'s aggregation order.
 	sort.Slice(data.Attendance, func(i, j int) bool { return data.Attendance[i].Month > data.Attendance[j].Month })
@@ -296,13 +309,13 @@ table.profile td:nth-child(1), table.election, td:nth-child(1) {
 </tr>
 <tr>
   <td>{{.ThisMonth}}</td>
-  <td>{{.ThisPast3}}</td>
-  <td>{{if ge .ThisPast3 2}}Yes{{else}}No{{end}}</td>
+  <td>{{.This.Past3}}</td>
+  <td>{{if .This.VotingEligible}}Yes{{else}}No{{end}}</td>
 </tr>
 <tr>
   <td>{{.NextMonth}}</td>
-  <td>{{.NextPast3}}</td>
-  <td>{{if ge .NextPast3 2}}Yes{{else}}No{{end}}</td>
+  <td>{{.Next.Past3}}</td>
+  <td>{{if .Next.VotingEligible}}Yes{{else}}No{{end}}</td>
 </tr>
 </table>
 {{else if .ChapterMember}}
@@ -318,17 +331,17 @@ table.profile td:nth-child(1), table.election, td:nth-child(1) {
 </tr>
 <tr>
   <td>{{.ThisMonth}}</td>
-  <td>{{if .ThisApproved6}}Yes{{else}}No{{end}}</td>
-  <td>{{.ThisPast12}}</td>
+  <td>{{if .This.Approved6}}Yes{{else}}No{{end}}</td>
+  <td>{{.This.Past12}}</td>
   <td>{{if .VotingAgreement}}Yes{{else}}No{{end}}</td>
-  <td>{{if and .ThisApproved6 (ge .ThisPast12 8) .VotingAgreement}}Yes{{else}}No{{end}}</td>
+  <td>{{if .This.VotingEligible}}Yes{{else}}No{{end}}</td>
 </tr>
 <tr>
   <td>{{.NextMonth}}</td>
-  <td>{{if .NextApproved6}}Yes{{else}}No{{end}}</td>
-  <td>{{.NextPast12}}</td>
+  <td>{{if .Next.Approved6}}Yes{{else}}No{{end}}</td>
+  <td>{{.Next.Past12}}</td>
   <td>{{if .VotingAgreement}}Yes{{else}}No{{end}}</td>
-  <td>{{if and .NextApproved6 (ge .NextPast12 8) .VotingAgreement}}Yes{{else}}No{{end}}</td>
+  <td>{{if .Next.VotingEligible}}Yes{{else}}No{{end}}</td>
 </tr>
 </table>
 {{else}}
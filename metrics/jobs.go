@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_run_timestamp_seconds",
+		Help:      "When the last run of a background job finished.",
+	}, []string{"job"})
+	jobLastDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_duration_seconds",
+		Help:      "How long the last run of a background job took.",
+	}, []string{"job"})
+	jobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Number of runs of a background job by result (success or failure).",
+	}, []string{"job", "result"})
+	jobItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_items_total",
+		Help:      "Number of items changed or emails sent by a background job.",
+	}, []string{"job"})
+)
+
+// JobRun is a run of a background job. Jobs log their errors and
+// carry on, so they call Fail on errors rather than returning them.
+type JobRun struct {
+	job    string
+	start  time.Time
+	failed bool
+}
+
+func StartJobRun(job string) *JobRun {
+	return &JobRun{job: job, start: time.Now()}
+}
+
+// AddItems counts items changed or emails sent.
+func (r *JobRun) AddItems(n int) {
+	jobItems.WithLabelValues(r.job).Add(float64(n))
+}
+
+// Fail marks the run as failed. It keeps running.
+func (r *JobRun) Fail() {
+	r.failed = true
+}
+
+// Failed reports whether Fail was called.
+func (r *JobRun) Failed() bool {
+	return r.failed
+}
+
+func (r *JobRun) Finish() {
+	now := time.Now()
+	jobLastRun.WithLabelValues(r.job).Set(float64(now.Unix()))
+	jobLastDuration.WithLabelValues(r.job).Set(now.Sub(r.start).Seconds())
+	result := "success"
+	if r.failed {
+		result = "failure"
+	}
+	jobRuns.WithLabelValues(r.job, result).Inc()
+}
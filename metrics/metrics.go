@@ -0,0 +1,170 @@
+// Package metrics exposes Prometheus metrics for HTTP handlers,
+// background jobs, the database pool and a few numbers about the
+// chapter.
+package metrics
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dxe/adb/model"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/urfave/negroni"
+)
+
+const namespace = "adb"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpRequestDuration)
+	prometheus.MustRegister(jobLastRun, jobLastDuration, jobRuns, jobItems)
+}
+
+// Register registers the collectors that read from the database. It
+// should only be called once.
+func Register(db *sqlx.DB) {
+	prometheus.MustRegister(dbStatsCollector{db: db}, domainCollector{db: db})
+}
+
+// Handler serves the metrics to requests that have token as a bearer
+// token. The metrics include counts of activists, so nothing is served
+// if token is empty.
+func Handler(token string) http.Handler {
+	h := promhttp.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		want := []byte("Bearer " + token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Middleware counts requests and their latencies by the path template
+// of the matched route, so it must be used on a mux router.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if tpl, err := current.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		rw := negroni.NewResponseWriter(w)
+		next.ServeHTTP(rw, r)
+
+		code := rw.Status()
+		if code == 0 {
+			// Nothing was written, which net/http sends as a
+			// 200.
+			code = http.StatusOK
+		}
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(code)).Inc()
+		httpRequestDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+var (
+	dbOpenConnections = prometheus.NewDesc(namespace+"_db_open_connections",
+		"Number of open database connections.", nil, nil)
+	dbInUseConnections = prometheus.NewDesc(namespace+"_db_in_use_connections",
+		"Number of database connections in use.", nil, nil)
+	dbIdleConnections = prometheus.NewDesc(namespace+"_db_idle_connections",
+		"Number of idle database connections.", nil, nil)
+	dbMaxOpenConnections = prometheus.NewDesc(namespace+"_db_max_open_connections",
+		"Maximum number of open database connections, or 0 if unlimited.", nil, nil)
+	dbWaitCount = prometheus.NewDesc(namespace+"_db_wait_count_total",
+		"Number of times a database connection was waited for.", nil, nil)
+	dbWaitDuration = prometheus.NewDesc(namespace+"_db_wait_duration_seconds_total",
+		"Total time spent waiting for database connections.", nil, nil)
+)
+
+type dbStatsCollector struct {
+	db *sqlx.DB
+}
+
+func (c dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- dbOpenConnections
+	ch <- dbInUseConnections
+	ch <- dbIdleConnections
+	ch <- dbMaxOpenConnections
+	ch <- dbWaitCount
+	ch <- dbWaitDuration
+}
+
+func (c dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(dbOpenConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbInUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(dbIdleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(dbMaxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(dbWaitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(dbWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+}
+
+var (
+	mpiDesc = prometheus.NewDesc(namespace+"_mpi",
+		"Number of activists who are MPI.", nil, nil)
+	activeChapterMembersDesc = prometheus.NewDesc(namespace+"_active_chapter_members",
+		"Number of chapter members and organizers who are MPI.", nil, nil)
+	activistsDesc = prometheus.NewDesc(namespace+"_activists",
+		"Number of activists by level.", []string{"level"}, nil)
+)
+
+// domainCollector queries the numbers on every scrape. The queries
+// only count rows, so they're cheap enough.
+type domainCollector struct {
+	db *sqlx.DB
+}
+
+func (c domainCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- mpiDesc
+	ch <- activeChapterMembersDesc
+	ch <- activistsDesc
+}
+
+func (c domainCollector) Collect(ch chan<- prometheus.Metric) {
+	if mpi, err := model.GetPower(c.db); err != nil {
+		log.Println("ERROR: failed to collect MPI metric:", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(mpiDesc, prometheus.GaugeValue, float64(mpi))
+	}
+	if members, err := model.GetActiveChapterMembers(c.db); err != nil {
+		log.Println("ERROR: failed to collect active chapter members metric:", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(activeChapterMembersDesc, prometheus.GaugeValue, float64(members))
+	}
+	if levels, err := model.GetActivistLevelCounts(c.db); err != nil {
+		log.Println("ERROR: failed to collect activist level metrics:", err)
+	} else {
+		for level, n := range levels {
+			ch <- prometheus.MustNewConstMetric(activistsDesc, prometheus.GaugeValue, float64(n), level)
+		}
+	}
+}
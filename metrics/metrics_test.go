@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/event/get/{event_id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {})
+	router.HandleFunc("/event/save", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	})
+
+	for _, path := range []string{"/event/get/1", "/event/get/2", "/event/save"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("/event/get/{event_id:[0-9]+}", "GET", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("/event/save", "GET", "400")))
+}
+
+func TestJobRun(t *testing.T) {
+	run := StartJobRun("test_job")
+	run.AddItems(3)
+	run.Finish()
+
+	run = StartJobRun("test_job")
+	run.Fail()
+	run.Finish()
+
+	require.Equal(t, 3.0, testutil.ToFloat64(jobItems.WithLabelValues("test_job")))
+	require.Equal(t, 1.0, testutil.ToFloat64(jobRuns.WithLabelValues("test_job", "success")))
+	require.Equal(t, 1.0, testutil.ToFloat64(jobRuns.WithLabelValues("test_job", "failure")))
+}
+
+func TestHandlerToken(t *testing.T) {
+	h := Handler("secret")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Without a token, the metrics aren't served at all.
+	w = httptest.NewRecorder()
+	Handler("").ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dxe/adb/mpi"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
@@ -36,7 +37,7 @@ FROM activists
 //    how they effect performance
 //  - It seems like it's usually faster to use subqueries in the top
 //    part of the SELECT expression vs joining on a table.
-const selectActivistExtraBaseQuery string = `
+var selectActivistExtraBaseQuery = `
 SELECT
 
   lower(email) as email,
@@ -142,7 +143,8 @@ SELECT
     mpi,
     notes,
     vision_wall,
-    mpp_requirements,
+    mpi_direct_action,
+    mpi_community,
     voting_agreement,
     street_address,
     city,
@@ -151,32 +153,29 @@ SELECT
 
 FROM activists a
 
-LEFT JOIN (
-  SELECT
-      activist_id, count(ea.event_id) as totalPoints
-      FROM event_attendance ea
-      JOIN events e ON e.id = ea.event_id
-      WHERE
-        e.date BETWEEN (NOW() - INTERVAL 30 DAY) AND NOW()
-      GROUP BY activist_id
+LEFT JOIN (` + activistPointsQuery() + `
 ) points
   ON points.activist_id = a.id
 
 LEFT JOIN (
 
+  -- Which kinds of events were attended this month. The MPI rules
+  -- are applied in Go, see the mpi package.
   select
     id as activist_id_mpp,
-      IF(is_protest = 1 and is_community = 1, 'Fulfilling requirements',IF(is_protest = 1 and is_community = 0, 'Missing Community event',IF(is_protest = 0 and is_community = 1, 'Missing DA event','Missing Community & DA events'))) as MPP_Requirements
+    IFNULL(is_protest, 0) as mpi_direct_action,
+    IFNULL(is_community, 0) as mpi_community
   from activists
 
   left join (
     select
         activist_id,
-        max((CASE WHEN ((e.event_type = 'action') OR (e.event_type = 'outreach') OR (e.event_type = 'frontline surveillance') OR (e.event_type = 'sanctuary') OR (e.event_type = 'campaign action')) THEN '1' ELSE '0' END)) AS is_protest,
-        max((CASE WHEN ((e.event_type = 'community') OR (e.event_type = 'training') OR (e.event_type = 'circle')) THEN '1' ELSE '0' END)) AS is_community  
+        max(et.mpi_category = 'direct_action') AS is_protest,
+        max(et.mpi_category = 'community') AS is_community
     from
         event_attendance ea
     join events e on e.id = ea.event_id
+    join event_types et on et.name = e.event_type
     where
         YEAR(e.date) = YEAR(now()) AND (MONTH(e.date) = MONTH(now()))
     group by ea.activist_id   
@@ -299,7 +298,8 @@ type ActivistConnectionData struct {
 	MPI                   bool           `db:"mpi"`
 	Notes                 sql.NullString `db:"notes"`
 	VisionWall            string         `db:"vision_wall"`
-	MPPRequirements       string         `db:"mpp_requirements"`
+	MPIDirectAction       bool           `db:"mpi_direct_action"`
+	MPICommunity          bool           `db:"mpi_community"`
 	VotingAgreement       bool           `db:"voting_agreement"`
 	StreetAddress         string         `db:"street_address"`
 	City                  string         `db:"city"`
@@ -500,6 +500,12 @@ func buildActivistJSONArray(activists []ActivistExtra) []ActivistJSON {
 		if a.ActivistConnectionData.LastConnection.Valid {
 			last_connection = a.ActivistConnectionData.LastConnection.String
 		}
+		mpp_requirements := mpi.Requirements(mpi.Month{
+			Month:        mpi.MonthOf(time.Now()),
+			DirectAction: a.MPIDirectAction,
+			Community:    a.MPICommunity,
+		})
+
 		cm_first_email := ""
 		if a.ActivistConnectionData.CMFirstEmail.Valid {
 			cm_first_email = a.ActivistConnectionData.CMFirstEmail.String
@@ -582,7 +588,7 @@ func buildActivistJSONArray(activists []ActivistExtra) []ActivistJSON {
 			MPI:                   a.MPI,
 			Notes:                 notes,
 			VisionWall:            a.VisionWall,
-			MPPRequirements:       a.MPPRequirements,
+			MPPRequirements:       mpp_requirements,
 			VotingAgreement:       a.VotingAgreement,
 			StreetAddress:         a.StreetAddress,
 			City:                  a.City,
@@ -731,7 +737,8 @@ func GetActivistsExtra(db *sqlx.DB, options GetActivistOptions) ([]ActivistExtra
 			whereClause = append(whereClause, "circle_interest = 1")
 		}
 		if options.Filter == "leaderboard" {
-			whereClause = append(whereClause, "a.id in (select distinct activist_id  from event_attendance ea  where ea.event_id in (select id from events e where e.date >= (now() - interval 30 day)))")
+			whereClause = append(whereClause, fmt.Sprintf("a.id in (select distinct activist_id  from event_attendance ea  where ea.event_id in (select id from events e where e.date >= (now() - interval %d day)))",
+				leaderboardWindows[defaultLeaderboardWindow]))
 		}
 
 		if len(whereClause) != 0 {
@@ -897,6 +904,11 @@ func GetOrCreateActivist(db *sqlx.DB, name string) (Activist, error) {
 }
 
 func CreateActivist(db *sqlx.DB, activist ActivistExtra) (int, error) {
+	return createActivist(db, activist)
+}
+
+// createActivist is CreateActivist, but can be run in a transaction.
+func createActivist(db sqlx.Ext, activist ActivistExtra) (int, error) {
 	if activist.ID != 0 {
 		return 0, errors.New("Activist ID must be 0")
 	}
@@ -904,7 +916,7 @@ func CreateActivist(db *sqlx.DB, activist ActivistExtra) (int, error) {
 		return 0, errors.New("Name cannot be empty")
 	}
 
-	result, err := db.NamedExec(`
+	result, err := sqlx.NamedExec(db, `
 INSERT INTO activists (
 
   email,
@@ -1003,6 +1015,17 @@ INSERT INTO activists (
 	if err != nil {
 		return 0, errors.Wrapf(err, "Could not get LastInsertId for %s", activist.Name)
 	}
+	if activist.ActivistLevel != "" && activist.ActivistLevel != "Supporter" {
+		err := insertLevelChange(db, LevelChange{
+			ActivistID: int(id),
+			NewLevel:   activist.ActivistLevel,
+			ChangedAt:  time.Now(),
+			Source:     LevelChangeSourceUpdate,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
 	return int(id), nil
 }
 
@@ -1014,6 +1037,11 @@ func UpdateActivistData(db *sqlx.DB, activist ActivistExtra, userEmail string) (
 		return 0, errors.New("Name cannot be empty")
 	}
 
+	var oldLevel string
+	if err := db.Get(&oldLevel, `SELECT activist_level FROM activists WHERE id = ?`, activist.ID); err != nil {
+		return 0, errors.Wrapf(err, "failed to select level of activist %d", activist.ID)
+	}
+
 	_, err := db.NamedExec(`UPDATE activists
 SET
 
@@ -1066,6 +1094,20 @@ WHERE
 		return 0, errors.Wrap(err, "failed to update activist data")
 	}
 
+	if activist.ActivistLevel != oldLevel {
+		err := insertLevelChange(db, LevelChange{
+			ActivistID: activist.ID,
+			OldLevel:   oldLevel,
+			NewLevel:   activist.ActivistLevel,
+			ChangedAt:  time.Now(),
+			Source:     LevelChangeSourceUpdate,
+			UserEmail:  userEmail,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
 	// LOGGING (work in progress)
 	_, err = db.NamedExec(`INSERT INTO activists_history (activist_id, action, user_email, name, email, facebook, activist_level)
 	VALUES (
@@ -1114,7 +1156,8 @@ func HideActivist(db *sqlx.DB, activistID int) error {
 // Merge activistID into targetActivistID.
 //  - The original activist is hidden
 //  - All of the original activist's event attendance is updated to be the target activist.
-func MergeActivist(db *sqlx.DB, originalActivistID, targetActivistID int) error {
+//  - The attendance changes are logged as made by user.
+func MergeActivist(db *sqlx.DB, originalActivistID, targetActivistID int, user ADBUser) error {
 	if originalActivistID == 0 {
 		return errors.New("originalActivistID cannot be 0")
 	}
@@ -1136,19 +1179,36 @@ func MergeActivist(db *sqlx.DB, originalActivistID, targetActivistID int) error
 		return errors.Wrapf(err, "failed to hide original activist %d", originalActivistID)
 	}
 
-	err = updateMergedActivistData(tx, originalActivistID, targetActivistID, true)
+	change := AttendanceChange{User: user, Source: AttendanceSourceMerge}
+	err = updateMergedActivistData(tx, originalActivistID, targetActivistID, true, change)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	err = updateMergedActivistData(tx, originalActivistID, targetActivistID, false)
+	err = updateMergedActivistData(tx, originalActivistID, targetActivistID, false, change)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
+	// Move attendance of archived events too, so that it isn't
+	// restored to the hidden activist.
+	_, err = tx.Exec(`
+UPDATE IGNORE archived_event_attendance
+SET activist_id = ?
+WHERE activist_id = ?`, targetActivistID, originalActivistID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to merge archived attendance of activist %d", originalActivistID)
+	}
+	_, err = tx.Exec(`DELETE FROM archived_event_attendance WHERE activist_id = ?`, originalActivistID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to merge archived attendance of activist %d", originalActivistID)
+	}
+
 	// Merge Activist data details
-	err = updateMergedActivistDataDetails(tx, originalActivistID, targetActivistID)
+	err = updateMergedActivistDataDetails(tx, originalActivistID, targetActivistID, user)
 	if err != nil {
 		tx.Rollback()
 		return err
@@ -1164,7 +1224,7 @@ func MergeActivist(db *sqlx.DB, originalActivistID, targetActivistID int) error
 	return nil
 }
 
-func updateMergedActivistData(tx *sqlx.Tx, originalActivistID int, targetActivistID int, originalActivistOnly bool) error {
+func updateMergedActivistData(tx *sqlx.Tx, originalActivistID int, targetActivistID int, originalActivistOnly bool, change AttendanceChange) error {
 	baseQuery := `
 SELECT event_id
 FROM event_attendance ea
@@ -1226,6 +1286,18 @@ WHERE
 		return errors.Wrapf(err, "could not update event attendance for activist: %d",
 			originalActivistID)
 	}
+	for _, eventID := range eventIDs {
+		if err := logAttendanceChange(tx, change, eventID, originalActivistID, AttendanceActionRemove); err != nil {
+			return err
+		}
+		// If both activists attended, the target activist's
+		// attendance is unchanged.
+		if originalActivistOnly {
+			if err := logAttendanceChange(tx, change, eventID, targetActivistID, AttendanceActionAdd); err != nil {
+				return err
+			}
+		}
+	}
 	err = insertMergedActivistAttendance(tx, originalActivistID, targetActivistID, eventIDs, originalActivistOnly)
 	if err != nil {
 		return err
@@ -1348,7 +1420,7 @@ func stringMergeSqlNullTime(original mysql.NullTime, target mysql.NullTime) mysq
 	return target
 }
 
-func updateMergedActivistDataDetails(tx *sqlx.Tx, originalActivistID int, targetActivistID int) error {
+func updateMergedActivistDataDetails(tx *sqlx.Tx, originalActivistID int, targetActivistID int, user ADBUser) error {
 	// Merge details of original activist into target activist
 	// Favor booleans that are set to TRUE, and pull in missing data from original activist to target; when both
 	// activists have data for the same field, we should use the target activist's data.
@@ -1375,6 +1447,22 @@ func updateMergedActivistDataDetails(tx *sqlx.Tx, originalActivistID int, target
 		return errors.Wrapf(err, "failed to update activist with id %d", targetActivistID)
 	}
 
+	// The merge can change the target's level, which the level
+	// funnel needs to know about.
+	if mergedActivist.ActivistLevel != targetActivist.ActivistLevel {
+		err := insertLevelChange(tx, LevelChange{
+			ActivistID: targetActivistID,
+			OldLevel:   targetActivist.ActivistLevel,
+			NewLevel:   mergedActivist.ActivistLevel,
+			ChangedAt:  time.Now(),
+			Source:     LevelChangeSourceUpdate,
+			UserEmail:  user.Email,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -1637,7 +1725,6 @@ func CleanActivistData(body io.Reader) (ActivistExtra, error) {
 			MPI:                   activistJSON.MPI,
 			Notes:                 sql.NullString{String: strings.TrimSpace(activistJSON.Notes), Valid: validNotes},
 			VisionWall:            strings.TrimSpace(activistJSON.VisionWall),
-			MPPRequirements:       strings.TrimSpace(activistJSON.MPPRequirements),
 			VotingAgreement:       activistJSON.VotingAgreement,
 			StreetAddress:         strings.TrimSpace(activistJSON.StreetAddress),
 			City:                  strings.TrimSpace(activistJSON.City),
@@ -394,7 +394,20 @@ func TestMergeActivist(t *testing.T) {
 	}}
 	mustInsertAllEvents(t, db, insertEvents)
 
-	require.NoError(t, MergeActivist(db, a1.ID, a2.ID))
+	_, err = db.Exec(`UPDATE activists SET activist_level = 'Organizer' WHERE id = ?`, a1.ID)
+	require.NoError(t, err)
+	require.NoError(t, MergeActivist(db, a1.ID, a2.ID, DevTestUser))
+
+	// The target took on the original's higher level, and the
+	// change is recorded.
+	var change LevelChange
+	require.NoError(t, db.Get(&change, `
+SELECT activist_id, old_level, new_level, changed_at, source, user_email
+FROM activist_level_changes
+WHERE activist_id = ?`, a2.ID))
+	require.Equal(t, "Supporter", change.OldLevel)
+	require.Equal(t, "Organizer", change.NewLevel)
+	require.Equal(t, DevTestUser.Email, change.UserEmail)
 
 	e1, err := GetEvent(db, GetEventOptions{EventID: 1})
 	require.NoError(t, err)
@@ -658,3 +671,24 @@ func reverseStringSlice(s []string) {
 		s[left], s[right] = s[right], s[left]
 	}
 }
+
+func TestBuildActivistJSONArray_MPPRequirements(t *testing.T) {
+	// Since 2020 a direct action is enough for MPI, so activists who
+	// only attended one this month aren't missing a community event.
+	activists := []ActivistExtra{
+		{ActivistConnectionData: ActivistConnectionData{MPIDirectAction: true, MPICommunity: true}},
+		{ActivistConnectionData: ActivistConnectionData{MPIDirectAction: true}},
+		{ActivistConnectionData: ActivistConnectionData{MPICommunity: true}},
+		{},
+	}
+	var requirements []string
+	for _, a := range buildActivistJSONArray(activists) {
+		requirements = append(requirements, a.MPPRequirements)
+	}
+	require.Equal(t, []string{
+		"Fulfilling requirements",
+		"Fulfilling requirements",
+		"Missing DA event",
+		"Missing DA event",
+	}, requirements)
+}
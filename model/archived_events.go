@@ -0,0 +1,173 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// ArchivedEvent is an event that was deleted. Its attendance is kept
+// in archived_event_attendance until the event is purged.
+type ArchivedEvent struct {
+	ID              int       `db:"id"`
+	EventID         int       `db:"event_id"`
+	EventName       string    `db:"name"`
+	EventDate       time.Time `db:"date"`
+	EventType       string    `db:"event_type"`
+	SurveySent      int       `db:"survey_sent"`
+	GroupID         int       `db:"group_id"`
+	ArchivedAt      time.Time `db:"archived_at"`
+	ArchivedByID    int       `db:"archived_by_user_id"`
+	ArchivedByEmail string    `db:"archived_by_email"`
+	TotalAttendees  int       `db:"total_attendees"`
+}
+
+/** Functions and Methods */
+
+func GetArchivedEvents(db *sqlx.DB) ([]ArchivedEvent, error) {
+	var events []ArchivedEvent
+	err := db.Select(&events, `
+SELECT
+  ae.id,
+  ae.event_id,
+  ae.name,
+  ae.date,
+  ae.event_type,
+  ae.survey_sent,
+  ae.archived_at,
+  ae.archived_by_user_id,
+  ae.archived_by_email,
+  (SELECT count(*) FROM archived_event_attendance aea WHERE aea.archived_event_id = ae.id) AS total_attendees
+FROM archived_events ae
+ORDER BY ae.archived_at DESC, ae.id DESC`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select archived events")
+	}
+	return events, nil
+}
+
+// RestoreArchivedEvent moves an archived event and its attendance
+// back, and returns the restored event's ID. The event keeps its old
+// ID unless another event has taken it in the meantime.
+func RestoreArchivedEvent(db *sqlx.DB, archivedEventID int, user ADBUser) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+
+	var archived ArchivedEvent
+	err = tx.Get(&archived, `
+SELECT id, event_id, name, date, event_type, survey_sent, group_id
+FROM archived_events
+WHERE id = ?`, archivedEventID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return 0, errors.Errorf("Archived event with id %d does not exist", archivedEventID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to select archived event %d", archivedEventID)
+	}
+
+	var idTaken int
+	err = tx.Get(&idTaken, `SELECT count(*) FROM events WHERE id = ?`, archived.EventID)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to get event count")
+	}
+	id := archived.EventID
+	if idTaken != 0 {
+		id = 0
+	}
+	res, err := tx.Exec(`
+INSERT INTO events (id, name, date, event_type, survey_sent, group_id)
+VALUES (NULLIF(?, 0), ?, ?, ?, ?, ?)`, id, archived.EventName, archived.EventDate, archived.EventType, archived.SurveySent, archived.GroupID)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to restore event %d", archived.EventID)
+	}
+	eventID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to get restored event id")
+	}
+
+	var attendees []struct {
+		ActivistID int    `db:"activist_id"`
+		Role       string `db:"role"`
+	}
+	err = tx.Select(&attendees, `
+SELECT activist_id, role
+FROM archived_event_attendance
+WHERE archived_event_id = ?`, archivedEventID)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to get archived attendance for event %d", archived.EventID)
+	}
+	change := AttendanceChange{User: user, Source: AttendanceSourceRestoreEvent}
+	for _, a := range attendees {
+		_, err := tx.Exec(`INSERT INTO event_attendance (activist_id, event_id, role) VALUES (?, ?, ?)`, a.ActivistID, eventID, a.Role)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "failed to restore attendance for event %d", eventID)
+		}
+		if err := logAttendanceChange(tx, change, int(eventID), a.ActivistID, AttendanceActionAdd); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := deleteArchivedEvents(tx, `id = ?`, archivedEventID); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to commit restoring event %d", eventID)
+	}
+	return int(eventID), nil
+}
+
+// PurgeArchivedEvents permanently deletes events archived before the
+// given time and returns how many were deleted.
+func PurgeArchivedEvents(db *sqlx.DB, archivedBefore time.Time) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+	var count int
+	err = tx.Get(&count, `SELECT count(*) FROM archived_events WHERE archived_at < ?`, archivedBefore)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to count archived events")
+	}
+	if err := deleteArchivedEvents(tx, `archived_at < ?`, archivedBefore); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to commit purging archived events")
+	}
+	return count, nil
+}
+
+func deleteArchivedEvents(tx *sqlx.Tx, filter string, args ...interface{}) error {
+	_, err := tx.Exec(`
+DELETE aea
+FROM archived_event_attendance aea
+JOIN archived_events ae ON ae.id = aea.archived_event_id
+WHERE ae.`+filter, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete archived event attendance")
+	}
+	_, err = tx.Exec(`DELETE FROM archived_events WHERE `+filter, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete archived events")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreArchivedEvent(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+
+	eventID, err := InsertUpdateEvent(db, Event{
+		EventName:      "event one",
+		EventDate:      time.Date(2017, 1, 15, 0, 0, 0, 0, time.UTC),
+		EventType:      "Working Group",
+		AddedAttendees: []Activist{a1, a2},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, DeleteEvent(db, eventID, DevTestUser))
+
+	events, err := GetEvents(db, GetEventOptions{})
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+
+	archived, err := GetArchivedEvents(db)
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	require.Equal(t, eventID, archived[0].EventID)
+	require.Equal(t, 2, archived[0].TotalAttendees)
+	require.Equal(t, DevTestUser.Email, archived[0].ArchivedByEmail)
+
+	restoredID, err := RestoreArchivedEvent(db, archived[0].ID, DevTestUser)
+	require.NoError(t, err)
+	require.Equal(t, eventID, restoredID)
+
+	attendees, err := GetEventAttendance(db, eventID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Hello", "Hi"}, attendees)
+
+	archived, err = GetArchivedEvents(db)
+	require.NoError(t, err)
+	require.Len(t, archived, 0)
+}
+
+func TestPurgeArchivedEvents(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	eventID, err := InsertUpdateEvent(db, Event{
+		EventName:      "event one",
+		EventDate:      time.Date(2017, 1, 15, 0, 0, 0, 0, time.UTC),
+		EventType:      "Working Group",
+		AddedAttendees: []Activist{a1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, DeleteEvent(db, eventID, DevTestUser))
+
+	n, err := PurgeArchivedEvents(db, time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	n, err = PurgeArchivedEvents(db, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	var remaining int
+	require.NoError(t, db.Get(&remaining, `SELECT count(*) FROM archived_event_attendance`))
+	require.Equal(t, 0, remaining)
+}
@@ -0,0 +1,375 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+const (
+	ImportLayoutEventbrite = "eventbrite"
+	ImportLayoutZoom       = "zoom"
+	ImportLayoutSignIn     = "sign_in_sheet"
+)
+
+// The activist source used for activists created by an import,
+// unless the user chooses a different one.
+var importLayoutSources = map[string]string{
+	ImportLayoutEventbrite: "Eventbrite",
+	ImportLayoutZoom:       "Zoom",
+	ImportLayoutSignIn:     "Sign-in Sheet",
+}
+
+// How an import row was matched to an activist.
+const (
+	ImportMatchEmail = "email"
+	ImportMatchName  = "name"
+	ImportMatchNew   = "new"
+)
+
+/** Type Definitions */
+
+type AttendanceImportRow struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+
+	// Filled in by PreviewAttendanceImport.
+	Match            string `json:"match"`
+	ActivistID       int    `json:"activist_id"`
+	ActivistName     string `json:"activist_name"`
+	AlreadyAttending bool   `json:"already_attending"`
+
+	// Only rows with Include set are imported.
+	Include bool `json:"include"`
+}
+
+type AttendanceImportPreview struct {
+	EventID int                   `json:"event_id"`
+	Layout  string                `json:"layout"`
+	Source  string                `json:"source"`
+	Rows    []AttendanceImportRow `json:"rows"`
+}
+
+// importColumns are the indexes of the columns we care about, or -1
+// if the file doesn't have them.
+type importColumns struct {
+	name, firstName, lastName, email, phone, status int
+}
+
+/** Functions and Methods */
+
+// ParseAttendanceCSV reads an Eventbrite attendee list, a Zoom
+// participant report or a plain sign-in sheet, and returns the
+// detected layout and one row per distinct attendee.
+func ParseAttendanceCSV(r io.Reader) (string, []AttendanceImportRow, error) {
+	reader := csv.NewReader(r)
+	// Zoom reports start with a meeting summary that has a
+	// different number of columns than the participant list.
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "could not read CSV file")
+	}
+
+	layout := ""
+	var cols importColumns
+	var i int
+	for i = 0; i < len(records); i++ {
+		layout, cols = detectImportLayout(records[i])
+		if layout != "" {
+			break
+		}
+	}
+	if layout == "" {
+		return "", nil, errors.New("Could not find a header row with a name column")
+	}
+
+	var rows []AttendanceImportRow
+	seen := map[string]bool{}
+	for _, record := range records[i+1:] {
+		row, ok := parseImportRecord(record, cols, layout)
+		if !ok {
+			continue
+		}
+		// People who leave and rejoin a Zoom meeting are
+		// listed once per session.
+		key := strings.ToLower(row.Email)
+		if key == "" {
+			key = strings.ToLower(row.Name)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		rows = append(rows, row)
+	}
+	return layout, rows, nil
+}
+
+func detectImportLayout(header []string) (string, importColumns) {
+	cols := importColumns{-1, -1, -1, -1, -1, -1}
+	isZoom, isEventbrite := false, false
+	for i, h := range header {
+		// Excel starts UTF-8 CSV files with a byte order mark.
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		switch {
+		case strings.Contains(h, "(original name)"):
+			cols.name = i
+			isZoom = true
+		case h == "name" || h == "full name" || h == "attendee name":
+			cols.name = i
+		case h == "first name":
+			cols.firstName = i
+		case h == "last name":
+			cols.lastName = i
+		case h == "email" || h == "user email" || h == "email address":
+			cols.email = i
+		case h == "phone" || h == "cell phone" || h == "phone number":
+			cols.phone = i
+		case h == "attendee status":
+			cols.status = i
+		case h == "order #" || h == "attendee #" || h == "ticket type":
+			isEventbrite = true
+		}
+	}
+
+	hasName := cols.name != -1 || (cols.firstName != -1 && cols.lastName != -1)
+	switch {
+	case !hasName:
+		return "", cols
+	case isZoom:
+		return ImportLayoutZoom, cols
+	case isEventbrite:
+		return ImportLayoutEventbrite, cols
+	}
+	return ImportLayoutSignIn, cols
+}
+
+func parseImportRecord(record []string, cols importColumns, layout string) (AttendanceImportRow, bool) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	// Eventbrite keeps refunded and transferred tickets in the
+	// attendee list.
+	if status := strings.ToLower(field(cols.status)); status == "not attending" || status == "deleted" {
+		return AttendanceImportRow{}, false
+	}
+
+	name := field(cols.name)
+	if name == "" {
+		name = strings.TrimSpace(field(cols.firstName) + " " + field(cols.lastName))
+	}
+	if layout == ImportLayoutZoom {
+		// Zoom appends the account name in parentheses when
+		// someone renamed themselves in the meeting. Use the
+		// name they chose.
+		if i := strings.LastIndex(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+			name = strings.TrimSpace(name[:i])
+		}
+	}
+	name = strings.Title(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		return AttendanceImportRow{}, false
+	}
+
+	return AttendanceImportRow{
+		Name:    name,
+		Email:   strings.ToLower(field(cols.email)),
+		Phone:   field(cols.phone),
+		Include: true,
+	}, true
+}
+
+// PreviewAttendanceImport parses the file and matches each row to an
+// activist, by email first and name second. It doesn't change the
+// database, so the user can review the matches first.
+func PreviewAttendanceImport(db *sqlx.DB, eventID int, file io.Reader) (AttendanceImportPreview, error) {
+	if eventID == 0 {
+		return AttendanceImportPreview{}, errors.New("Event ID cannot be 0")
+	}
+	layout, rows, err := ParseAttendanceCSV(file)
+	if err != nil {
+		return AttendanceImportPreview{}, err
+	}
+
+	var attendeeIDs []int
+	err = db.Select(&attendeeIDs, `SELECT activist_id FROM event_attendance WHERE event_id = ?`, eventID)
+	if err != nil {
+		return AttendanceImportPreview{}, errors.Wrapf(err, "failed to get attendance for event %d", eventID)
+	}
+	attending := map[int]bool{}
+	for _, id := range attendeeIDs {
+		attending[id] = true
+	}
+
+	for i := range rows {
+		if err := matchImportRow(db, &rows[i]); err != nil {
+			return AttendanceImportPreview{}, err
+		}
+		rows[i].AlreadyAttending = attending[rows[i].ActivistID]
+		rows[i].Include = !rows[i].AlreadyAttending
+	}
+
+	return AttendanceImportPreview{
+		EventID: eventID,
+		Layout:  layout,
+		Source:  importLayoutSources[layout],
+		Rows:    rows,
+	}, nil
+}
+
+func matchImportRow(db sqlx.Queryer, row *AttendanceImportRow) error {
+	var matches []Activist
+	if row.Email != "" {
+		err := sqlx.Select(db, &matches, selectActivistBaseQuery+` WHERE hidden = 0 AND lower(email) = ? ORDER BY id`, row.Email)
+		if err != nil {
+			return errors.Wrapf(err, "failed to match activist by email %s", row.Email)
+		}
+		if len(matches) > 0 {
+			// Several activists can share an email address;
+			// prefer the one with the same name.
+			match := matches[0]
+			for _, m := range matches {
+				if strings.EqualFold(m.Name, row.Name) {
+					match = m
+				}
+			}
+			row.Match = ImportMatchEmail
+			row.ActivistID = match.ID
+			row.ActivistName = match.Name
+			return nil
+		}
+	}
+
+	err := sqlx.Select(db, &matches, selectActivistBaseQuery+` WHERE hidden = 0 AND name = ?`, row.Name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to match activist by name %s", row.Name)
+	}
+	if len(matches) > 0 {
+		row.Match = ImportMatchName
+		row.ActivistID = matches[0].ID
+		row.ActivistName = matches[0].Name
+		return nil
+	}
+
+	row.Match = ImportMatchNew
+	row.ActivistID = 0
+	row.ActivistName = row.Name
+	return nil
+}
+
+func CleanAttendanceImportData(body io.Reader) (AttendanceImportPreview, error) {
+	var preview AttendanceImportPreview
+	if err := json.NewDecoder(body).Decode(&preview); err != nil {
+		return AttendanceImportPreview{}, err
+	}
+	if preview.EventID == 0 {
+		return AttendanceImportPreview{}, errors.New("Event ID cannot be 0")
+	}
+	preview.Source = strings.TrimSpace(preview.Source)
+	for i, row := range preview.Rows {
+		for _, s := range []string{row.Name, row.Email, row.Phone, preview.Source} {
+			if err := checkForDangerousChars(s); err != nil {
+				return AttendanceImportPreview{}, err
+			}
+		}
+		preview.Rows[i].Name = strings.TrimSpace(row.Name)
+		preview.Rows[i].Email = strings.TrimSpace(row.Email)
+		preview.Rows[i].Phone = strings.TrimSpace(row.Phone)
+	}
+	return preview, nil
+}
+
+// ImportAttendance creates activists for the unmatched rows and adds
+// every included row to the event's attendance, all in one
+// transaction. It returns the number of rows that were imported.
+func ImportAttendance(db *sqlx.DB, preview AttendanceImportPreview, user ADBUser) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+	count, err := importAttendance(tx, preview, user)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to commit attendance import")
+	}
+	return count, nil
+}
+
+func importAttendance(tx *sqlx.Tx, preview AttendanceImportPreview, user ADBUser) (int, error) {
+	var attendees []Activist
+	for _, row := range preview.Rows {
+		if !row.Include {
+			continue
+		}
+		if row.ActivistID != 0 {
+			// The IDs come from the client, so make sure
+			// they're still visible activists.
+			var name string
+			err := tx.Get(&name, `SELECT name FROM activists WHERE id = ? AND hidden = 0`, row.ActivistID)
+			if err == sql.ErrNoRows {
+				return 0, errors.Errorf("Activist %d does not exist", row.ActivistID)
+			}
+			if err != nil {
+				return 0, errors.Wrapf(err, "failed to select activist %d", row.ActivistID)
+			}
+			attendees = append(attendees, Activist{ID: row.ActivistID, Name: name})
+			continue
+		}
+
+		if row.Name == "" {
+			return 0, errors.New("Name cannot be empty")
+		}
+		// Someone may have created the activist since the
+		// preview.
+		if err := matchImportRow(tx, &row); err != nil {
+			return 0, err
+		}
+		if row.ActivistID == 0 {
+			id, err := createActivist(tx, ActivistExtra{
+				Activist: Activist{
+					Name:  row.Name,
+					Email: row.Email,
+					Phone: row.Phone,
+				},
+				ActivistMembershipData: ActivistMembershipData{
+					ActivistLevel: "Supporter",
+					Source:        preview.Source,
+				},
+			})
+			if err != nil {
+				return 0, err
+			}
+			row.ActivistID = id
+		}
+		attendees = append(attendees, Activist{ID: row.ActivistID, Name: row.Name})
+	}
+
+	if len(attendees) == 0 {
+		return 0, nil
+	}
+	if err := addEventAttendance(tx, preview.EventID, attendees, AttendanceChange{
+		User:   user,
+		Source: AttendanceSourceImport,
+	}); err != nil {
+		return 0, err
+	}
+	return len(attendees), nil
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttendanceCSV_eventbrite(t *testing.T) {
+	csv := "\ufeffOrder #,Order Date,First Name,Last Name,Email,Cell Phone,Ticket Type,Attendee Status\n" +
+		"1,2020-01-01,jane,doe,Jane@Example.com,555-1234,General,Attending\n" +
+		"2,2020-01-01,John,Smith,john@example.com,,General,Not Attending\n" +
+		"3,2020-01-02,Jane,Doe,jane@example.com,,General,Checked In\n"
+
+	layout, rows, err := ParseAttendanceCSV(strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Equal(t, ImportLayoutEventbrite, layout)
+	require.Equal(t, []AttendanceImportRow{{
+		Name:    "Jane Doe",
+		Email:   "jane@example.com",
+		Phone:   "555-1234",
+		Include: true,
+	}}, rows)
+}
+
+func TestParseAttendanceCSV_zoom(t *testing.T) {
+	csv := "Meeting ID,Topic,Start Time,End Time,User Email,Duration (Minutes),Participants\n" +
+		"123 456 789,Chapter Meeting,05/01/2020 07:00:00 PM,05/01/2020 09:00:00 PM,host@example.com,120,3\n" +
+		"\n" +
+		"Name (Original Name),User Email,Total Duration (Minutes),Guest\n" +
+		"Jane D (Jane Doe),jane@example.com,60,No\n" +
+		"Jane D (Jane Doe),jane@example.com,55,No\n" +
+		"Phone Caller,,30,Yes\n"
+
+	layout, rows, err := ParseAttendanceCSV(strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Equal(t, ImportLayoutZoom, layout)
+	require.Len(t, rows, 2)
+	require.Equal(t, "Jane D", rows[0].Name)
+	require.Equal(t, "jane@example.com", rows[0].Email)
+	require.Equal(t, "Phone Caller", rows[1].Name)
+}
+
+func TestParseAttendanceCSV_signInSheet(t *testing.T) {
+	csv := "Name,Email,Phone\n" +
+		"  alex   b  ,,555-0000\n" +
+		",,\n"
+
+	layout, rows, err := ParseAttendanceCSV(strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Equal(t, ImportLayoutSignIn, layout)
+	require.Len(t, rows, 1)
+	require.Equal(t, "Alex B", rows[0].Name)
+	require.Equal(t, "555-0000", rows[0].Phone)
+
+	_, _, err = ParseAttendanceCSV(strings.NewReader("Email,Phone\na@example.com,\n"))
+	require.Error(t, err)
+}
+
+func TestImportAttendance(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Jane Doe")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activists SET email = ? WHERE id = ?`, "jane@example.com", a1.ID)
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Alex B")
+	require.NoError(t, err)
+
+	eventID, err := InsertUpdateEvent(db, Event{
+		EventName:      "Chapter Meeting",
+		EventDate:      time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		EventType:      "Community",
+		AddedAttendees: []Activist{a2},
+	})
+	require.NoError(t, err)
+
+	csv := "Name,Email\n" +
+		"Jane,JANE@example.com\n" +
+		"Alex B,\n" +
+		"New Person,new@example.com\n"
+	preview, err := PreviewAttendanceImport(db, eventID, strings.NewReader(csv))
+	require.NoError(t, err)
+	require.Len(t, preview.Rows, 3)
+
+	require.Equal(t, ImportMatchEmail, preview.Rows[0].Match)
+	require.Equal(t, a1.ID, preview.Rows[0].ActivistID)
+	require.True(t, preview.Rows[0].Include)
+
+	require.Equal(t, ImportMatchName, preview.Rows[1].Match)
+	require.True(t, preview.Rows[1].AlreadyAttending)
+	require.False(t, preview.Rows[1].Include)
+
+	require.Equal(t, ImportMatchNew, preview.Rows[2].Match)
+	require.Equal(t, 0, preview.Rows[2].ActivistID)
+
+	// The preview doesn't create anyone.
+	_, err = GetActivist(db, "New Person")
+	require.Error(t, err)
+
+	// A bad activist ID fails the whole import, so nobody is
+	// created.
+	bad := preview
+	bad.Rows = append([]AttendanceImportRow{}, preview.Rows...)
+	bad.Rows = append(bad.Rows, AttendanceImportRow{Name: "Nobody", ActivistID: a2.ID + 100, Include: true})
+	_, err = ImportAttendance(db, bad, DevTestUser)
+	require.Error(t, err)
+	_, err = GetActivist(db, "New Person")
+	require.Error(t, err)
+
+	imported, err := ImportAttendance(db, preview, DevTestUser)
+	require.NoError(t, err)
+	require.Equal(t, 2, imported)
+
+	attendees, err := GetEventAttendance(db, eventID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Jane Doe", "Alex B", "New Person"}, attendees)
+
+	created, err := GetActivist(db, "New Person")
+	require.NoError(t, err)
+	require.Equal(t, "new@example.com", created.Email)
+}
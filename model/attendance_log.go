@@ -0,0 +1,147 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+const (
+	AttendanceActionAdd    = "add"
+	AttendanceActionRemove = "remove"
+	// An attendee's role was changed. The log entry has the new role.
+	AttendanceActionSetRole = "set_role"
+)
+
+// Where an attendance change came from.
+const (
+	AttendanceSourceEditor       = "editor"
+	AttendanceSourceImport       = "import"
+	AttendanceSourceMerge        = "merge"
+	AttendanceSourceDeleteEvent  = "delete"
+	AttendanceSourceRestoreEvent = "restore"
+)
+
+const selectAttendanceLogBaseQuery string = `
+SELECT
+  l.id,
+  l.event_id,
+  COALESCE(e.name, (SELECT max(ae.name) FROM archived_events ae WHERE ae.event_id = l.event_id), '') AS event_name,
+  l.activist_id,
+  IFNULL(a.name, '') AS activist_name,
+  l.action,
+  l.role,
+  l.source,
+  l.user_id,
+  l.user_email,
+  l.timestamp
+FROM event_attendance_log l
+LEFT JOIN events e ON e.id = l.event_id
+LEFT JOIN activists a ON a.id = l.activist_id
+`
+
+/** Type Definitions */
+
+type AttendanceLogEntry struct {
+	ID           int       `db:"id"`
+	EventID      int       `db:"event_id"`
+	EventName    string    `db:"event_name"`
+	ActivistID   int       `db:"activist_id"`
+	ActivistName string    `db:"activist_name"`
+	Action       string    `db:"action"`
+	Role         string    `db:"role"`
+	Source       string    `db:"source"`
+	UserID       int       `db:"user_id"`
+	UserEmail    string    `db:"user_email"`
+	Timestamp    time.Time `db:"timestamp"`
+}
+
+type AttendanceLogEntryJSON struct {
+	ID           int    `json:"id"`
+	EventID      int    `json:"event_id"`
+	EventName    string `json:"event_name"`
+	ActivistID   int    `json:"activist_id"`
+	ActivistName string `json:"activist_name"`
+	Action       string `json:"action"`
+	Role         string `json:"role"`
+	Source       string `json:"source"`
+	UserID       int    `json:"user_id"`
+	UserEmail    string `json:"user_email"`
+	Timestamp    string `json:"timestamp"`
+}
+
+// AttendanceChange says who is changing attendance and how, for the
+// attendance log.
+type AttendanceChange struct {
+	User   ADBUser
+	Source string
+}
+
+/** Functions and Methods */
+
+func logAttendanceChange(tx *sqlx.Tx, change AttendanceChange, eventID, activistID int, action string) error {
+	return insertAttendanceLogEntry(tx, change, eventID, activistID, action, "")
+}
+
+// logRoleChange logs that an attendee's role was changed to role,
+// which is empty if their role was removed.
+func logRoleChange(tx *sqlx.Tx, change AttendanceChange, eventID, activistID int, role string) error {
+	return insertAttendanceLogEntry(tx, change, eventID, activistID, AttendanceActionSetRole, role)
+}
+
+func insertAttendanceLogEntry(tx *sqlx.Tx, change AttendanceChange, eventID, activistID int, action, role string) error {
+	source := change.Source
+	if source == "" {
+		source = AttendanceSourceEditor
+	}
+	_, err := tx.Exec(`
+INSERT INTO event_attendance_log (event_id, activist_id, action, role, source, user_id, user_email)
+VALUES (?, ?, ?, ?, ?, ?, ?)`, eventID, activistID, action, role, source, change.User.ID, change.User.Email)
+	if err != nil {
+		return errors.Wrapf(err, "failed to log attendance change for event %d", eventID)
+	}
+	return nil
+}
+
+func GetEventAttendanceLogJSON(db *sqlx.DB, eventID int) ([]AttendanceLogEntryJSON, error) {
+	if eventID == 0 {
+		return nil, errors.New("Event ID cannot be 0")
+	}
+	return getAttendanceLogJSON(db, "l.event_id = ?", eventID)
+}
+
+func GetActivistAttendanceLogJSON(db *sqlx.DB, activistID int) ([]AttendanceLogEntryJSON, error) {
+	if activistID == 0 {
+		return nil, errors.New("Activist ID cannot be 0")
+	}
+	return getAttendanceLogJSON(db, "l.activist_id = ?", activistID)
+}
+
+func getAttendanceLogJSON(db *sqlx.DB, filter string, args ...interface{}) ([]AttendanceLogEntryJSON, error) {
+	var entries []AttendanceLogEntry
+	err := db.Select(&entries, selectAttendanceLogBaseQuery+` WHERE `+filter+` ORDER BY l.timestamp DESC, l.id DESC`, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select attendance log")
+	}
+
+	entriesJSON := []AttendanceLogEntryJSON{}
+	for _, e := range entries {
+		entriesJSON = append(entriesJSON, AttendanceLogEntryJSON{
+			ID:           e.ID,
+			EventID:      e.EventID,
+			EventName:    e.EventName,
+			ActivistID:   e.ActivistID,
+			ActivistName: e.ActivistName,
+			Action:       e.Action,
+			Role:         e.Role,
+			Source:       e.Source,
+			UserID:       e.UserID,
+			UserEmail:    e.UserEmail,
+			Timestamp:    e.Timestamp.Format(time.RFC3339),
+		})
+	}
+	return entriesJSON, nil
+}
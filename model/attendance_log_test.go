@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttendanceLog(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+
+	event := Event{
+		EventName:        "event one",
+		EventDate:        time.Now(),
+		EventType:        "Working Group",
+		AddedAttendees:   []Activist{a1, a2},
+		AttendanceChange: AttendanceChange{User: DevTestUser, Source: AttendanceSourceImport},
+	}
+	eventID, err := InsertUpdateEvent(db, event)
+	require.NoError(t, err)
+
+	// Saving the same attendees again isn't a change.
+	event.ID = eventID
+	event.AddedAttendees = []Activist{a1}
+	event.DeletedAttendees = []Activist{a2}
+	event.AttendanceChange.Source = AttendanceSourceEditor
+	_, err = InsertUpdateEvent(db, event)
+	require.NoError(t, err)
+
+	entries, err := GetEventAttendanceLogJSON(db, eventID)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	// Newest first.
+	require.Equal(t, a2.ID, entries[0].ActivistID)
+	require.Equal(t, AttendanceActionRemove, entries[0].Action)
+	require.Equal(t, AttendanceSourceEditor, entries[0].Source)
+	require.Equal(t, DevTestUser.Email, entries[0].UserEmail)
+	require.Equal(t, AttendanceActionAdd, entries[2].Action)
+	require.Equal(t, AttendanceSourceImport, entries[2].Source)
+
+	entries, err = GetActivistAttendanceLogJSON(db, a2.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "event one", entries[0].EventName)
+}
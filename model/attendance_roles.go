@@ -0,0 +1,268 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// AttendanceRole is a role an activist can have at an event, like
+// host or police liaison. Attendees without a role have an empty
+// role.
+type AttendanceRole struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+	// Leaderboard points for having this role, on top of the points
+	// for attending the event.
+	Points int `db:"points"`
+}
+
+type AttendanceRoleJSON struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+}
+
+// AttendeeRole sets the role of one attendee of an event.
+type AttendeeRole struct {
+	Activist Activist
+	Role     string
+}
+
+type LeadershipReportRow struct {
+	ActivistID    int            `json:"activist_id"`
+	Name          string         `json:"name"`
+	ActivistLevel string         `json:"activist_level"`
+	Roles         map[string]int `json:"roles"`
+	Total         int            `json:"total"`
+	LastEventDate string         `json:"last_event_date"`
+}
+
+/** Functions and Methods */
+
+func GetAttendanceRoles(db *sqlx.DB) ([]AttendanceRole, error) {
+	var roles []AttendanceRole
+	if err := db.Select(&roles, `SELECT id, name, points FROM attendance_roles ORDER BY name`); err != nil {
+		return nil, errors.Wrap(err, "failed to select attendance roles")
+	}
+	return roles, nil
+}
+
+func GetAttendanceRolesJSON(db *sqlx.DB) ([]AttendanceRoleJSON, error) {
+	roles, err := GetAttendanceRoles(db)
+	if err != nil {
+		return nil, err
+	}
+	rolesJSON := []AttendanceRoleJSON{}
+	for _, r := range roles {
+		rolesJSON = append(rolesJSON, AttendanceRoleJSON{ID: r.ID, Name: r.Name, Points: r.Points})
+	}
+	return rolesJSON, nil
+}
+
+func getAttendanceRole(db *sqlx.DB, rawRole string) (string, error) {
+	rawRole = strings.TrimSpace(rawRole)
+	if rawRole == "" {
+		return "", nil
+	}
+	var name string
+	err := db.Get(&name, `SELECT name FROM attendance_roles WHERE name = ?`, rawRole)
+	if err == sql.ErrNoRows {
+		return "", errors.New("Not a valid attendance role: " + rawRole)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to select attendance role")
+	}
+	return name, nil
+}
+
+func CleanAttendanceRoleData(id int, name string, points int) (AttendanceRole, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return AttendanceRole{}, errors.New("Name cannot be empty")
+	}
+	if err := checkForDangerousChars(name); err != nil {
+		return AttendanceRole{}, err
+	}
+	if points < 0 {
+		return AttendanceRole{}, errors.New("Points cannot be negative")
+	}
+	return AttendanceRole{ID: id, Name: name, Points: points}, nil
+}
+
+func CreateAttendanceRole(db *sqlx.DB, role AttendanceRole) (int, error) {
+	if role.ID != 0 {
+		return 0, errors.New("Attendance role ID must be 0")
+	}
+	res, err := db.NamedExec(`INSERT INTO attendance_roles (name, points) VALUES (:name, :points)`, role)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to insert attendance role %s", role.Name)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get inserted attendance role id")
+	}
+	return int(id), nil
+}
+
+// UpdateAttendanceRole renames a role, including on all attendance
+// that has it.
+func UpdateAttendanceRole(db *sqlx.DB, role AttendanceRole) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	var oldName string
+	err = tx.Get(&oldName, `SELECT name FROM attendance_roles WHERE id = ?`, role.ID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return errors.Errorf("Attendance role with id %d does not exist", role.ID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to select attendance role %d", role.ID)
+	}
+
+	if _, err := tx.NamedExec(`UPDATE attendance_roles SET name = :name, points = :points WHERE id = :id`, role); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to update attendance role %d", role.ID)
+	}
+	for _, table := range []string{"event_attendance", "archived_event_attendance"} {
+		_, err := tx.Exec(`UPDATE `+table+` SET role = ? WHERE role = ?`, role.Name, oldName)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to rename attendance role %s", oldName)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to commit attendance role %d", role.ID)
+	}
+	return nil
+}
+
+// DeleteAttendanceRole deletes a role that no attendee has.
+func DeleteAttendanceRole(db *sqlx.DB, id int) error {
+	var name string
+	err := db.Get(&name, `SELECT name FROM attendance_roles WHERE id = ?`, id)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("Attendance role with id %d does not exist", id)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to select attendance role %d", id)
+	}
+
+	var count int
+	err = db.Get(&count, `
+SELECT (SELECT count(*) FROM event_attendance WHERE role = ?)
+     + (SELECT count(*) FROM archived_event_attendance WHERE role = ?)`, name, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to count attendance with role")
+	}
+	if count != 0 {
+		return errors.Errorf("Cannot delete %s, %d attendees have that role", name, count)
+	}
+
+	if _, err := db.Exec(`DELETE FROM attendance_roles WHERE id = ?`, id); err != nil {
+		return errors.Wrapf(err, "failed to delete attendance role %d", id)
+	}
+	return nil
+}
+
+func updateAttendeeRoles(tx *sqlx.Tx, event Event) error {
+	for _, r := range event.UpdatedRoles {
+		var role string
+		err := tx.Get(&role, `
+SELECT role
+FROM event_attendance
+WHERE event_id = ? AND activist_id = ?
+FOR UPDATE`, event.ID, r.Activist.ID)
+		if err == sql.ErrNoRows {
+			return errors.Errorf("Can't set the role of %s: they didn't attend the event", r.Activist.Name)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to select role of activist %d", r.Activist.ID)
+		}
+		if role == r.Role {
+			continue
+		}
+		_, err = tx.Exec(`
+UPDATE event_attendance
+SET role = ?
+WHERE event_id = ? AND activist_id = ?`, r.Role, event.ID, r.Activist.ID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to update role of activist %d", r.Activist.ID)
+		}
+		if err := logRoleChange(tx, event.AttendanceChange, event.ID, r.Activist.ID, r.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLeadershipReport counts, for each activist who had a role at an
+// event between dateFrom and dateTo, how many times they had each
+// role. Either date may be empty.
+func GetLeadershipReport(db *sqlx.DB, dateFrom, dateTo string) ([]LeadershipReportRow, error) {
+	query := `
+SELECT
+  a.id,
+  a.name,
+  a.activist_level,
+  ea.role,
+  count(*) AS total,
+  DATE_FORMAT(max(e.date), '%Y-%m-%d') AS last_event_date
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+JOIN activists a ON a.id = ea.activist_id
+WHERE ea.role <> ''
+  AND a.hidden = 0`
+	var args []interface{}
+	if dateFrom != "" {
+		query += ` AND e.date >= ?`
+		args = append(args, dateFrom)
+	}
+	if dateTo != "" {
+		query += ` AND e.date <= ?`
+		args = append(args, dateTo)
+	}
+	query += `
+GROUP BY a.id, a.name, a.activist_level, ea.role
+ORDER BY a.name, a.id, ea.role`
+
+	var counts []struct {
+		ActivistID    int    `db:"id"`
+		Name          string `db:"name"`
+		ActivistLevel string `db:"activist_level"`
+		Role          string `db:"role"`
+		Total         int    `db:"total"`
+		LastEventDate string `db:"last_event_date"`
+	}
+	if err := db.Select(&counts, query, args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select leadership report")
+	}
+
+	rows := []LeadershipReportRow{}
+	for _, c := range counts {
+		if len(rows) == 0 || rows[len(rows)-1].ActivistID != c.ActivistID {
+			rows = append(rows, LeadershipReportRow{
+				ActivistID:    c.ActivistID,
+				Name:          c.Name,
+				ActivistLevel: c.ActivistLevel,
+				Roles:         map[string]int{},
+			})
+		}
+		row := &rows[len(rows)-1]
+		row.Roles[c.Role] = c.Total
+		row.Total += c.Total
+		if c.LastEventDate > row.LastEventDate {
+			row.LastEventDate = c.LastEventDate
+		}
+	}
+	return rows, nil
+}
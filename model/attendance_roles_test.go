@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttendeeRoles(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+
+	e1, err := InsertUpdateEvent(db, Event{
+		EventName:      "event one",
+		EventDate:      time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2},
+		UpdatedRoles:   []AttendeeRole{{Activist: a1, Role: "Marshal"}},
+	})
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "event two",
+		EventDate:      time.Date(2020, 5, 8, 0, 0, 0, 0, time.UTC),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2},
+		UpdatedRoles:   []AttendeeRole{{Activist: a1, Role: "Host"}, {Activist: a2, Role: "Marshal"}},
+	})
+	require.NoError(t, err)
+
+	event, err := GetEvent(db, GetEventOptions{EventID: e1})
+	require.NoError(t, err)
+	roles := map[string]string{}
+	for i, name := range event.Attendees {
+		roles[name] = event.AttendeeRoles[i]
+	}
+	require.Equal(t, map[string]string{"Hello": "Marshal", "Hi": ""}, roles)
+
+	// Role changes are logged, and only attendees can have roles.
+	entries, err := GetEventAttendanceLogJSON(db, e1)
+	require.NoError(t, err)
+	require.Equal(t, AttendanceActionSetRole, entries[0].Action)
+	require.Equal(t, "Marshal", entries[0].Role)
+	a3, err := GetOrCreateActivist(db, "Hey")
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{
+		ID:           e1,
+		EventName:    "event one",
+		EventDate:    time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		EventType:    "Action",
+		UpdatedRoles: []AttendeeRole{{Activist: a3, Role: "Host"}},
+	})
+	require.Error(t, err)
+
+	events, err := GetEvents(db, GetEventOptions{AttendeeRole: "Marshal"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	events, err = GetEvents(db, GetEventOptions{AttendeeRole: "Marshal", ActivistID: a2.ID})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, "event two", events[0].EventName)
+
+	report, err := GetLeadershipReport(db, "", "")
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	require.Equal(t, "Hello", report[0].Name)
+	require.Equal(t, map[string]int{"Host": 1, "Marshal": 1}, report[0].Roles)
+	require.Equal(t, 2, report[0].Total)
+	require.Equal(t, "2020-05-08", report[0].LastEventDate)
+
+	report, err = GetLeadershipReport(db, "2020-05-02", "")
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	require.Equal(t, 1, report[0].Total)
+
+	// Roles in use can't be deleted.
+	attendanceRoles, err := GetAttendanceRoles(db)
+	require.NoError(t, err)
+	for _, r := range attendanceRoles {
+		if r.Name == "Marshal" {
+			require.Error(t, DeleteAttendanceRole(db, r.ID))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// CalendarToken grants read access to an iCalendar feed. Calendar
+// clients can't log in, so the token in the feed URL is the only
+// credential. A token belongs either to an ADB user (feed of all
+// events) or to an activist (feed of their own events).
+type CalendarToken struct {
+	Token      string `db:"token"`
+	ADBUserID  int    `db:"adb_user_id"`
+	ActivistID int    `db:"activist_id"`
+}
+
+/** Functions and Methods */
+
+func GetUserCalendarToken(db *sqlx.DB, adbUserID int) (string, error) {
+	if adbUserID == 0 {
+		return "", errors.New("ADB user ID cannot be 0")
+	}
+	return getOrCreateCalendarToken(db, CalendarToken{ADBUserID: adbUserID})
+}
+
+func GetActivistCalendarToken(db *sqlx.DB, activistID int) (string, error) {
+	if activistID == 0 {
+		return "", errors.New("Activist ID cannot be 0")
+	}
+	return getOrCreateCalendarToken(db, CalendarToken{ActivistID: activistID})
+}
+
+func getOrCreateCalendarToken(db *sqlx.DB, owner CalendarToken) (string, error) {
+	var token string
+	err := db.Get(&token, `
+SELECT token
+FROM calendar_tokens
+WHERE adb_user_id = ? AND activist_id = ?`, owner.ADBUserID, owner.ActivistID)
+	if err == nil {
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", errors.Wrap(err, "failed to select calendar token")
+	}
+
+	owner.Token, err = newAccessToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.NamedExec(`
+INSERT INTO calendar_tokens (token, adb_user_id, activist_id)
+VALUES (:token, :adb_user_id, :activist_id)`, owner)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to insert calendar token")
+	}
+	return owner.Token, nil
+}
+
+func GetCalendarToken(db *sqlx.DB, token string) (CalendarToken, error) {
+	var t CalendarToken
+	err := db.Get(&t, `
+SELECT token, adb_user_id, activist_id
+FROM calendar_tokens
+WHERE token = ?`, token)
+	if err == sql.ErrNoRows {
+		return CalendarToken{}, errors.New("Calendar token not found")
+	}
+	if err != nil {
+		return CalendarToken{}, errors.Wrap(err, "failed to select calendar token")
+	}
+	return t, nil
+}
+
+// newAccessToken returns a random token for URLs used without logging
+// in, like calendar feeds and wallboards.
+func newAccessToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate token")
+	}
+	return fmt.Sprintf("%x", b), nil
+}
@@ -0,0 +1,317 @@
+package model
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Global Variable Definitions */
+
+const (
+	earthRadiusMiles = 3959
+
+	defaultCircleMatchLimit = 3
+)
+
+/** Type Definitions */
+
+// Geocoder looks up the coordinates of an address. found is false if
+// the address doesn't exist.
+type Geocoder func(address string) (lat, lng float64, found bool, err error)
+
+// CircleMatch is a circle suggested to an activist.
+type CircleMatch struct {
+	GroupID         int    `json:"group_id"`
+	Name            string `json:"name"`
+	MeetingTime     string `json:"meeting_time"`
+	MeetingLocation string `json:"meeting_location"`
+	// In miles.
+	Distance float64 `json:"distance"`
+}
+
+type CircleMatchOptions struct {
+	// Limits the results to circles whose meeting time contains
+	// this, ignoring case, e.g. "tuesday".
+	MeetingTime string
+	// Defaults to 3.
+	Limit int
+}
+
+// ProspectCircleMatches are the circles suggested to an activist who
+// is interested in joining one.
+type ProspectCircleMatches struct {
+	ActivistID int           `json:"activist_id"`
+	Name       string        `json:"name"`
+	Email      string        `json:"email"`
+	Address    string        `json:"address"`
+	Circles    []CircleMatch `json:"circles"`
+	// Why no circles could be suggested, if none were.
+	Error string `json:"error"`
+}
+
+/** Functions and Methods */
+
+// distanceMiles returns the great-circle distance between two
+// points.
+func distanceMiles(lat1, lng1, lat2, lng2 float64) float64 {
+	radians := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := radians(lat2 - lat1)
+	dLng := radians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Sqrt(a))
+}
+
+// nearestCircles ranks the circles with coordinates by their distance
+// from a point.
+func nearestCircles(circles []Group, lat, lng float64, options CircleMatchOptions) []CircleMatch {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = defaultCircleMatchLimit
+	}
+	meetingTime := strings.ToLower(strings.TrimSpace(options.MeetingTime))
+
+	matches := []CircleMatch{}
+	for _, c := range circles {
+		if !c.Lat.Valid || !c.Lng.Valid {
+			continue
+		}
+		if meetingTime != "" && !strings.Contains(strings.ToLower(c.MeetingTime), meetingTime) {
+			continue
+		}
+		matches = append(matches, CircleMatch{
+			GroupID:         c.ID,
+			Name:            c.Name,
+			MeetingTime:     c.MeetingTime,
+			MeetingLocation: c.MeetingLocation,
+			Distance:        distanceMiles(lat, lng, c.Lat.Float64, c.Lng.Float64),
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Distance < matches[j].Distance
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// getMatchableCircles returns the visible circles.
+func getMatchableCircles(db *sqlx.DB) ([]Group, error) {
+	groups, err := GetGroups(db, GroupQueryOptions{Types: []string{GroupTypeCircle}})
+	if err != nil {
+		return nil, err
+	}
+	var circles []Group
+	for _, g := range groups {
+		if g.Visible {
+			circles = append(circles, g)
+		}
+	}
+	return circles, nil
+}
+
+func activistAddress(streetAddress, city, state string) string {
+	var parts []string
+	for _, p := range []string{streetAddress, city, state} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cachedGeocode returns the cached coordinates of an address. cached
+// is false if the address hasn't been looked up yet.
+func cachedGeocode(db *sqlx.DB, address string) (lat, lng float64, found, cached bool, err error) {
+	var row struct {
+		Found bool    `db:"found"`
+		Lat   float64 `db:"lat"`
+		Lng   float64 `db:"lng"`
+	}
+	err = db.Get(&row, `SELECT found, lat, lng FROM geocoded_addresses WHERE address = ?`, address)
+	if err == sql.ErrNoRows {
+		return 0, 0, false, false, nil
+	}
+	if err != nil {
+		return 0, 0, false, false, errors.Wrap(err, "failed to select geocoded address")
+	}
+	return row.Lat, row.Lng, row.Found, true, nil
+}
+
+// geocodeAddress looks up an address, caching the result so each
+// address is only looked up once.
+func geocodeAddress(db *sqlx.DB, geocoder Geocoder, address string) (lat, lng float64, found bool, err error) {
+	lat, lng, found, cached, err := cachedGeocode(db, address)
+	if err != nil || cached {
+		return lat, lng, found, err
+	}
+
+	lat, lng, found, err = geocoder(address)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	_, err = db.Exec(`
+REPLACE INTO geocoded_addresses (address, found, lat, lng)
+VALUES (?, ?, ?, ?)`, address, found, lat, lng)
+	if err != nil {
+		return 0, 0, false, errors.Wrap(err, "failed to save geocoded address")
+	}
+	return lat, lng, found, nil
+}
+
+type circleProspect struct {
+	ID            int    `db:"id"`
+	Name          string `db:"name"`
+	Email         string `db:"email"`
+	StreetAddress string `db:"street_address"`
+	City          string `db:"city"`
+	State         string `db:"state"`
+}
+
+func getCircleProspects(db *sqlx.DB) ([]circleProspect, error) {
+	var prospects []circleProspect
+	err := db.Select(&prospects, `
+SELECT id, name, email, street_address, city, state
+FROM activists
+WHERE circle_interest = 1
+  AND hidden = 0
+ORDER BY name`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select circle prospects")
+	}
+	return prospects, nil
+}
+
+// GeocodeCircleProspects looks up the addresses of activists
+// interested in joining a circle that haven't been looked up yet, at
+// most limit of them, so GetCircleInterestMatches can match them. It
+// stops at the first failed lookup, since it's usually the quota
+// running out. It returns how many addresses were looked up.
+func GeocodeCircleProspects(db *sqlx.DB, geocoder Geocoder, limit int) (int, error) {
+	prospects, err := getCircleProspects(db)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	seen := map[string]bool{}
+	for _, p := range prospects {
+		if count >= limit {
+			break
+		}
+		address := activistAddress(p.StreetAddress, p.City, p.State)
+		if address == "" || seen[address] {
+			continue
+		}
+		seen[address] = true
+		if _, _, _, cached, err := cachedGeocode(db, address); err != nil {
+			return count, err
+		} else if cached {
+			continue
+		}
+		if _, _, _, err := geocodeAddress(db, geocoder, address); err != nil {
+			return count, errors.Wrapf(err, "failed to geocode address of activist %d", p.ID)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// FindNearestCircles returns the visible circles closest to a point.
+func FindNearestCircles(db *sqlx.DB, lat, lng float64, options CircleMatchOptions) ([]CircleMatch, error) {
+	circles, err := getMatchableCircles(db)
+	if err != nil {
+		return nil, err
+	}
+	return nearestCircles(circles, lat, lng, options), nil
+}
+
+// FindNearestCirclesToAddress returns the visible circles closest to
+// an address.
+func FindNearestCirclesToAddress(db *sqlx.DB, geocoder Geocoder, address string, options CircleMatchOptions) ([]CircleMatch, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil, errors.New("Address must not be blank")
+	}
+	lat, lng, found, err := geocodeAddress(db, geocoder, address)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("Address not found: %s", address)
+	}
+	return FindNearestCircles(db, lat, lng, options)
+}
+
+// FindNearestCirclesToActivist returns the visible circles closest to
+// an activist's address.
+func FindNearestCirclesToActivist(db *sqlx.DB, geocoder Geocoder, activistID int, options CircleMatchOptions) ([]CircleMatch, error) {
+	var a struct {
+		StreetAddress string `db:"street_address"`
+		City          string `db:"city"`
+		State         string `db:"state"`
+	}
+	err := db.Get(&a, `SELECT street_address, city, state FROM activists WHERE id = ?`, activistID)
+	if err == sql.ErrNoRows {
+		return nil, errors.Errorf("Activist with id %d does not exist", activistID)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select address of activist %d", activistID)
+	}
+	address := activistAddress(a.StreetAddress, a.City, a.State)
+	if address == "" {
+		return nil, errors.New("Activist has no address")
+	}
+	return FindNearestCirclesToAddress(db, geocoder, address, options)
+}
+
+// GetCircleInterestMatches suggests circles to every activist who is
+// interested in joining one. It only uses addresses that were already
+// looked up by GeocodeCircleProspects; the others are reported on the
+// activist's result.
+func GetCircleInterestMatches(db *sqlx.DB, options CircleMatchOptions) ([]ProspectCircleMatches, error) {
+	circles, err := getMatchableCircles(db)
+	if err != nil {
+		return nil, err
+	}
+	prospects, err := getCircleProspects(db)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []ProspectCircleMatches{}
+	for _, p := range prospects {
+		m := ProspectCircleMatches{
+			ActivistID: p.ID,
+			Name:       p.Name,
+			Email:      p.Email,
+			Address:    activistAddress(p.StreetAddress, p.City, p.State),
+			Circles:    []CircleMatch{},
+		}
+		if m.Address == "" {
+			m.Error = "No address"
+		} else {
+			lat, lng, found, cached, err := cachedGeocode(db, m.Address)
+			if err != nil {
+				return nil, err
+			}
+			if !cached {
+				m.Error = "Address not looked up yet"
+			} else if found {
+				m.Circles = nearestCircles(circles, lat, lng, options)
+			} else {
+				m.Error = "Address not found"
+			}
+		}
+		if m.Error == "" && len(m.Circles) == 0 {
+			m.Error = "No matching circles"
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoords(t *testing.T) {
+	lat, lng, err := parseCoords(" 37.8044, -122.2712 ")
+	require.NoError(t, err)
+	require.Equal(t, sql.NullFloat64{Float64: 37.8044, Valid: true}, lat)
+	require.Equal(t, sql.NullFloat64{Float64: -122.2712, Valid: true}, lng)
+
+	lat, lng, err = parseCoords("")
+	require.NoError(t, err)
+	require.False(t, lat.Valid)
+	require.False(t, lng.Valid)
+
+	for _, coords := range []string{"37.8044", "north, south", "91, 0", "0, 181", "1, 2, 3"} {
+		_, _, err := parseCoords(coords)
+		require.Error(t, err, coords)
+	}
+}
+
+func TestDistanceMiles(t *testing.T) {
+	// Oakland to San Francisco.
+	d := distanceMiles(37.8044, -122.2712, 37.7749, -122.4194)
+	require.InDelta(t, 8.3, d, 0.1)
+	require.Equal(t, 0.0, distanceMiles(10, 20, 10, 20))
+}
+
+func TestNearestCircles(t *testing.T) {
+	coord := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	circles := []Group{
+		{ID: 1, Name: "San Francisco", MeetingTime: "Tuesdays 7pm", Lat: coord(37.7749), Lng: coord(-122.4194)},
+		{ID: 2, Name: "Berkeley", MeetingTime: "Sundays 2pm", Lat: coord(37.8716), Lng: coord(-122.2727)},
+		{ID: 3, Name: "San Jose", MeetingTime: "tuesdays 6pm", Lat: coord(37.3382), Lng: coord(-121.8863)},
+		// No coordinates.
+		{ID: 4, Name: "Online", MeetingTime: "Tuesdays 8pm"},
+	}
+	ids := func(matches []CircleMatch) []int {
+		var out []int
+		for _, m := range matches {
+			out = append(out, m.GroupID)
+		}
+		return out
+	}
+
+	// From Oakland.
+	matches := nearestCircles(circles, 37.8044, -122.2712, CircleMatchOptions{})
+	require.Equal(t, []int{2, 1, 3}, ids(matches))
+
+	matches = nearestCircles(circles, 37.8044, -122.2712, CircleMatchOptions{Limit: 1})
+	require.Equal(t, []int{2}, ids(matches))
+
+	matches = nearestCircles(circles, 37.8044, -122.2712, CircleMatchOptions{MeetingTime: "Tuesday"})
+	require.Equal(t, []int{1, 3}, ids(matches))
+}
+
+func TestGeocodeCircleProspects(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	_, err := CreateGroup(db, Group{Name: "Oakland", Type: GroupTypeCircle, Visible: true, Coords: "37.8044, -122.2712"}, "")
+	require.NoError(t, err)
+	_, err = db.Exec(`
+INSERT INTO activists (name, circle_interest, city, state) VALUES
+  ('Alice', 1, 'Berkeley', 'CA'),
+  ('Bob', 1, 'Nowhere', 'CA')`)
+	require.NoError(t, err)
+
+	// Lookups stop at the first failure, and the report only uses
+	// addresses that were looked up.
+	geocoder := func(address string) (float64, float64, bool, error) {
+		if address == "Nowhere, CA" {
+			return 0, 0, false, errors.New("OVER_QUERY_LIMIT")
+		}
+		return 37.8716, -122.2727, true, nil
+	}
+	n, err := GeocodeCircleProspects(db, geocoder, 10)
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+	matches, err := GetCircleInterestMatches(db, CircleMatchOptions{})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	require.Equal(t, "Alice", matches[0].Name)
+	require.Len(t, matches[0].Circles, 1)
+	require.Equal(t, "Bob", matches[1].Name)
+	require.Equal(t, "Address not looked up yet", matches[1].Error)
+
+	// Cached addresses aren't looked up again.
+	n, err = GeocodeCircleProspects(db, func(string) (float64, float64, bool, error) {
+		return 0, 0, false, nil
+	}, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	matches, err = GetCircleInterestMatches(db, CircleMatchOptions{})
+	require.NoError(t, err)
+	require.Len(t, matches[0].Circles, 1)
+	require.Equal(t, "Address not found", matches[1].Error)
+}
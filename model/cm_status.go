@@ -0,0 +1,368 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dxe/adb/mpi"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Statuses of chapter members and organizers in a review.
+const (
+	// Eligible now, but not next month unless they're MPI this
+	// month.
+	CMStatusAtRisk = "at_risk"
+	// Not eligible and not warned recently, so they should be
+	// warned.
+	CMStatusWarn = "warn"
+	// Not eligible even though they were warned, so they should lose
+	// their status.
+	CMStatusDemote = "demote"
+)
+
+// Decisions on a proposed level change. Changes are never applied
+// without an organizer approving them.
+const (
+	CMStatusDecisionPending  = ""
+	CMStatusDecisionApproved = "approved"
+	CMStatusDecisionRejected = "rejected"
+)
+
+/** Type Definitions */
+
+// CMRetentionRule is how many months with MPI chapter members and
+// organizers need to keep their level: at least MinMonths of the past
+// Window full months. It's set in the config.
+type CMRetentionRule struct {
+	MinMonths int
+	Window    int
+}
+
+type CMStatusReview struct {
+	ID      int                  `db:"id" json:"id"`
+	Month   int                  `db:"month" json:"month"`
+	Created time.Time            `db:"created" json:"created"`
+	Items   []CMStatusReviewItem `json:"items"`
+}
+
+type CMStatusReviewItem struct {
+	ID             int    `db:"id" json:"id"`
+	ReviewID       int    `db:"review_id" json:"review_id"`
+	ActivistID     int    `db:"activist_id" json:"activist_id"`
+	Name           string `db:"name" json:"name"`
+	Email          string `db:"email" json:"email"`
+	Status         string `db:"status" json:"status"`
+	Past3          int    `db:"past_3" json:"past_3"`
+	CurrentLevel   string `db:"current_level" json:"current_level"`
+	ProposedLevel  string `db:"proposed_level" json:"proposed_level"`
+	WarningSent    bool   `db:"warning_sent" json:"warning_sent"`
+	Decision       string `db:"decision" json:"decision"`
+	DecidedByEmail string `db:"decided_by_email" json:"decided_by_email"`
+}
+
+/** Functions and Methods */
+
+// Enabled returns whether the rule is set. Chapter members aren't
+// reviewed without one.
+func (r CMRetentionRule) Enabled() bool {
+	return r.MinMonths > 0 && r.Window >= r.MinMonths
+}
+
+// eligible returns whether the activist meets the rule as of the
+// start of month.
+func (r CMRetentionRule) eligible(a mpi.Activist, month int) bool {
+	return mpi.MetMonths(a, month, r.Window) >= r.MinMonths
+}
+
+// demotedLevel is the level a chapter member or organizer is proposed
+// to move down to when they lose their status.
+func demotedLevel(level string) string {
+	if level == "Organizer" {
+		return ACTIVIST_LEVEL_CHAPTER_MEMBER
+	}
+	return "Supporter"
+}
+
+// classifyChapterMember returns the status of a chapter member or
+// organizer under rule as of the start of month, or "" if they're
+// fine. warningDate is when they were last sent a warning, formatted
+// as YYYY-MM-DD, or empty. Warnings count for as long as the rule's
+// window.
+func classifyChapterMember(a mpi.Activist, warningDate string, month int, rule CMRetentionRule) string {
+	if !rule.eligible(a, month) {
+		warned := false
+		if d, err := time.Parse(EventDateLayout, warningDate); err == nil {
+			warned = mpi.MonthOf(d) >= mpi.AddMonths(month, -rule.Window)
+		}
+		if warned {
+			return CMStatusDemote
+		}
+		return CMStatusWarn
+	}
+
+	// Would they still be eligible next month without any
+	// attendance this month?
+	withoutThisMonth := a
+	withoutThisMonth.Attendance = nil
+	for _, m := range a.Attendance {
+		if m.Month != month {
+			withoutThisMonth.Attendance = append(withoutThisMonth.Attendance, m)
+		}
+	}
+	if !rule.eligible(withoutThisMonth, mpi.AddMonths(month, 1)) {
+		return CMStatusAtRisk
+	}
+	return ""
+}
+
+// CreateCMStatusReview evaluates every chapter member and organizer
+// against rule as of the start of month and records who is at risk,
+// should be warned or should lose their status. If the month was
+// already reviewed, the existing review is returned and created is
+// false.
+func CreateCMStatusReview(db *sqlx.DB, month int, rule CMRetentionRule) (review CMStatusReview, created bool, err error) {
+	if !rule.Enabled() {
+		return CMStatusReview{}, false, errors.New("No chapter member retention rule is set")
+	}
+	var existing int
+	if err := db.Get(&existing, `SELECT count(*) FROM cm_status_reviews WHERE month = ?`, month); err != nil {
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to check for chapter member review")
+	}
+	if existing != 0 {
+		review, err := GetCMStatusReview(db, month)
+		return review, false, err
+	}
+
+	levels := []interface{}{[]string{ACTIVIST_LEVEL_CHAPTER_MEMBER, "Organizer"}}
+	activists, err := getMPIActivists(db, "a.activist_level IN (?)", levels, month)
+	if err != nil {
+		return CMStatusReview{}, false, err
+	}
+	var warnings []struct {
+		ID             int            `db:"id"`
+		CMWarningEmail sql.NullString `db:"cm_warning_email"`
+	}
+	query, args, err := sqlx.In(`
+SELECT id, cm_warning_email
+FROM activists a
+WHERE a.hidden = 0 AND a.activist_level IN (?)`, levels...)
+	if err != nil {
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to build warning dates query")
+	}
+	if err := db.Select(&warnings, db.Rebind(query), args...); err != nil {
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to select warning dates")
+	}
+	warningDates := map[int]string{}
+	for _, w := range warnings {
+		warningDates[w.ID] = w.CMWarningEmail.String
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to create transaction")
+	}
+	res, err := tx.Exec(`INSERT INTO cm_status_reviews (month) VALUES (?)`, month)
+	if err != nil {
+		tx.Rollback()
+		return CMStatusReview{}, false, errors.Wrapf(err, "failed to insert chapter member review for %d", month)
+	}
+	reviewID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to get chapter member review id")
+	}
+	for _, a := range activists {
+		status := classifyChapterMember(a, warningDates[a.ID], month, rule)
+		if status == "" {
+			continue
+		}
+		proposedLevel := ""
+		if status == CMStatusDemote {
+			proposedLevel = demotedLevel(a.Level)
+		}
+		_, err := tx.Exec(`
+INSERT INTO cm_status_review_items (review_id, activist_id, status, past_3, current_level, proposed_level)
+VALUES (?, ?, ?, ?, ?, ?)`, reviewID, a.ID, status, mpi.Evaluate(a, month).Past3, a.Level, proposedLevel)
+		if err != nil {
+			tx.Rollback()
+			return CMStatusReview{}, false, errors.Wrapf(err, "failed to insert review of activist %d", a.ID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return CMStatusReview{}, false, errors.Wrap(err, "failed to commit chapter member review")
+	}
+
+	review, err = GetCMStatusReview(db, month)
+	return review, true, err
+}
+
+// GetCMStatusReview returns the review of a month. If month is 0, it
+// returns the latest review.
+func GetCMStatusReview(db *sqlx.DB, month int) (CMStatusReview, error) {
+	query := `SELECT id, month, created FROM cm_status_reviews`
+	var args []interface{}
+	if month != 0 {
+		query += ` WHERE month = ?`
+		args = append(args, month)
+	}
+	query += ` ORDER BY month DESC LIMIT 1`
+
+	var review CMStatusReview
+	err := db.Get(&review, query, args...)
+	if err == sql.ErrNoRows {
+		return CMStatusReview{}, errors.New("Chapter member review not found")
+	}
+	if err != nil {
+		return CMStatusReview{}, errors.Wrap(err, "failed to select chapter member review")
+	}
+
+	review.Items = []CMStatusReviewItem{}
+	err = db.Select(&review.Items, `
+SELECT
+  i.id,
+  i.review_id,
+  i.activist_id,
+  a.name,
+  a.email,
+  i.status,
+  i.past_3,
+  i.current_level,
+  i.proposed_level,
+  i.warning_sent,
+  i.decision,
+  i.decided_by_email
+FROM cm_status_review_items i
+JOIN activists a ON a.id = i.activist_id
+WHERE i.review_id = ?
+ORDER BY FIELD(i.status, ?, ?, ?), a.name`, review.ID, CMStatusDemote, CMStatusWarn, CMStatusAtRisk)
+	if err != nil {
+		return CMStatusReview{}, errors.Wrapf(err, "failed to select items of chapter member review %d", review.ID)
+	}
+	return review, nil
+}
+
+// GetCMStatusReviewByMonth is like GetCMStatusReview, but the month
+// is formatted as YYYY-MM. If it's empty, it returns the latest review.
+func GetCMStatusReviewByMonth(db *sqlx.DB, month string) (CMStatusReview, error) {
+	m := 0
+	if month != "" {
+		var err error
+		if m, err = parseMonth(month); err != nil {
+			return CMStatusReview{}, err
+		}
+	}
+	return GetCMStatusReview(db, m)
+}
+
+// MarkCMWarningSent records that the activist of a review item was
+// sent a warning, including in their cm_warning_email.
+func MarkCMWarningSent(db *sqlx.DB, item CMStatusReviewItem, sent time.Time) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	if _, err := tx.Exec(`UPDATE cm_status_review_items SET warning_sent = 1 WHERE id = ?`, item.ID); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to update review item %d", item.ID)
+	}
+	_, err = tx.Exec(`UPDATE activists SET cm_warning_email = ? WHERE id = ?`,
+		sent.Format(EventDateLayout), item.ActivistID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to update warning date of activist %d", item.ActivistID)
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to commit warning")
+	}
+	return nil
+}
+
+// DecideCMStatusChange approves or rejects the level change proposed
+// by a review item. Approving it changes the activist's level.
+func DecideCMStatusChange(db *sqlx.DB, itemID int, approve bool, user ADBUser) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	var item CMStatusReviewItem
+	err = tx.Get(&item, `
+SELECT id, activist_id, status, current_level, proposed_level, decision
+FROM cm_status_review_items
+WHERE id = ?
+FOR UPDATE`, itemID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return errors.Errorf("Review item %d does not exist", itemID)
+	}
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to select review item %d", itemID)
+	}
+	if item.ProposedLevel == "" {
+		tx.Rollback()
+		return errors.New("No level change was proposed")
+	}
+	if item.Decision != CMStatusDecisionPending {
+		tx.Rollback()
+		return errors.New("The level change was already " + item.Decision)
+	}
+
+	decision := CMStatusDecisionRejected
+	if approve {
+		decision = CMStatusDecisionApproved
+		var level string
+		if err := tx.Get(&level, `SELECT activist_level FROM activists WHERE id = ?`, item.ActivistID); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to select level of activist %d", item.ActivistID)
+		}
+		if level != item.CurrentLevel {
+			tx.Rollback()
+			return errors.Errorf("The activist's level changed to %s since the review", level)
+		}
+		if _, err := tx.Exec(`UPDATE activists SET activist_level = ? WHERE id = ?`, item.ProposedLevel, item.ActivistID); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to update level of activist %d", item.ActivistID)
+		}
+		_, err = tx.Exec(`
+INSERT INTO activists_history (activist_id, action, user_email, name, email, facebook, activist_level)
+SELECT id, 'UPDATE', ?, name, email, facebook, activist_level
+FROM activists
+WHERE id = ?`, user.Email, item.ActivistID)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to log level change of activist %d", item.ActivistID)
+		}
+		err = insertLevelChange(tx, LevelChange{
+			ActivistID: item.ActivistID,
+			OldLevel:   item.CurrentLevel,
+			NewLevel:   item.ProposedLevel,
+			ChangedAt:  time.Now(),
+			Source:     LevelChangeSourceUpdate,
+			UserEmail:  user.Email,
+		})
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+UPDATE cm_status_review_items
+SET decision = ?, decided_by_email = ?, decided_at = NOW()
+WHERE id = ?`, decision, user.Email, itemID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to update review item %d", itemID)
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to commit decision")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dxe/adb/mpi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyChapterMember(t *testing.T) {
+	da := func(month int) mpi.Month { return mpi.Month{Month: month, DirectAction: true} }
+	rule := CMRetentionRule{MinMonths: 2, Window: 3}
+	require.True(t, rule.Enabled())
+	require.False(t, CMRetentionRule{}.Enabled())
+
+	// MPI in February and March, so still eligible in May.
+	a := mpi.Activist{Level: ACTIVIST_LEVEL_CHAPTER_MEMBER, Attendance: []mpi.Month{da(202002), da(202003)}}
+	require.Equal(t, "", classifyChapterMember(a, "", 202004, rule))
+	a.Attendance = []mpi.Month{da(202003)}
+	require.Equal(t, CMStatusWarn, classifyChapterMember(a, "", 202004, rule))
+	// Eligible in April, but only March counts in May unless they're
+	// MPI in April.
+	a.Attendance = []mpi.Month{da(202001), da(202003)}
+	require.Equal(t, CMStatusAtRisk, classifyChapterMember(a, "", 202004, rule))
+
+	a.Attendance = nil
+	require.Equal(t, CMStatusWarn, classifyChapterMember(a, "2019-12-31", 202004, rule))
+	require.Equal(t, CMStatusDemote, classifyChapterMember(a, "2020-01-01", 202004, rule))
+	require.Equal(t, CMStatusDemote, classifyChapterMember(a, "2020-03-02", 202004, rule))
+}
+
+func TestCMStatusReview(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	warned, err := GetOrCreateActivist(db, "Warned")
+	require.NoError(t, err)
+	unwarned, err := GetOrCreateActivist(db, "Unwarned")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activists SET activist_level = 'Organizer', cm_warning_email = '2020-03-01' WHERE id = ?`, warned.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activists SET activist_level = ?, email = 'unwarned@example.org' WHERE id = ?`,
+		ACTIVIST_LEVEL_CHAPTER_MEMBER, unwarned.ID)
+	require.NoError(t, err)
+
+	rule := CMRetentionRule{MinMonths: 2, Window: 3}
+	_, _, err = CreateCMStatusReview(db, 202004, CMRetentionRule{})
+	require.Error(t, err)
+	review, created, err := CreateCMStatusReview(db, 202004, rule)
+	require.NoError(t, err)
+	require.True(t, created)
+	require.Len(t, review.Items, 2)
+	require.Equal(t, CMStatusDemote, review.Items[0].Status)
+	require.Equal(t, warned.ID, review.Items[0].ActivistID)
+	require.Equal(t, ACTIVIST_LEVEL_CHAPTER_MEMBER, review.Items[0].ProposedLevel)
+	require.Equal(t, CMStatusWarn, review.Items[1].Status)
+	require.Equal(t, "", review.Items[1].ProposedLevel)
+
+	_, created, err = CreateCMStatusReview(db, 202004, rule)
+	require.NoError(t, err)
+	require.False(t, created)
+
+	require.NoError(t, MarkCMWarningSent(db, review.Items[1], time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)))
+	var warning string
+	require.NoError(t, db.Get(&warning, `SELECT cm_warning_email FROM activists WHERE id = ?`, unwarned.ID))
+	require.Equal(t, "2020-04-01", warning)
+
+	// Nothing changes until the level change is approved.
+	var level string
+	require.NoError(t, db.Get(&level, `SELECT activist_level FROM activists WHERE id = ?`, warned.ID))
+	require.Equal(t, "Organizer", level)
+
+	user := ADBUser{Email: "admin@example.org"}
+	require.Error(t, DecideCMStatusChange(db, review.Items[1].ID, true, user))
+	require.NoError(t, DecideCMStatusChange(db, review.Items[0].ID, true, user))
+	require.Error(t, DecideCMStatusChange(db, review.Items[0].ID, false, user))
+	require.NoError(t, db.Get(&level, `SELECT activist_level FROM activists WHERE id = ?`, warned.ID))
+	require.Equal(t, ACTIVIST_LEVEL_CHAPTER_MEMBER, level)
+
+	review, err = GetCMStatusReviewByMonth(db, "2020-04")
+	require.NoError(t, err)
+	require.Equal(t, CMStatusDecisionApproved, review.Items[0].Decision)
+	require.Equal(t, "admin@example.org", review.Items[0].DecidedByEmail)
+}
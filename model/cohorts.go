@@ -0,0 +1,226 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+const (
+	CohortGroupByNone      = ""
+	CohortGroupByEventType = "event_type"
+	CohortGroupBySource    = "source"
+)
+
+const (
+	defaultCohortMonths = 12
+	maxCohortMonths     = 60
+)
+
+/** Type Definitions */
+
+type CohortOptions struct {
+	// One of the CohortGroupBy constants. Cohorts are split by the
+	// type of the activists' first event or by their source.
+	GroupBy string
+	// First event months to include, formatted as YYYY-MM. Either
+	// may be empty.
+	DateFrom string
+	DateTo   string
+	// Length of the curves, in months after the first event month.
+	Months int
+}
+
+// Cohort is the activists whose first event was in the same month
+// (and, if grouped, of the same type or from the same source).
+//
+// Active[k] is the number of them who attended an event k months
+// after their first month, and Retained[k] is the number who attended
+// an event k or more months after it. Curves stop at the last month
+// that has closed, so recent cohorts have shorter curves.
+type Cohort struct {
+	Month         string    `json:"month"`
+	Group         string    `json:"group"`
+	Size          int       `json:"size"`
+	Active        []int     `json:"active"`
+	Retained      []int     `json:"retained"`
+	RetentionRate []float64 `json:"retention_rate"`
+}
+
+type cohortActivist struct {
+	ID             int    `db:"id"`
+	Source         string `db:"source"`
+	FirstEventType string `db:"first_event_type"`
+	// Months with attendance, formatted as YYYYMM.
+	months []int
+}
+
+/** Functions and Methods */
+
+func CleanCohortOptions(groupBy, dateFrom, dateTo, months string) (CohortOptions, error) {
+	options := CohortOptions{GroupBy: groupBy, Months: defaultCohortMonths}
+	switch groupBy {
+	case CohortGroupByNone, CohortGroupByEventType, CohortGroupBySource:
+	default:
+		return CohortOptions{}, errors.New("Cohorts can only be grouped by event_type or source")
+	}
+	for _, m := range []string{dateFrom, dateTo} {
+		if m != "" {
+			if _, err := parseMonth(m); err != nil {
+				return CohortOptions{}, err
+			}
+		}
+	}
+	options.DateFrom = dateFrom
+	options.DateTo = dateTo
+	if months != "" {
+		n, err := strconv.Atoi(months)
+		if err != nil || n < 1 || n > maxCohortMonths {
+			return CohortOptions{}, errors.Errorf("Months must be between 1 and %d", maxCohortMonths)
+		}
+		options.Months = n
+	}
+	return options, nil
+}
+
+// GetCohorts builds cohorts from event attendance as of now.
+func GetCohorts(db *sqlx.DB, options CohortOptions, now time.Time) ([]Cohort, error) {
+	var activists []cohortActivist
+	err := db.Select(&activists, `
+SELECT
+  a.id,
+  a.source,
+  (SELECT e.event_type
+    FROM event_attendance ea
+    JOIN events e ON e.id = ea.event_id
+    WHERE ea.activist_id = a.id
+    ORDER BY e.date, e.id
+    LIMIT 1) AS first_event_type
+FROM activists a
+WHERE a.hidden = 0
+  AND EXISTS (SELECT 1 FROM event_attendance ea WHERE ea.activist_id = a.id)`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select cohort activists")
+	}
+
+	var attendance []struct {
+		ActivistID int `db:"activist_id"`
+		Month      int `db:"month"`
+	}
+	err = db.Select(&attendance, `
+SELECT DISTINCT ea.activist_id, extract(year_month from e.date) AS month
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+ORDER BY ea.activist_id, month`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select cohort attendance")
+	}
+	byID := map[int]*cohortActivist{}
+	for i := range activists {
+		byID[activists[i].ID] = &activists[i]
+	}
+	for _, att := range attendance {
+		if a, ok := byID[att.ActivistID]; ok {
+			a.months = append(a.months, att.Month)
+		}
+	}
+
+	return buildCohorts(activists, options, PreviousMonth(now))
+}
+
+// buildCohorts groups activists into cohorts, skipping cohorts after
+// the through month, and computes their curves up to it. Each
+// activist's months must be sorted.
+func buildCohorts(activists []cohortActivist, options CohortOptions, through int) ([]Cohort, error) {
+	from, to := 0, through
+	if options.DateFrom != "" {
+		m, err := parseMonth(options.DateFrom)
+		if err != nil {
+			return nil, err
+		}
+		from = m
+	}
+	if options.DateTo != "" {
+		m, err := parseMonth(options.DateTo)
+		if err != nil {
+			return nil, err
+		}
+		if m < to {
+			to = m
+		}
+	}
+
+	type cohortKey struct {
+		month int
+		group string
+	}
+	members := map[cohortKey][]cohortActivist{}
+	for _, a := range activists {
+		if len(a.months) == 0 || a.months[0] < from || a.months[0] > to {
+			continue
+		}
+		key := cohortKey{month: a.months[0]}
+		switch options.GroupBy {
+		case CohortGroupByEventType:
+			key.group = a.FirstEventType
+		case CohortGroupBySource:
+			key.group = strings.TrimSpace(a.Source)
+		}
+		members[key] = append(members[key], a)
+	}
+
+	var keys []cohortKey
+	for k := range members {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].month != keys[j].month {
+			return keys[i].month < keys[j].month
+		}
+		return keys[i].group < keys[j].group
+	})
+
+	cohorts := []Cohort{}
+	for _, k := range keys {
+		// Offsets of the months in the curve. The month of the
+		// first event is offset 0.
+		var offsets []int
+		for i, m := 0, k.month; i < options.Months && m <= through; i, m = i+1, nextMonth(m) {
+			offsets = append(offsets, m)
+		}
+		cohort := Cohort{
+			Month:         formatMonth(k.month),
+			Group:         k.group,
+			Size:          len(members[k]),
+			Active:        make([]int, len(offsets)),
+			Retained:      make([]int, len(offsets)),
+			RetentionRate: make([]float64, len(offsets)),
+		}
+		for _, a := range members[k] {
+			attended := map[int]bool{}
+			for _, m := range a.months {
+				attended[m] = true
+			}
+			last := a.months[len(a.months)-1]
+			for i, m := range offsets {
+				if attended[m] {
+					cohort.Active[i]++
+				}
+				if last >= m {
+					cohort.Retained[i]++
+				}
+			}
+		}
+		for i := range offsets {
+			cohort.RetentionRate[i] = float64(cohort.Retained[i]) / float64(cohort.Size)
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	return cohorts, nil
+}
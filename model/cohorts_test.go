@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCohorts(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+	a3, err := GetOrCreateActivist(db, "Hey")
+	require.NoError(t, err)
+
+	insert := func(date time.Time, eventType EventType, attendees ...Activist) {
+		_, err := InsertUpdateEvent(db, Event{
+			EventName:      "event",
+			EventDate:      date,
+			EventType:      eventType,
+			AddedAttendees: attendees,
+		})
+		require.NoError(t, err)
+	}
+	insert(time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC), "Action", a1)
+	insert(time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC), "Community", a2)
+	insert(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), "Action", a1, a3)
+
+	now := time.Date(2020, 4, 15, 0, 0, 0, 0, time.UTC)
+	options, err := CleanCohortOptions("", "", "", "6")
+	require.NoError(t, err)
+	cohorts, err := GetCohorts(db, options, now)
+	require.NoError(t, err)
+	require.Len(t, cohorts, 2)
+
+	require.Equal(t, "2020-01", cohorts[0].Month)
+	require.Equal(t, 2, cohorts[0].Size)
+	// The curve stops at March, the last closed month.
+	require.Equal(t, []int{2, 0, 1}, cohorts[0].Active)
+	require.Equal(t, []int{2, 1, 1}, cohorts[0].Retained)
+	require.Equal(t, []float64{1, 0.5, 0.5}, cohorts[0].RetentionRate)
+
+	require.Equal(t, "2020-03", cohorts[1].Month)
+	require.Equal(t, []int{1}, cohorts[1].Active)
+
+	options, err = CleanCohortOptions(CohortGroupByEventType, "2020-01", "2020-01", "")
+	require.NoError(t, err)
+	cohorts, err = GetCohorts(db, options, now)
+	require.NoError(t, err)
+	require.Len(t, cohorts, 2)
+	require.Equal(t, "Action", cohorts[0].Group)
+	require.Equal(t, "Community", cohorts[1].Group)
+
+	_, err = CleanCohortOptions("name", "", "", "")
+	require.Error(t, err)
+}
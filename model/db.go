@@ -26,13 +26,31 @@ func WipeDatabase(db *sqlx.DB) {
 	db.MustExec(`DROP TABLE IF EXISTS users_roles`)
 	db.MustExec(`DROP TABLE IF EXISTS adb_users`)
 	db.MustExec(`DROP TABLE IF EXISTS merged_activist_attendance`)
-	db.MustExec(`DROP TABLE IF EXISTS working_groups`)
-	db.MustExec(`DROP TABLE IF EXISTS working_group_members`)
-	db.MustExec(`DROP TABLE IF EXISTS circles`)
-	db.MustExec(`DROP TABLE IF EXISTS circle_members`)
+	db.MustExec(`DROP TABLE IF EXISTS activist_groups`)
+	db.MustExec(`DROP TABLE IF EXISTS activist_group_members`)
+	db.MustExec(`DROP TABLE IF EXISTS activist_group_memberships`)
+	db.MustExec(`DROP TABLE IF EXISTS geocoded_addresses`)
 	db.MustExec(`DROP TABLE IF EXISTS fb_pages`)
 	db.MustExec(`DROP TABLE IF EXISTS fb_events`)
 	db.MustExec(`DROP TABLE IF EXISTS discord_users`)
+	db.MustExec(`DROP TABLE IF EXISTS calendar_tokens`)
+	db.MustExec(`DROP TABLE IF EXISTS event_types`)
+	db.MustExec(`DROP TABLE IF EXISTS event_attendance_log`)
+	db.MustExec(`DROP TABLE IF EXISTS archived_events`)
+	db.MustExec(`DROP TABLE IF EXISTS archived_event_attendance`)
+	db.MustExec(`DROP TABLE IF EXISTS attendance_roles`)
+	db.MustExec(`DROP TABLE IF EXISTS mpi_snapshots`)
+	db.MustExec(`DROP TABLE IF EXISTS mpi_snapshot_levels`)
+	db.MustExec(`DROP TABLE IF EXISTS wallboard_tokens`)
+	db.MustExec(`DROP TABLE IF EXISTS activist_level_changes`)
+	db.MustExec(`DROP TABLE IF EXISTS cm_status_reviews`)
+	db.MustExec(`DROP TABLE IF EXISTS cm_status_review_items`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_list_sync_runs`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_list_sync_lists`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_list_sync_items`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_lists`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_list_overrides`)
+	db.MustExec(`DROP TABLE IF EXISTS mailing_list_holds`)
 
 	db.MustExec(`
 CREATE TABLE activists (
@@ -113,6 +131,8 @@ CREATE TABLE activists_history (
 CREATE TABLE event_attendance (
   activist_id INTEGER NOT NULL,
   event_id INTEGER NOT NULL,
+  -- One of attendance_roles, or '' if the activist had no role.
+  role VARCHAR(40) NOT NULL DEFAULT '',
   UNIQUE (activist_id, event_id),
   UNIQUE (event_id, activist_id)
 )
@@ -125,7 +145,10 @@ CREATE TABLE events (
   date DATE NOT NULL,
   event_type VARCHAR(60) NOT NULL,
   survey_sent TINYINT(1) NOT NULL DEFAULT '0',
+  -- The group whose meeting this was, or 0.
+  group_id INTEGER NOT NULL DEFAULT '0',
   INDEX (date, name),
+  INDEX (group_id, date),
   FULLTEXT (name)
 )
 `)
@@ -151,58 +174,60 @@ CREATE TABLE merged_activist_attendance (
 `)
 
 	db.MustExec(`
-CREATE TABLE working_groups (
+CREATE TABLE activist_groups (
   id INTEGER PRIMARY KEY AUTO_INCREMENT,
   name VARCHAR(60) NOT NULL,
-  type TINYINT(1) NOT NULL,
+  -- working_group, committee or circle.
+  type VARCHAR(20) NOT NULL,
   group_email VARCHAR(100) NOT NULL,
   visible TINYINT(1) NOT NULL DEFAULT '0',
   description TEXT NOT NULL,
   meeting_time TEXT NOT NULL,
   meeting_location TEXT NOT NULL,
   coords TEXT NOT NULL,
-  UNIQUE (name)
+  -- Parsed from coords.
+  lat DOUBLE NULL DEFAULT NULL,
+  lng DOUBLE NULL DEFAULT NULL,
+  -- Archived groups keep their members for reporting, but aren't
+  -- listed or synced to mailing lists.
+  archived TINYINT(1) NOT NULL DEFAULT '0',
+  archived_at TIMESTAMP NULL DEFAULT NULL,
+  archived_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  -- The most leads the group can have. 0 means no limit.
+  max_leads INTEGER NOT NULL DEFAULT '1',
+  -- Guardrails against removing too many members from the group's
+  -- mailing list. See model/mailing_list_holds.go.
+  max_removals INTEGER NOT NULL DEFAULT '10',
+  max_remove_percent INTEGER NOT NULL DEFAULT '50',
+  allow_empty TINYINT(1) NOT NULL DEFAULT '0',
+  UNIQUE (type, name)
 )
 `)
 
 	db.MustExec(`
-CREATE TABLE working_group_members (
-  working_group_id INTEGER NOT NULL,
+CREATE TABLE activist_group_members (
+  group_id INTEGER NOT NULL,
   activist_id INTEGER NOT NULL,
-  -- True if the activist is the point person of the working group.
-  -- There should be only one point person per working group, but we
-  -- don't restrict that on the backend.
-  point_person TINYINT NOT NULL DEFAULT '0',
-  -- Some activists need to be on the mailing list even though they
-  -- aren't in the workin group.
-  non_member_on_mailing_list TINYINT NOT NULL DEFAULT '0',
-  UNIQUE (working_group_id, activist_id),
+  -- lead, co_lead, member, mailing_list_only or observer. Only leads,
+  -- co-leads and members count as members of the group.
+  role VARCHAR(20) NOT NULL DEFAULT 'member',
+  UNIQUE (group_id, activist_id),
   INDEX (activist_id)
 )
 `)
 
 	db.MustExec(`
-CREATE TABLE circles (
+CREATE TABLE activist_group_memberships (
   id INTEGER PRIMARY KEY AUTO_INCREMENT,
-  name VARCHAR(60) NOT NULL,
-  type TINYINT(1) NOT NULL,
-  group_email VARCHAR(100) NOT NULL DEFAULT '1',
-  visible TINYINT(1) NOT NULL DEFAULT '1',
-  description TEXT NOT NULL,
-  meeting_time TEXT NOT NULL,
-  meeting_location TEXT NOT NULL,
-  coords TEXT NOT NULL,
-  UNIQUE (name)
-)
-`)
-
-	db.MustExec(`
-CREATE TABLE circle_members (
-  circle_id INTEGER NOT NULL,
+  group_id INTEGER NOT NULL,
   activist_id INTEGER NOT NULL,
-  point_person TINYINT NOT NULL DEFAULT '0',
-  non_member_on_mailing_list TINYINT NOT NULL DEFAULT '0',
-  UNIQUE (circle_id, activist_id),
+  -- NULL if the activist joined before memberships were recorded.
+  started TIMESTAMP NULL DEFAULT NULL,
+  -- NULL while the activist is still a member.
+  ended TIMESTAMP NULL DEFAULT NULL,
+  added_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  removed_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  INDEX (group_id, activist_id),
   INDEX (activist_id)
 )
 `)
@@ -269,6 +294,275 @@ CREATE TABLE discord_users (
   token VARCHAR(64) NOT NULL,
   confirmed TINYINT(1) NOT NULL DEFAULT '0'
 )
+`)
+
+	db.MustExec(`
+CREATE TABLE calendar_tokens (
+  token VARCHAR(64) PRIMARY KEY,
+  -- Exactly one of adb_user_id and activist_id is set.
+  adb_user_id INTEGER NOT NULL DEFAULT '0',
+  activist_id INTEGER NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (adb_user_id, activist_id)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE event_types (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(60) NOT NULL,
+  -- One of 'direct_action', 'community' or 'none'.
+  mpi_category VARCHAR(20) NOT NULL DEFAULT 'none',
+  -- Leaderboard points for attending an event of this type.
+  points INTEGER NOT NULL DEFAULT '1',
+  UNIQUE (name)
+)
+`)
+
+	db.MustExec(`
+INSERT INTO event_types (name, mpi_category) VALUES
+  ('Action', 'direct_action'),
+  ('Campaign Action', 'direct_action'),
+  ('Circle', 'community'),
+  ('Community', 'community'),
+  ('Connection', 'none'),
+  ('Frontline Surveillance', 'direct_action'),
+  ('Meeting', 'none'),
+  ('Outreach', 'direct_action'),
+  ('Sanctuary', 'direct_action'),
+  ('Training', 'community')
+`)
+
+	db.MustExec(`
+CREATE TABLE event_attendance_log (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  event_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  -- 'add', 'remove' or 'set_role'.
+  action VARCHAR(10) NOT NULL,
+  -- The new role for 'set_role', otherwise ''.
+  role VARCHAR(40) NOT NULL DEFAULT '',
+  -- 'editor', 'import', 'merge', 'delete' or 'restore'.
+  source VARCHAR(20) NOT NULL,
+  user_id INTEGER NOT NULL,
+  user_email VARCHAR(80) NOT NULL,
+  timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX (event_id),
+  INDEX (activist_id)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE archived_events (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  -- The id the event had in the events table.
+  event_id INTEGER NOT NULL,
+  name VARCHAR(60) NOT NULL,
+  date DATE NOT NULL,
+  event_type VARCHAR(60) NOT NULL,
+  survey_sent TINYINT(1) NOT NULL DEFAULT '0',
+  group_id INTEGER NOT NULL DEFAULT '0',
+  archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  archived_by_user_id INTEGER NOT NULL,
+  archived_by_email VARCHAR(80) NOT NULL,
+  INDEX (event_id),
+  INDEX (archived_at)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE archived_event_attendance (
+  archived_event_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  role VARCHAR(40) NOT NULL DEFAULT '',
+  UNIQUE (archived_event_id, activist_id),
+  INDEX (activist_id)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE attendance_roles (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(40) NOT NULL,
+  -- Leaderboard points on top of the event type's points.
+  points INTEGER NOT NULL DEFAULT '0',
+  UNIQUE (name)
+)
+`)
+
+	db.MustExec(`
+INSERT INTO attendance_roles (name, points) VALUES
+  ('Host', 2),
+  ('Marshal', 1),
+  ('Police Liaison', 1),
+  ('Speaker', 1),
+  ('Volunteer', 0)
+`)
+
+	db.MustExec(`
+CREATE TABLE mpi_snapshots (
+  -- Formatted as YYYYMM.
+  month INTEGER PRIMARY KEY,
+  mpi INTEGER NOT NULL,
+  active_chapter_members INTEGER NOT NULL,
+  -- Whether the snapshot was reconstructed after the month closed.
+  backfilled TINYINT(1) NOT NULL DEFAULT '0',
+  created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mpi_snapshot_levels (
+  month INTEGER NOT NULL,
+  activist_level VARCHAR(40) NOT NULL,
+  activists INTEGER NOT NULL,
+  PRIMARY KEY (month, activist_level)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE wallboard_tokens (
+  token VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(80) NOT NULL,
+  -- Chapter whose upcoming events are shown, or 0 for online events.
+  page_id BIGINT NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE activist_level_changes (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  activist_id INTEGER NOT NULL,
+  -- Empty if the previous level is unknown.
+  old_level VARCHAR(40) NOT NULL DEFAULT '',
+  new_level VARCHAR(40) NOT NULL,
+  changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- update, backfill_history or backfill_date_organizer.
+  source VARCHAR(30) NOT NULL,
+  user_email VARCHAR(80) NOT NULL DEFAULT '',
+  INDEX (activist_id)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE cm_status_reviews (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  -- Formatted as YYYYMM.
+  month INTEGER NOT NULL,
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (month)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE cm_status_review_items (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  review_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  -- at_risk, warn or demote.
+  status VARCHAR(20) NOT NULL,
+  past_3 INTEGER NOT NULL,
+  current_level VARCHAR(40) NOT NULL,
+  -- Set for demote; only applied once an organizer approves it.
+  proposed_level VARCHAR(40) NOT NULL DEFAULT '',
+  warning_sent TINYINT(1) NOT NULL DEFAULT '0',
+  -- '', approved or rejected.
+  decision VARCHAR(20) NOT NULL DEFAULT '',
+  decided_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  decided_at TIMESTAMP NULL DEFAULT NULL,
+  UNIQUE (review_id, activist_id),
+  INDEX (activist_id)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE geocoded_addresses (
+  address VARCHAR(255) PRIMARY KEY,
+  -- False if the address couldn't be found.
+  found TINYINT(1) NOT NULL,
+  lat DOUBLE NOT NULL DEFAULT '0',
+  lng DOUBLE NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_list_sync_runs (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  started TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished TIMESTAMP NULL DEFAULT NULL,
+  -- Empty for scheduled runs.
+  triggered_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  failed TINYINT(1) NOT NULL DEFAULT '0',
+  INDEX (started)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_list_sync_lists (
+  run_id INTEGER NOT NULL,
+  list_email VARCHAR(100) NOT NULL,
+  failed TINYINT(1) NOT NULL DEFAULT '0',
+  -- Activists who couldn't be synced.
+  skipped INTEGER NOT NULL DEFAULT '0',
+  UNIQUE (run_id, list_email)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_list_sync_items (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  run_id INTEGER NOT NULL,
+  list_email VARCHAR(100) NOT NULL,
+  -- add, remove, fail or skip.
+  action VARCHAR(10) NOT NULL,
+  member_email VARCHAR(100) NOT NULL DEFAULT '',
+  activist_name VARCHAR(80) NOT NULL DEFAULT '',
+  reason TEXT NOT NULL,
+  INDEX (run_id, list_email)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_lists (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  email VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL,
+  -- activist_level, group_members, group_leads, group_emails or
+  -- manual. See model/mailing_lists.go for what rule_value holds.
+  rule_type VARCHAR(20) NOT NULL,
+  rule_value TEXT NOT NULL,
+  -- Guardrails against removing too many members. See
+  -- model/mailing_list_holds.go.
+  max_removals INTEGER NOT NULL DEFAULT '10',
+  max_remove_percent INTEGER NOT NULL DEFAULT '50',
+  allow_empty TINYINT(1) NOT NULL DEFAULT '0',
+  UNIQUE (email)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_list_overrides (
+  list_id INTEGER NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  -- 1 if the email is always on the list, 0 if it never is.
+  include TINYINT(1) NOT NULL,
+  UNIQUE (list_id, email)
+)
+`)
+
+	db.MustExec(`
+CREATE TABLE mailing_list_holds (
+  list_email VARCHAR(100) PRIMARY KEY,
+  held DATETIME NOT NULL,
+  reason VARCHAR(200) NOT NULL,
+  -- pending, approved or rejected.
+  status VARCHAR(20) NOT NULL,
+  -- Newline-separated emails.
+  additions TEXT NOT NULL,
+  removals TEXT NOT NULL
+)
 `)
 
 }
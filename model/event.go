@@ -1,6 +1,7 @@
 package model
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"io"
@@ -15,34 +16,27 @@ import (
 
 const EventDateLayout string = "2006-01-02"
 
-var EventTypes map[string]bool = map[string]bool{
-	"Action":                 true,
-	"Campaign Action":        true,
-	"Circle":                 true,
-	"Community":              true,
-	"Frontline Surveillance": true,
-	"Meeting":                true,
-	"Outreach":               true,
-	"Sanctuary":              true,
-	"Training":               true,
-	"Connection":             true,
-}
-
 /** Type Definitions */
 
 type EventType string
 
 /* TODO Restructure this struct */
 type EventJSON struct {
-	EventID          int      `json:"event_id"`
-	EventName        string   `json:"event_name"`
-	EventDate        string   `json:"event_date"`
-	EventType        string   `json:"event_type"`
+	EventID   int    `json:"event_id"`
+	EventName string `json:"event_name"`
+	EventDate string `json:"event_date"`
+	EventType string `json:"event_type"`
+	// The group whose meeting this was, or 0.
+	GroupID          int      `json:"group_id"`
 	Attendees        []string `json:"attendees"` // For displaying all event attendees
 	AttendeeEmails   []string `json:"attendee_emails"`
 	AttendeeIDs      []int    `json:"attendee_ids"`
+	AttendeeRoles    []string `json:"attendee_roles"`
 	AddedAttendees   []string `json:"added_attendees"`   // Used for Updating Events
 	DeletedAttendees []string `json:"deleted_attendees"` // Used for Updating Events
+	// Maps attendee names to their new role. Attendees that
+	// aren't listed keep their role.
+	UpdatedRoles map[string]string `json:"updated_roles"`
 }
 
 /* TODO Restructure this Struct */
@@ -52,12 +46,16 @@ type Event struct {
 	EventDate             time.Time `db:"date"`
 	EventType             EventType `db:"event_type"`
 	SurveySent            int       `db:"survey_sent"` // Used for sending event surveys
+	GroupID               int       `db:"group_id"`
 	Attendees             []string  // For retrieving all event attendees
 	AttendeeEmails        []string
 	AttendeeIDs           []int
-	AttendeeMissingEmails []string   // Used for sending event surveys
-	AddedAttendees        []Activist // Used for Updating Events
-	DeletedAttendees      []Activist // Used for Updating Events
+	AttendeeRoles         []string
+	AttendeeMissingEmails []string         // Used for sending event surveys
+	AddedAttendees        []Activist       // Used for Updating Events
+	DeletedAttendees      []Activist       // Used for Updating Events
+	UpdatedRoles          []AttendeeRole   // Used for Updating Events
+	AttendanceChange      AttendanceChange // Used for logging attendance changes
 }
 
 func (event *Event) ToJSON() EventJSON {
@@ -66,9 +64,11 @@ func (event *Event) ToJSON() EventJSON {
 		EventName:      event.EventName,
 		EventDate:      event.EventDate.Format(EventDateLayout),
 		EventType:      string(event.EventType),
+		GroupID:        event.GroupID,
 		Attendees:      event.Attendees,
 		AttendeeEmails: event.AttendeeEmails,
 		AttendeeIDs:    event.AttendeeIDs,
+		AttendeeRoles:  event.AttendeeRoles,
 	}
 }
 
@@ -83,6 +83,23 @@ type GetEventOptions struct {
 	EventNameQuery string
 	EventActivist  string
 	SurveySent     string
+	// ActivistID limits the results to events attended by the
+	// activist.
+	ActivistID int
+	// WorkingGroupID limits the results to events attended by at
+	// least one member of the group.
+	WorkingGroupID int
+	// GroupID limits the results to meetings of the group.
+	GroupID int
+	// PlannedForActivistID limits the results to events the
+	// activist is on the attendance of and meetings of groups
+	// they're a member of. Combine it with DateFrom to only get
+	// upcoming events.
+	PlannedForActivistID int
+	// AttendeeRole limits the results to events where someone had
+	// the role. Combined with ActivistID, it limits them to events
+	// where that activist had the role.
+	AttendeeRole string
 }
 
 /** Functions and Methods */
@@ -121,7 +138,7 @@ func GetEvent(db *sqlx.DB, options GetEventOptions) (Event, error) {
 }
 
 func getEvents(db *sqlx.DB, options GetEventOptions) ([]Event, error) {
-	query := `SELECT e.id, e.name, e.date, e.event_type, e.survey_sent FROM events e `
+	query := `SELECT e.id, e.name, e.date, e.event_type, e.survey_sent, e.group_id FROM events e `
 
 	// Items in whereClause are added to the query in order, separated by ' AND '.
 	var whereClause []string
@@ -146,6 +163,35 @@ ON (e.id = ea.event_id AND ea.activist_id = a.id)
 	if options.EventID != 0 {
 		where("e.id = ?", options.EventID)
 	}
+	if options.ActivistID != 0 {
+		where("e.id IN (SELECT event_id FROM event_attendance WHERE activist_id = ?)", options.ActivistID)
+	}
+	if options.AttendeeRole != "" {
+		if options.ActivistID != 0 {
+			where("e.id IN (SELECT event_id FROM event_attendance WHERE activist_id = ? AND role = ?)", options.ActivistID, options.AttendeeRole)
+		} else {
+			where("e.id IN (SELECT event_id FROM event_attendance WHERE role = ?)", options.AttendeeRole)
+		}
+	}
+	if options.WorkingGroupID != 0 {
+		where(`e.id IN (
+  SELECT ea.event_id
+  FROM event_attendance ea
+  JOIN activist_group_members gm ON gm.activist_id = ea.activist_id
+  WHERE gm.group_id = ?
+    AND gm.role IN (`+groupMemberRolesQuery+`))`, options.WorkingGroupID)
+	}
+	if options.GroupID != 0 {
+		where("e.group_id = ?", options.GroupID)
+	}
+	if options.PlannedForActivistID != 0 {
+		where(`(e.id IN (SELECT event_id FROM event_attendance WHERE activist_id = ?)
+  OR e.group_id IN (
+    SELECT group_id
+    FROM activist_group_members
+    WHERE activist_id = ? AND role IN (`+groupMemberRolesQuery+`)))`,
+			options.PlannedForActivistID, options.PlannedForActivistID)
+	}
 	if options.DateFrom != "" {
 		where("e.date >= ?", options.DateFrom)
 	}
@@ -158,9 +204,9 @@ ON (e.id = ea.event_id AND ea.activist_id = a.id)
 	if options.EventType == "noConnections" {
 		where("e.event_type <> 'Connection'")
 	} else if options.EventType == "mpiDA" {
-		where("e.event_type in ('Outreach', 'Action', 'Campaign Action', 'Sanctuary', 'Frontline Surveillance')")
+		where("e.event_type in (" + mpiDirectActionEventTypesQuery + ")")
 	} else if options.EventType == "mpiCOM" {
-		where("e.event_type in ('Community', 'Training', 'Circle')")
+		where("e.event_type in (" + mpiCommunityEventTypesQuery + ")")
 	} else if options.EventType != "" {
 		where("e.event_type like ?", options.EventType)
 	}
@@ -203,7 +249,8 @@ SELECT
   ea.event_id,
   a.name as activist_name,
   a.email as activist_email,
-  a.id as activist_id
+  a.id as activist_id,
+  ea.role
 FROM activists a
 JOIN event_attendance ea
   ON a.id = ea.activist_id
@@ -219,6 +266,7 @@ WHERE
 		ActivistName  string `db:"activist_name"`
 		ActivistEmail string `db:"activist_email"`
 		ActivistID    int    `db:"activist_id"`
+		Role          string `db:"role"`
 	}
 	var allAttendance []Attendance
 	err = db.Select(&allAttendance, attendanceQuery, attendanceArgs...)
@@ -231,6 +279,7 @@ WHERE
 		events[i].Attendees = append(events[i].Attendees, a.ActivistName)
 		events[i].AttendeeEmails = append(events[i].AttendeeEmails, a.ActivistEmail)
 		events[i].AttendeeIDs = append(events[i].AttendeeIDs, a.ActivistID)
+		events[i].AttendeeRoles = append(events[i].AttendeeRoles, a.Role)
 	}
 
 	return events, nil
@@ -249,17 +298,61 @@ func GetEventAttendance(db *sqlx.DB, eventID int) ([]string, error) {
 	return attendees, nil
 }
 
-func DeleteEvent(db *sqlx.DB, eventID int) error {
+// DeleteEvent moves the event and its attendance to the archive
+// tables, so it no longer counts anywhere but can be restored with
+// RestoreArchivedEvent until it's purged.
+func DeleteEvent(db *sqlx.DB, eventID int, user ADBUser) error {
 	tx, err := db.Beginx()
 	if err != nil {
 		return errors.Wrap(err, "failed to create transaction")
 	}
+	res, err := tx.Exec(`
+INSERT INTO archived_events (event_id, name, date, event_type, survey_sent, group_id, archived_by_user_id, archived_by_email)
+SELECT id, name, date, event_type, survey_sent, group_id, ?, ?
+FROM events
+WHERE id = ?`, user.ID, user.Email, eventID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to archive event %d", eventID)
+	}
+	archivedEventID, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to get archived event id")
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return errors.Errorf("Event with id %d does not exist", eventID)
+	}
+	_, err = tx.Exec(`
+INSERT INTO archived_event_attendance (archived_event_id, activist_id, role)
+SELECT ?, activist_id, role
+FROM event_attendance
+WHERE event_id = ?`, archivedEventID, eventID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to archive event attendance for event %d", eventID)
+	}
+
+	var attendeeIDs []int
+	err = tx.Select(&attendeeIDs, `SELECT activist_id FROM event_attendance WHERE event_id = ?`, eventID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to get event attendance for event %d", eventID)
+	}
 	_, err = tx.Exec(`DELETE FROM event_attendance
 WHERE event_id = ?`, eventID)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrapf(err, "failed to delete event attendance for event %d", eventID)
 	}
+	change := AttendanceChange{User: user, Source: AttendanceSourceDeleteEvent}
+	for _, activistID := range attendeeIDs {
+		if err := logAttendanceChange(tx, change, eventID, activistID, AttendanceActionRemove); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
 	_, err = tx.Exec(`DELETE FROM events
 WHERE id = ?`, eventID)
@@ -288,14 +381,6 @@ func (et *EventType) Scan(src interface{}) error {
 	return nil
 }
 
-func getEventType(rawEventType string) (EventType, error) {
-	rawEventType = strings.TrimSpace(rawEventType)
-	if EventTypes[rawEventType] {
-		return EventType(rawEventType), nil
-	}
-	return "", errors.New("Not a valid event type: " + rawEventType)
-}
-
 func InsertUpdateEvent(db *sqlx.DB, event Event) (eventID int, err error) {
 	if event.ID == 0 {
 		return insertEvent(db, event)
@@ -308,8 +393,8 @@ func insertEvent(db *sqlx.DB, event Event) (eventID int, err error) {
 	if err != nil {
 		return 0, errors.Wrap(err, "failed to create transaction")
 	}
-	res, err := tx.NamedExec(`INSERT INTO events (name, date, event_type)
-VALUES (:name, :date, :event_type)`, event)
+	res, err := tx.NamedExec(`INSERT INTO events (name, date, event_type, group_id)
+VALUES (:name, :date, :event_type, :group_id)`, event)
 	if err != nil {
 		tx.Rollback()
 		return 0, errors.Wrap(err, "failed to insert event")
@@ -354,7 +439,8 @@ func updateEvent(db *sqlx.DB, event Event) (eventID int, err error) {
 SET
   name = :name,
   date = :date,
-  event_type = :event_type
+  event_type = :event_type,
+  group_id = :group_id
 WHERE
   id = :id`, event)
 	if err != nil {
@@ -397,6 +483,40 @@ WHERE
 	return event.ID, nil
 }
 
+// AddEventAttendance adds activists to an existing event without
+// touching the event's other fields or existing attendees.
+func AddEventAttendance(db *sqlx.DB, eventID int, attendees []Activist, change AttendanceChange) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	if err := addEventAttendance(tx, eventID, attendees, change); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to commit event attendance")
+	}
+	return nil
+}
+
+func addEventAttendance(tx *sqlx.Tx, eventID int, attendees []Activist, change AttendanceChange) error {
+	var eventCount int
+	err := tx.Get(&eventCount, `SELECT count(*) FROM events WHERE id = ?`, eventID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get event count")
+	}
+	if eventCount == 0 {
+		return errors.Errorf("Event with id %d does not exist", eventID)
+	}
+
+	if err := insertEventAttendance(tx, Event{ID: eventID, AddedAttendees: attendees, AttendanceChange: change}); err != nil {
+		return errors.Wrap(err, "failed to insert event attendance")
+	}
+	return nil
+}
+
 /* Changes: Delete removed activists from attendance and add new ones */
 func insertEventAttendance(tx *sqlx.Tx, event Event) error {
 	if event.ID == 0 {
@@ -405,11 +525,14 @@ func insertEventAttendance(tx *sqlx.Tx, event Event) error {
 	}
 	// First, remove deleted attendees.
 	for _, u := range event.DeletedAttendees {
-		_, err := tx.Exec(`DELETE FROM event_attendance WHERE event_id = ?
+		res, err := tx.Exec(`DELETE FROM event_attendance WHERE event_id = ?
         AND activist_id = ?`, event.ID, u.ID)
 		if err != nil {
 			return errors.Wrap(err, "failed to delete attendees")
 		}
+		if err := logAttendanceResult(tx, res, event, u.ID, AttendanceActionRemove); err != nil {
+			return err
+		}
 	}
 	// Add new attendees to the event_attendance
 	seen := map[int]bool{}
@@ -421,13 +544,30 @@ func insertEventAttendance(tx *sqlx.Tx, event Event) error {
 		seen[u.ID] = true
 		// Insert new (activist_id, event_id) pairs to event_attendance table
 		// For duplicates,  set activist_id equal to itself. In other words, do nothing
-		_, err := tx.Exec(`INSERT INTO event_attendance (activist_id, event_id)
+		res, err := tx.Exec(`INSERT INTO event_attendance (activist_id, event_id)
             VALUES(?,?) ON DUPLICATE KEY UPDATE activist_id = activist_id`, u.ID, event.ID)
 		if err != nil {
 			return errors.Wrap(err, "failed to insert attendees")
 		}
+		if err := logAttendanceResult(tx, res, event, u.ID, AttendanceActionAdd); err != nil {
+			return err
+		}
 	}
-	return nil
+	return updateAttendeeRoles(tx, event)
+}
+
+// logAttendanceResult logs an attendance change if the statement
+// actually changed a row, so that re-saving an event with the same
+// attendees doesn't fill the log.
+func logAttendanceResult(tx *sqlx.Tx, res sql.Result, event Event, activistID int, action string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to get affected rows")
+	}
+	if n == 0 {
+		return nil
+	}
+	return logAttendanceChange(tx, event.AttendanceChange, event.ID, activistID, action)
 }
 
 func CleanEventData(db *sqlx.DB, body io.Reader) (Event, error) {
@@ -451,12 +591,19 @@ func CleanEventData(db *sqlx.DB, body io.Reader) (Event, error) {
 		return Event{}, err
 	}
 	e.EventDate = t
-	eventType, err := getEventType(eventJSON.EventType)
+	eventType, err := getEventType(db, eventJSON.EventType)
 	if err != nil {
 		return Event{}, err
 	}
 	e.EventType = eventType
 
+	if eventJSON.GroupID != 0 {
+		if _, err := GetGroup(db, GroupQueryOptions{GroupID: eventJSON.GroupID}); err != nil {
+			return Event{}, err
+		}
+		e.GroupID = eventJSON.GroupID
+	}
+
 	addedAttendees, err := cleanEventAttendanceData(db, eventJSON.AddedAttendees)
 	if err != nil {
 		return Event{}, err
@@ -470,6 +617,25 @@ func CleanEventData(db *sqlx.DB, body io.Reader) (Event, error) {
 	e.AddedAttendees = addedAttendees
 	e.DeletedAttendees = deletedAttendees
 
+	// Roles are only set for attendees, so they're looked up
+	// rather than created. Attendees added above already exist.
+	for name, rawRole := range eventJSON.UpdatedRoles {
+		if err := checkForDangerousChars(name); err != nil {
+			return Event{}, err
+		}
+		attendee, err := GetActivist(db, strings.Title(strings.TrimSpace(name)))
+		if err != nil {
+			return Event{}, errors.Wrapf(err, "Can't set the role of %s", name)
+		}
+		role, err := getAttendanceRole(db, rawRole)
+		if err != nil {
+			return Event{}, err
+		}
+		e.UpdatedRoles = append(e.UpdatedRoles, AttendeeRole{Activist: attendee, Role: role})
+	}
+
+	e.AttendanceChange.Source = AttendanceSourceEditor
+
 	return e, nil
 }
 
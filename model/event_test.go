@@ -235,7 +235,7 @@ func TestDeleteEvents(t *testing.T) {
 	}
 
 	// Delete the first event
-	err = DeleteEvent(db, 1)
+	err = DeleteEvent(db, 1, DevTestUser)
 	require.NoError(t, err)
 
 	gotEvents, err := GetEvents(db, GetEventOptions{})
@@ -282,3 +282,33 @@ func TestCleanEventAttendanceData(t *testing.T) {
 	}
 	require.Equal(t, gotActivistNames, wantActivistNames)
 }
+
+func TestGetEvents_plannedForActivist(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+	group := Group{Name: "Outreach", Type: GroupTypeWorkingGroup}
+	group.Members = []GroupMember{{ActivistID: a1.ID, ActivistName: a1.Name, Role: GroupRoleMember}}
+	groupID, err := CreateGroup(db, group, "")
+	require.NoError(t, err)
+
+	date := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	_, err = InsertUpdateEvent(db, Event{EventName: "meeting", EventDate: date, EventType: "Community", GroupID: groupID})
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{EventName: "protest", EventDate: date, EventType: "Action", AddedAttendees: []Activist{a1}})
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{EventName: "other", EventDate: date, EventType: "Action", AddedAttendees: []Activist{a2}})
+	require.NoError(t, err)
+
+	events, err := GetEvents(db, GetEventOptions{PlannedForActivistID: a1.ID})
+	require.NoError(t, err)
+	var names []string
+	for _, e := range events {
+		names = append(names, e.EventName)
+	}
+	require.ElementsMatch(t, []string{"meeting", "protest"}, names)
+}
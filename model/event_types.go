@@ -0,0 +1,234 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/dxe/adb/mpi"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// MPI categories an event type can count towards. See the mpi
+// package for how they're used.
+const (
+	MPICategoryDirectAction = mpi.CategoryDirectAction
+	MPICategoryCommunity    = mpi.CategoryCommunity
+	MPICategoryNone         = mpi.CategoryNone
+)
+
+var validMPICategories = map[string]bool{
+	MPICategoryDirectAction: true,
+	MPICategoryCommunity:    true,
+	MPICategoryNone:         true,
+}
+
+// Coaching sessions are stored as events of this type, and parts of
+// the ADB look for it by name, so it can't be renamed or deleted.
+const connectionEventType = "Connection"
+
+const selectEventTypeBaseQuery string = `
+SELECT
+  id,
+  name,
+  mpi_category,
+  points
+FROM event_types
+`
+
+// Subqueries for the names of the event types in each MPI category,
+// for use in `event_type IN (...)` expressions.
+const (
+	mpiDirectActionEventTypesQuery = `SELECT name FROM event_types WHERE mpi_category = '` + MPICategoryDirectAction + `'`
+	mpiCommunityEventTypesQuery    = `SELECT name FROM event_types WHERE mpi_category = '` + MPICategoryCommunity + `'`
+)
+
+/** Type Definitions */
+
+type EventTypeData struct {
+	ID          int    `db:"id"`
+	Name        string `db:"name"`
+	MPICategory string `db:"mpi_category"`
+	Points      int    `db:"points"`
+}
+
+type EventTypeJSON struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	MPICategory string `json:"mpi_category"`
+	Points      int    `json:"points"`
+}
+
+/** Functions and Methods */
+
+func GetEventTypes(db *sqlx.DB) ([]EventTypeData, error) {
+	var types []EventTypeData
+	if err := db.Select(&types, selectEventTypeBaseQuery+` ORDER BY name`); err != nil {
+		return nil, errors.Wrap(err, "failed to select event types")
+	}
+	return types, nil
+}
+
+func GetEventTypesJSON(db *sqlx.DB) ([]EventTypeJSON, error) {
+	types, err := GetEventTypes(db)
+	if err != nil {
+		return nil, err
+	}
+	typesJSON := []EventTypeJSON{}
+	for _, t := range types {
+		typesJSON = append(typesJSON, EventTypeJSON{
+			ID:          t.ID,
+			Name:        t.Name,
+			MPICategory: t.MPICategory,
+			Points:      t.Points,
+		})
+	}
+	return typesJSON, nil
+}
+
+func getEventTypeByID(tx *sqlx.Tx, id int) (EventTypeData, error) {
+	var t EventTypeData
+	err := tx.Get(&t, selectEventTypeBaseQuery+` WHERE id = ?`, id)
+	if err == sql.ErrNoRows {
+		return EventTypeData{}, errors.Errorf("Event type with id %d does not exist", id)
+	}
+	if err != nil {
+		return EventTypeData{}, errors.Wrapf(err, "failed to select event type %d", id)
+	}
+	return t, nil
+}
+
+func getEventType(db *sqlx.DB, rawEventType string) (EventType, error) {
+	rawEventType = strings.TrimSpace(rawEventType)
+	var name string
+	err := db.Get(&name, `SELECT name FROM event_types WHERE name = ?`, rawEventType)
+	if err == sql.ErrNoRows {
+		return "", errors.New("Not a valid event type: " + rawEventType)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to select event type")
+	}
+	return EventType(name), nil
+}
+
+func CleanEventTypeData(id int, name, mpiCategory string, points int) (EventTypeData, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return EventTypeData{}, errors.New("Name cannot be empty")
+	}
+	if err := checkForDangerousChars(name); err != nil {
+		return EventTypeData{}, err
+	}
+	if !validMPICategories[mpiCategory] {
+		return EventTypeData{}, errors.New("Not a valid MPI category: " + mpiCategory)
+	}
+	if points < 0 {
+		return EventTypeData{}, errors.New("Points cannot be negative")
+	}
+	return EventTypeData{
+		ID:          id,
+		Name:        name,
+		MPICategory: mpiCategory,
+		Points:      points,
+	}, nil
+}
+
+func CreateEventType(db *sqlx.DB, t EventTypeData) (int, error) {
+	if t.ID != 0 {
+		return 0, errors.New("Event type ID must be 0")
+	}
+	res, err := db.NamedExec(`
+INSERT INTO event_types (name, mpi_category, points)
+VALUES (:name, :mpi_category, :points)`, t)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to insert event type %s", t.Name)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get inserted event type id")
+	}
+	return int(id), nil
+}
+
+// UpdateEventType saves an event type. Renaming a type also renames
+// it on all of its events.
+func UpdateEventType(db *sqlx.DB, t EventTypeData) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	old, err := getEventTypeByID(tx, t.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if old.Name == connectionEventType && t.Name != old.Name {
+		tx.Rollback()
+		return errors.New("The Connection event type cannot be renamed")
+	}
+
+	_, err = tx.NamedExec(`
+UPDATE event_types
+SET
+  name = :name,
+  mpi_category = :mpi_category,
+  points = :points
+WHERE id = :id`, t)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to update event type %d", t.ID)
+	}
+	if t.Name != old.Name {
+		_, err = tx.Exec(`UPDATE events SET event_type = ? WHERE event_type = ?`, t.Name, old.Name)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to rename event type %s", old.Name)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to commit event type %d", t.ID)
+	}
+	return nil
+}
+
+// DeleteEventType deletes an event type that no events use.
+func DeleteEventType(db *sqlx.DB, id int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	t, err := getEventTypeByID(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if t.Name == connectionEventType {
+		tx.Rollback()
+		return errors.New("The Connection event type cannot be deleted")
+	}
+
+	var eventCount int
+	err = tx.Get(&eventCount, `SELECT count(*) FROM events WHERE event_type = ?`, t.Name)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "failed to count events")
+	}
+	if eventCount != 0 {
+		tx.Rollback()
+		return errors.Errorf("Cannot delete %s, %d events have that type", t.Name, eventCount)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM event_types WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to delete event type %d", id)
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to commit deleting event type %d", id)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDeleteEventType(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	id, err := CreateEventType(db, EventTypeData{Name: "Vigil", MPICategory: MPICategoryDirectAction, Points: 2})
+	require.NoError(t, err)
+
+	eventID, err := InsertUpdateEvent(db, Event{
+		EventName: "Vigil",
+		EventDate: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		EventType: "Vigil",
+	})
+	require.NoError(t, err)
+
+	// Events in use can't be deleted.
+	require.Error(t, DeleteEventType(db, id))
+
+	// Renaming a type renames it on its events.
+	require.NoError(t, UpdateEventType(db, EventTypeData{ID: id, Name: "Slaughterhouse Vigil", MPICategory: MPICategoryDirectAction, Points: 2}))
+	event, err := GetEvent(db, GetEventOptions{EventID: eventID})
+	require.NoError(t, err)
+	require.EqualValues(t, "Slaughterhouse Vigil", event.EventType)
+
+	events, err := GetEvents(db, GetEventOptions{EventType: "mpiDA"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	_, err = db.Exec(`DELETE FROM events WHERE id = ?`, eventID)
+	require.NoError(t, err)
+	require.NoError(t, DeleteEventType(db, id))
+
+	types, err := GetEventTypes(db)
+	require.NoError(t, err)
+	for _, et := range types {
+		require.NotEqual(t, id, et.ID)
+		if et.Name == "Connection" {
+			require.Error(t, DeleteEventType(db, et.ID))
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
-	"strconv"
 	"time"
 )
 
@@ -251,54 +250,43 @@ func GetAllFBPagesByRegion(db *sqlx.DB) (map[string][]FacebookPageOutput, error)
 	return regions, nil
 }
 
-func GetFacebookEvents(db *sqlx.DB, pageID int, startTime string, endTime string) ([]FacebookEventOutput, error) {
-	query := `SELECT id, page_id, name, description, start_time, end_time, location_name,
-		location_country, location_country, location_state, location_address, location_zip,
+const selectFacebookEventsBaseQuery string = `SELECT id, page_id, name, description, start_time, end_time, location_name,
+		location_city, location_country, location_state, location_address, location_zip,
 		lat, lng, cover, attending_count, interested_count, is_canceled, last_update FROM fb_events`
 
-	query += " WHERE is_canceled = 0 and page_id = " + strconv.Itoa(pageID)
-
-	if startTime != "" {
-		query += " and start_time >= '" + startTime + "'"
-	}
-	if endTime != "" {
-		// we actually want to show events which have a START time before the query's end time
-		// otherwise really long (or recurring) events could be hidden
-		query += " and start_time <= '" + endTime + "'"
-	}
-
-	query += " ORDER BY start_time"
-
-	var events []FacebookEventOutput
-	err := db.Select(&events, query)
-	if err != nil {
-		// error
-		return nil, errors.Wrap(err, "failed to select events")
-	}
-
-	return events, nil
+// Canceled events are left out unless includeCanceled is set, e.g.
+// for calendar feeds, which need to mark them as canceled instead.
+func GetFacebookEvents(db *sqlx.DB, pageID int, startTime string, endTime string, includeCanceled bool) ([]FacebookEventOutput, error) {
+	return getFacebookEvents(db, "page_id = ?", []interface{}{pageID}, startTime, endTime, includeCanceled)
 }
 
-func GetOnlineFacebookEvents(db *sqlx.DB, startTime string, endTime string) ([]FacebookEventOutput, error) {
+func GetOnlineFacebookEvents(db *sqlx.DB, startTime string, endTime string, includeCanceled bool) ([]FacebookEventOutput, error) {
 	// TODO: move these page IDs to config variables?
-	query := `SELECT id, page_id, name, description, start_time, end_time, location_name,
-		location_country, location_country, location_state, location_address, location_zip,
-		lat, lng, cover, attending_count, interested_count, is_canceled, last_update FROM fb_events
-		WHERE is_canceled = 0 and ((page_id = 1377014279263790 and location_name = 'Online') or page_id = 287332515138353)`
+	return getFacebookEvents(db, "((page_id = 1377014279263790 and location_name = 'Online') or page_id = 287332515138353)", nil, startTime, endTime, includeCanceled)
+}
 
+func getFacebookEvents(db *sqlx.DB, filter string, filterArgs []interface{}, startTime string, endTime string, includeCanceled bool) ([]FacebookEventOutput, error) {
+	query := selectFacebookEventsBaseQuery + " WHERE " + filter
+	queryArgs := filterArgs
+
+	if !includeCanceled {
+		query += " and is_canceled = 0"
+	}
 	if startTime != "" {
-		query += " and start_time >= '" + startTime + "'"
+		query += " and start_time >= ?"
+		queryArgs = append(queryArgs, startTime)
 	}
 	if endTime != "" {
 		// we actually want to show events which have a START time before the query's end time
 		// otherwise really long (or recurring) events could be hidden
-		query += " and start_time <= '" + endTime + "'"
+		query += " and start_time <= ?"
+		queryArgs = append(queryArgs, endTime)
 	}
 
 	query += " ORDER BY start_time"
 
 	var events []FacebookEventOutput
-	err := db.Select(&events, query)
+	err := db.Select(&events, query, queryArgs...)
 	if err != nil {
 		// error
 		return nil, errors.Wrap(err, "failed to select events")
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Global Variable Definitions */
+
+const (
+	// Non-members who attended at least this share of a group's
+	// meetings, and at least regularAttendeeMinMeetings of them,
+	// are reported as regular attendees.
+	regularAttendeeRate        = 0.5
+	regularAttendeeMinMeetings = 2
+)
+
+/** Type Definitions */
+
+// GroupAttendanceReport summarizes attendance at the meetings of a
+// group, which are the events linked to it.
+type GroupAttendanceReport struct {
+	GroupID int `json:"group_id"`
+	// Meetings on or after Since, formatted as YYYY-MM-DD, are
+	// counted in attendance rates.
+	Since    string `json:"since"`
+	Meetings int    `json:"meetings"`
+	// Current members, by name.
+	Members []GroupAttendee `json:"members"`
+	// Members who haven't attended a meeting since InactiveSince,
+	// least recently seen first.
+	InactiveSince   string          `json:"inactive_since"`
+	InactiveMembers []GroupAttendee `json:"inactive_members"`
+	// Non-members who attend regularly and should be invited,
+	// most meetings attended first.
+	RegularNonMembers []GroupAttendee `json:"regular_non_members"`
+}
+
+type GroupAttendee struct {
+	ActivistID   int    `json:"activist_id"`
+	ActivistName string `json:"activist_name"`
+	// Meetings attended since the start of the report.
+	Attended int     `json:"attended"`
+	Rate     float64 `json:"rate"`
+	// Date of the last meeting attended, ever. Empty if they never
+	// attended one.
+	LastAttended string `json:"last_attended"`
+}
+
+type groupMeeting struct {
+	ID   int       `db:"id"`
+	Date time.Time `db:"date"`
+}
+
+type groupMeetingAttendance struct {
+	EventID      int    `db:"event_id"`
+	ActivistID   int    `db:"activist_id"`
+	ActivistName string `db:"activist_name"`
+}
+
+/** Functions and Methods */
+
+// GetGroupAttendanceReport reports attendance at a group's meetings
+// over the last weeks weeks, and which members haven't attended in
+// inactiveWeeks weeks.
+func GetGroupAttendanceReport(db *sqlx.DB, groupID, weeks, inactiveWeeks int, now time.Time) (GroupAttendanceReport, error) {
+	if weeks < 1 || inactiveWeeks < 1 {
+		return GroupAttendanceReport{}, errors.New("The number of weeks must be positive")
+	}
+	group, err := GetGroup(db, GroupQueryOptions{GroupID: groupID})
+	if err != nil {
+		return GroupAttendanceReport{}, err
+	}
+
+	var meetings []groupMeeting
+	err = db.Select(&meetings, `
+SELECT id, date
+FROM events
+WHERE group_id = ?`, groupID)
+	if err != nil {
+		return GroupAttendanceReport{}, errors.Wrapf(err, "failed to select meetings of group %d", groupID)
+	}
+	var attendance []groupMeetingAttendance
+	err = db.Select(&attendance, `
+SELECT ea.event_id, ea.activist_id, a.name AS activist_name
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+JOIN activists a ON a.id = ea.activist_id
+WHERE e.group_id = ?
+  AND a.hidden = 0`, groupID)
+	if err != nil {
+		return GroupAttendanceReport{}, errors.Wrapf(err, "failed to select meeting attendance of group %d", groupID)
+	}
+
+	return buildGroupAttendanceReport(group, meetings, attendance, weeks, inactiveWeeks, now), nil
+}
+
+func buildGroupAttendanceReport(group Group, meetings []groupMeeting, attendance []groupMeetingAttendance, weeks, inactiveWeeks int, now time.Time) GroupAttendanceReport {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	since := today.AddDate(0, 0, -7*weeks)
+	inactiveSince := today.AddDate(0, 0, -7*inactiveWeeks)
+
+	meetingDates := map[int]time.Time{}
+	inWindow := map[int]bool{}
+	report := GroupAttendanceReport{
+		GroupID:           group.ID,
+		Since:             since.Format(EventDateLayout),
+		InactiveSince:     inactiveSince.Format(EventDateLayout),
+		Members:           []GroupAttendee{},
+		InactiveMembers:   []GroupAttendee{},
+		RegularNonMembers: []GroupAttendee{},
+	}
+	for _, m := range meetings {
+		meetingDates[m.ID] = m.Date
+		if !m.Date.Before(since) && !m.Date.After(today) {
+			inWindow[m.ID] = true
+			report.Meetings++
+		}
+	}
+
+	attendees := map[int]*GroupAttendee{}
+	lastAttended := map[int]time.Time{}
+	attendee := func(id int, name string) *GroupAttendee {
+		if attendees[id] == nil {
+			attendees[id] = &GroupAttendee{ActivistID: id, ActivistName: name}
+		}
+		return attendees[id]
+	}
+	for _, a := range attendance {
+		date, ok := meetingDates[a.EventID]
+		if !ok || date.After(today) {
+			continue
+		}
+		at := attendee(a.ActivistID, a.ActivistName)
+		if inWindow[a.EventID] {
+			at.Attended++
+		}
+		if date.After(lastAttended[a.ActivistID]) {
+			lastAttended[a.ActivistID] = date
+		}
+	}
+	for id, at := range attendees {
+		if report.Meetings != 0 {
+			at.Rate = float64(at.Attended) / float64(report.Meetings)
+		}
+		if last, ok := lastAttended[id]; ok {
+			at.LastAttended = last.Format(EventDateLayout)
+		}
+	}
+
+	isMember := map[int]bool{}
+	for _, m := range group.Members {
+		if !m.IsMember() {
+			continue
+		}
+		isMember[m.ActivistID] = true
+		at := *attendee(m.ActivistID, m.ActivistName)
+		report.Members = append(report.Members, at)
+		if last, ok := lastAttended[m.ActivistID]; !ok || last.Before(inactiveSince) {
+			report.InactiveMembers = append(report.InactiveMembers, at)
+		}
+	}
+	for id, at := range attendees {
+		if isMember[id] {
+			continue
+		}
+		if at.Attended >= regularAttendeeMinMeetings && at.Rate >= regularAttendeeRate {
+			report.RegularNonMembers = append(report.RegularNonMembers, *at)
+		}
+	}
+
+	sort.Slice(report.Members, func(i, j int) bool {
+		return report.Members[i].ActivistName < report.Members[j].ActivistName
+	})
+	sort.Slice(report.InactiveMembers, func(i, j int) bool {
+		a, b := report.InactiveMembers[i], report.InactiveMembers[j]
+		if a.LastAttended != b.LastAttended {
+			return a.LastAttended < b.LastAttended
+		}
+		return a.ActivistName < b.ActivistName
+	})
+	sort.Slice(report.RegularNonMembers, func(i, j int) bool {
+		a, b := report.RegularNonMembers[i], report.RegularNonMembers[j]
+		if a.Attended != b.Attended {
+			return a.Attended > b.Attended
+		}
+		return a.ActivistName < b.ActivistName
+	})
+	return report
+}
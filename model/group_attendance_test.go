@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildGroupAttendanceReport(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(EventDateLayout, s)
+		if err != nil {
+			panic(err)
+		}
+		return d
+	}
+	group := Group{
+		ID: 1,
+		Members: []GroupMember{
+			{ActivistID: 1, ActivistName: "Alice", Role: GroupRoleLead},
+			{ActivistID: 2, ActivistName: "Bob", Role: GroupRoleMember},
+			{ActivistID: 3, ActivistName: "Carol", Role: GroupRoleMember},
+			// Only on the mailing list, so they count as a
+			// non-member.
+			{ActivistID: 4, ActivistName: "Dan", Role: GroupRoleMailingListOnly},
+		},
+	}
+	meetings := []groupMeeting{
+		// Before the report starts.
+		{ID: 1, Date: date("2020-01-01")},
+		{ID: 2, Date: date("2020-03-04")},
+		{ID: 3, Date: date("2020-03-11")},
+		{ID: 4, Date: date("2020-03-18")},
+		{ID: 5, Date: date("2020-03-25")},
+		// Not held yet.
+		{ID: 6, Date: date("2020-04-15")},
+	}
+	attendance := []groupMeetingAttendance{
+		{EventID: 1, ActivistID: 2, ActivistName: "Bob"},
+		{EventID: 2, ActivistID: 1, ActivistName: "Alice"},
+		{EventID: 3, ActivistID: 1, ActivistName: "Alice"},
+		{EventID: 4, ActivistID: 1, ActivistName: "Alice"},
+		{EventID: 5, ActivistID: 1, ActivistName: "Alice"},
+		{EventID: 2, ActivistID: 4, ActivistName: "Dan"},
+		{EventID: 3, ActivistID: 4, ActivistName: "Dan"},
+		{EventID: 4, ActivistID: 5, ActivistName: "Erin"},
+		{EventID: 6, ActivistID: 5, ActivistName: "Erin"},
+	}
+	now := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	report := buildGroupAttendanceReport(group, meetings, attendance, 8, 2, now)
+	require.Equal(t, "2020-02-05", report.Since)
+	require.Equal(t, 4, report.Meetings)
+	require.Equal(t, []GroupAttendee{
+		{ActivistID: 1, ActivistName: "Alice", Attended: 4, Rate: 1, LastAttended: "2020-03-25"},
+		{ActivistID: 2, ActivistName: "Bob", LastAttended: "2020-01-01"},
+		{ActivistID: 3, ActivistName: "Carol"},
+	}, report.Members)
+	require.Equal(t, []GroupAttendee{
+		{ActivistID: 3, ActivistName: "Carol"},
+		{ActivistID: 2, ActivistName: "Bob", LastAttended: "2020-01-01"},
+	}, report.InactiveMembers)
+	require.Equal(t, []GroupAttendee{
+		{ActivistID: 4, ActivistName: "Dan", Attended: 2, Rate: 0.5, LastAttended: "2020-03-11"},
+	}, report.RegularNonMembers)
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"time"
+
+	"github.com/dxe/adb/mpi"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// GroupMembership is a period during which an activist was a member
+// of a group. Ended is null while they're still a member.
+type GroupMembership struct {
+	ID             int            `db:"id"`
+	GroupID        int            `db:"group_id"`
+	GroupName      string         `db:"group_name"`
+	GroupType      string         `db:"group_type"`
+	ActivistID     int            `db:"activist_id"`
+	ActivistName   string         `db:"activist_name"`
+	Started        mysql.NullTime `db:"started"`
+	Ended          mysql.NullTime `db:"ended"`
+	AddedByEmail   string         `db:"added_by_email"`
+	RemovedByEmail string         `db:"removed_by_email"`
+}
+
+type GroupMembershipJSON struct {
+	GroupID      int    `json:"group_id"`
+	GroupName    string `json:"group_name"`
+	GroupType    string `json:"group_type"`
+	ActivistID   int    `json:"activist_id"`
+	ActivistName string `json:"activist_name"`
+	// Empty if the activist joined before memberships were
+	// recorded.
+	Started string `json:"started"`
+	// Empty if the activist is still a member.
+	Ended          string `json:"ended"`
+	AddedByEmail   string `json:"added_by_email"`
+	RemovedByEmail string `json:"removed_by_email"`
+}
+
+type GroupSize struct {
+	// Formatted as YYYY-MM.
+	Month string `json:"month"`
+	// Members at the end of the month, or now for the current month.
+	Members int `json:"members"`
+}
+
+/** Functions and Methods */
+
+// recordGroupMembershipChanges starts memberships for activists who
+// joined the group and ends them for activists who left.
+func recordGroupMembershipChanges(tx *sqlx.Tx, groupID int, before, after []int, userEmail string, now time.Time) error {
+	wasMember := map[int]bool{}
+	for _, id := range before {
+		wasMember[id] = true
+	}
+	isMember := map[int]bool{}
+	for _, id := range after {
+		isMember[id] = true
+	}
+
+	for _, id := range before {
+		if isMember[id] {
+			continue
+		}
+		_, err := tx.Exec(`
+UPDATE activist_group_memberships
+SET ended = ?, removed_by_email = ?
+WHERE group_id = ? AND activist_id = ? AND ended IS NULL`, now, userEmail, groupID, id)
+		if err != nil {
+			return errors.Wrapf(err, "failed to end membership of activist %d", id)
+		}
+	}
+	for _, id := range after {
+		if wasMember[id] {
+			continue
+		}
+		_, err := tx.Exec(`
+INSERT INTO activist_group_memberships (group_id, activist_id, started, added_by_email)
+VALUES (?, ?, ?, ?)`, groupID, id, now, userEmail)
+		if err != nil {
+			return errors.Wrapf(err, "failed to start membership of activist %d", id)
+		}
+	}
+	return nil
+}
+
+const selectGroupMembershipsQuery = `
+SELECT
+  m.id,
+  m.group_id,
+  g.name AS group_name,
+  g.type AS group_type,
+  m.activist_id,
+  a.name AS activist_name,
+  m.started,
+  m.ended,
+  m.added_by_email,
+  m.removed_by_email
+FROM activist_group_memberships m
+JOIN activist_groups g ON g.id = m.group_id
+JOIN activists a ON a.id = m.activist_id
+`
+
+// GetGroupMembersAsOf returns the memberships of a group that were
+// current at t.
+func GetGroupMembersAsOf(db *sqlx.DB, groupID int, t time.Time) ([]GroupMembership, error) {
+	var memberships []GroupMembership
+	err := db.Select(&memberships, selectGroupMembershipsQuery+`
+WHERE m.group_id = ?
+  AND (m.started IS NULL OR m.started <= ?)
+  AND (m.ended IS NULL OR m.ended > ?)
+ORDER BY a.name`, groupID, t, t)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select members of group %d", groupID)
+	}
+	return memberships, nil
+}
+
+// GetActivistGroupHistory returns every group membership of an
+// activist, most recent first.
+func GetActivistGroupHistory(db *sqlx.DB, activistID int) ([]GroupMembership, error) {
+	var memberships []GroupMembership
+	err := db.Select(&memberships, selectGroupMembershipsQuery+`
+WHERE m.activist_id = ?
+ORDER BY m.ended IS NULL DESC, m.ended DESC, m.started DESC`, activistID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select group history of activist %d", activistID)
+	}
+	return memberships, nil
+}
+
+func GroupMembershipsToJSON(memberships []GroupMembership) []GroupMembershipJSON {
+	formatTime := func(t mysql.NullTime) string {
+		if !t.Valid {
+			return ""
+		}
+		return t.Time.Format(time.RFC3339)
+	}
+	out := []GroupMembershipJSON{}
+	for _, m := range memberships {
+		out = append(out, GroupMembershipJSON{
+			GroupID:        m.GroupID,
+			GroupName:      m.GroupName,
+			GroupType:      m.GroupType,
+			ActivistID:     m.ActivistID,
+			ActivistName:   m.ActivistName,
+			Started:        formatTime(m.Started),
+			Ended:          formatTime(m.Ended),
+			AddedByEmail:   m.AddedByEmail,
+			RemovedByEmail: m.RemovedByEmail,
+		})
+	}
+	return out
+}
+
+// groupSizes counts the memberships that were current at the end of
+// each month from the from month through the to month (formatted as
+// YYYYMM). The current month is counted as of now.
+func groupSizes(memberships []GroupMembership, from, to int, now time.Time) []GroupSize {
+	sizes := []GroupSize{}
+	for m := from; m <= to; m = nextMonth(m) {
+		t := monthStart(nextMonth(m))
+		if t.After(now) {
+			t = now
+		}
+		n := 0
+		for _, membership := range memberships {
+			started := !membership.Started.Valid || !membership.Started.Time.After(t)
+			ended := membership.Ended.Valid && !membership.Ended.Time.After(t)
+			if started && !ended {
+				n++
+			}
+		}
+		sizes = append(sizes, GroupSize{Month: formatMonth(m), Members: n})
+	}
+	return sizes
+}
+
+// GetGroupSizeHistory returns the size of a group at the end of each
+// month between from and to, formatted as YYYY-MM. If from is empty,
+// it starts 12 months ago; if to is empty, it ends this month.
+func GetGroupSizeHistory(db *sqlx.DB, groupID int, from, to string, now time.Time) ([]GroupSize, error) {
+	toMonth := mpi.MonthOf(now)
+	if to != "" {
+		m, err := parseMonth(to)
+		if err != nil {
+			return nil, err
+		}
+		toMonth = m
+	}
+	fromMonth := mpi.AddMonths(toMonth, -11)
+	if from != "" {
+		m, err := parseMonth(from)
+		if err != nil {
+			return nil, err
+		}
+		fromMonth = m
+	}
+	if fromMonth > toMonth {
+		return nil, errors.New("The start month must not be after the end month")
+	}
+
+	var memberships []GroupMembership
+	err := db.Select(&memberships, selectGroupMembershipsQuery+`
+WHERE m.group_id = ?`, groupID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select memberships of group %d", groupID)
+	}
+	return groupSizes(memberships, fromMonth, toMonth, now), nil
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupMembershipHistory(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	group := Group{
+		Name: "Outreach",
+		Type: GroupTypeCircle,
+	}
+	members := insertActivists(t, db, []string{"Alice", "Bob", "Carol"})
+	group.Members = members[:2]
+	id, err := CreateGroup(db, group, "first@example.org")
+	require.NoError(t, err)
+	group.ID = id
+
+	beforeUpdate := time.Now()
+	time.Sleep(time.Second)
+
+	group.Members = members[1:]
+	_, err = UpdateGroup(db, group, "second@example.org")
+	require.NoError(t, err)
+
+	current, err := GetGroupMembersAsOf(db, id, time.Now())
+	require.NoError(t, err)
+	require.Len(t, current, 2)
+	require.Equal(t, "Bob", current[0].ActivistName)
+	require.Equal(t, "Carol", current[1].ActivistName)
+
+	previous, err := GetGroupMembersAsOf(db, id, beforeUpdate)
+	require.NoError(t, err)
+	require.Len(t, previous, 2)
+	require.Equal(t, "Alice", previous[0].ActivistName)
+	require.Equal(t, "Bob", previous[1].ActivistName)
+
+	history, err := GetActivistGroupHistory(db, members[0].ActivistID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "Outreach", history[0].GroupName)
+	require.Equal(t, "first@example.org", history[0].AddedByEmail)
+	require.Equal(t, "second@example.org", history[0].RemovedByEmail)
+	require.True(t, history[0].Ended.Valid)
+}
+
+func TestGroupSizes(t *testing.T) {
+	at := func(s string) mysql.NullTime {
+		d, err := time.Parse(EventDateLayout, s)
+		if err != nil {
+			panic(err)
+		}
+		return mysql.NullTime{Time: d, Valid: true}
+	}
+	memberships := []GroupMembership{
+		// Joined before memberships were recorded.
+		{},
+		{Started: at("2020-02-10")},
+		{Started: at("2020-01-15"), Ended: at("2020-02-20")},
+	}
+	now := time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	sizes := groupSizes(memberships, 201912, 202003, now)
+	require.Equal(t, []GroupSize{
+		{Month: "2019-12", Members: 1},
+		{Month: "2020-01", Members: 2},
+		{Month: "2020-02", Members: 2},
+		{Month: "2020-03", Members: 2},
+	}, sizes)
+}
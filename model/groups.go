@@ -0,0 +1,796 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+const (
+	GroupTypeWorkingGroup = "working_group"
+	GroupTypeCommittee    = "committee"
+	GroupTypeCircle       = "circle"
+)
+
+// GroupTypes are the valid types of groups. All types share the same
+// fields and membership, so adding a type only means adding it here.
+var GroupTypes = []string{
+	GroupTypeWorkingGroup,
+	GroupTypeCommittee,
+	GroupTypeCircle,
+}
+
+func isGroupType(t string) bool {
+	for _, groupType := range GroupTypes {
+		if t == groupType {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	GroupRoleLead   = "lead"
+	GroupRoleCoLead = "co_lead"
+	GroupRoleMember = "member"
+	// Not a member, but on the group's mailing list.
+	GroupRoleMailingListOnly = "mailing_list_only"
+	// Not a member and not on the mailing list, but following
+	// along, e.g. as a prospective member.
+	GroupRoleObserver = "observer"
+)
+
+// GroupMemberRoles are the valid roles of group members.
+var GroupMemberRoles = []string{
+	GroupRoleLead,
+	GroupRoleCoLead,
+	GroupRoleMember,
+	GroupRoleMailingListOnly,
+	GroupRoleObserver,
+}
+
+// groupMemberRolesQuery are the roles that make an activist a member
+// of a group, formatted for use in SQL queries.
+const groupMemberRolesQuery = `'lead', 'co_lead', 'member'`
+
+func isGroupMemberRole(role string) bool {
+	for _, r := range GroupMemberRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+/** User-defined Types */
+
+type Group struct {
+	ID              int    `db:"id"`
+	Name            string `db:"name"`
+	Type            string `db:"type"`
+	GroupEmail      string `db:"group_email"`
+	Members         []GroupMember
+	Visible         bool   `db:"visible"`
+	Description     string `db:"description"`
+	MeetingTime     string `db:"meeting_time"`
+	MeetingLocation string `db:"meeting_location"`
+	Coords          string `db:"coords"`
+	// Parsed from Coords when the group is saved. Null if Coords is
+	// empty.
+	Lat sql.NullFloat64 `db:"lat"`
+	Lng sql.NullFloat64 `db:"lng"`
+	// Set by ArchiveGroup; saving a group doesn't change it.
+	Archived bool `db:"archived"`
+	// The most members with the lead or co-lead role, or 0 for no
+	// limit.
+	MaxLeads int `db:"max_leads"`
+	// Limits on how much a sync may change the group's mailing
+	// list. Set by admins with UpdateGroupGuardrails; saving a group
+	// doesn't change them.
+	MailingListGuardrails
+}
+
+type GroupQueryOptions struct {
+	GroupID int
+	// Limits the groups to these types. Empty means all types.
+	Types []string
+	// Archived groups are left out unless this is set or they're
+	// fetched by ID.
+	IncludeArchived bool
+	// Limits the groups to those led by the activist with this
+	// email.
+	LeadEmail string
+}
+
+type GroupMember struct {
+	ActivistName  string `db:"activist_name"`
+	ActivistID    int    `db:"activist_id"`
+	ActivistEmail string `db:"activist_email"`
+	Role          string `db:"role"`
+}
+
+// IsMember reports whether the member's role makes them a member of
+// the group, as opposed to someone on its mailing list or observing.
+func (m GroupMember) IsMember() bool {
+	return m.Role == GroupRoleLead || m.Role == GroupRoleCoLead || m.Role == GroupRoleMember
+}
+
+// IsLead reports whether the member leads the group. Leads can edit
+// the group's membership.
+func (m GroupMember) IsLead() bool {
+	return m.Role == GroupRoleLead || m.Role == GroupRoleCoLead
+}
+
+// OnMailingList reports whether the member should be on the group's
+// mailing list.
+func (m GroupMember) OnMailingList() bool {
+	return m.Role != GroupRoleObserver
+}
+
+type GroupJSON struct {
+	ID              int               `json:"id"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Email           string            `json:"email"`
+	Members         []GroupMemberJSON `json:"members"`
+	Visible         bool              `json:"visible"`
+	Description     string            `json:"description"`
+	MeetingTime     string            `json:"meeting_time"`
+	MeetingLocation string            `json:"meeting_location"`
+	Coords          string            `json:"coords"`
+	Archived        bool              `json:"archived"`
+	MaxLeads        int               `json:"max_leads"`
+}
+
+type GroupMemberJSON struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Defaults to member.
+	Role string `json:"role"`
+}
+
+/** Functions and Methods */
+
+// CreateGroup creates a group. Membership changes are recorded as
+// made by userEmail.
+func CreateGroup(db *sqlx.DB, group Group, userEmail string) (int, error) {
+	if group.ID != 0 {
+		return 0, errors.New("Cannot create a group that already exists")
+	}
+	return createOrUpdateGroup(db, group, userEmail)
+}
+
+// UpdateGroup updates a group. Membership changes are recorded as
+// made by userEmail.
+func UpdateGroup(db *sqlx.DB, group Group, userEmail string) (int, error) {
+	if group.ID == 0 {
+		return 0, errors.New("Unable to update group if no group id is provided")
+	}
+	return createOrUpdateGroup(db, group, userEmail)
+}
+
+func createOrUpdateGroup(db *sqlx.DB, group Group, userEmail string) (int, error) {
+	// Check that required parameters are present
+	if group.Name == "" {
+		return 0, errors.New("Group name must not be zero-value")
+	}
+	if !isGroupType(group.Type) {
+		return 0, errors.Errorf("Group type must be one of %s", strings.Join(GroupTypes, ", "))
+	}
+	lat, lng, err := parseCoords(group.Coords)
+	if err != nil {
+		// Groups saved before coordinates were validated may have
+		// ones that can't be parsed. Leave those as they are, with no
+		// location, rather than blocking other changes to the group.
+		unchanged, checkErr := groupCoordsUnchanged(db, group)
+		if checkErr != nil {
+			return 0, checkErr
+		}
+		if !unchanged {
+			return 0, err
+		}
+	}
+	group.Lat, group.Lng = lat, lng
+	if err := validateGroupMembers(group); err != nil {
+		return 0, err
+	}
+
+	var query string
+	if group.ID == 0 {
+		// Create group
+		query = `
+    INSERT INTO activist_groups (name, type, group_email, visible, description, meeting_time, meeting_location, coords, lat, lng, max_leads)
+    VALUES (:name, :type, :group_email, :visible, :description, :meeting_time, :meeting_location, :coords, :lat, :lng, :max_leads)
+    `
+	} else {
+		// Update existing group
+		query = `
+UPDATE activist_groups
+SET
+  name = :name,
+  type = :type,
+  group_email = :group_email,
+  visible = :visible,
+  description = :description,
+  meeting_time = :meeting_time,
+  meeting_location = :meeting_location,
+  coords = :coords,
+  lat = :lat,
+  lng = :lng,
+  max_leads = :max_leads
+WHERE
+id = :id
+`
+	}
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to Create Transaction")
+	}
+	res, err := tx.NamedExec(query, group)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "Failed to insert new group")
+	}
+
+	if group.ID == 0 {
+		id, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrap(err, "Failed to get last inserted group ID")
+		}
+		group.ID = int(id)
+	}
+
+	if err := insertGroupMembers(tx, group, userEmail); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "Failed to insert members for group %s", group.Name)
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "Failed to commit group %s", group.Name)
+	}
+	return group.ID, nil
+}
+
+func insertGroupMembers(tx *sqlx.Tx, group Group, userEmail string) error {
+	if group.ID == 0 {
+		return errors.New("Invalid group ID. ID's must be greater than 0")
+	}
+	// Only members are recorded in the membership history.
+	var before []int
+	err := tx.Select(&before, `
+SELECT activist_id
+FROM activist_group_members
+WHERE group_id = ? AND role IN (`+groupMemberRolesQuery+`)`, group.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get members for group: %s", group.Name)
+	}
+	// First drop all members of the group.
+	_, err = tx.Exec(`DELETE FROM activist_group_members WHERE group_id = ?`, group.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to drop members for group: %s", group.Name)
+	}
+
+	var after []int
+	for _, m := range group.Members {
+		if m.ActivistID < 1 {
+			return errors.New("Invalid Activist ID; cannot add as a group member")
+		}
+		_, err = tx.Exec(`INSERT INTO activist_group_members (group_id, activist_id, role)
+    VALUES (?, ?, ?)`, group.ID, m.ActivistID, m.Role)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to insert %s into group %s", m.ActivistName, group.Name)
+		}
+		if m.IsMember() {
+			after = append(after, m.ActivistID)
+		}
+	}
+	return recordGroupMembershipChanges(tx, group.ID, before, after, userEmail, time.Now())
+}
+
+// groupCoordsUnchanged returns whether an existing group's coordinates
+// are the ones already saved.
+func groupCoordsUnchanged(db *sqlx.DB, group Group) (bool, error) {
+	if group.ID == 0 {
+		return false, nil
+	}
+	var coords string
+	err := db.Get(&coords, `SELECT coords FROM activist_groups WHERE id = ?`, group.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to select coordinates of group %d", group.ID)
+	}
+	return coords == group.Coords, nil
+}
+
+// parseCoords parses coordinates formatted as "lat, lng". Empty
+// coordinates are null.
+func parseCoords(coords string) (lat, lng sql.NullFloat64, err error) {
+	if strings.TrimSpace(coords) == "" {
+		return lat, lng, nil
+	}
+	parts := strings.Split(coords, ",")
+	if len(parts) != 2 {
+		return lat, lng, errors.Errorf("Coordinates must be formatted as latitude, longitude: %s", coords)
+	}
+	lat.Float64, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat.Float64 < -90 || lat.Float64 > 90 {
+		return lat, lng, errors.Errorf("Not a valid latitude: %s", parts[0])
+	}
+	lng.Float64, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lng.Float64 < -180 || lng.Float64 > 180 {
+		return lat, lng, errors.Errorf("Not a valid longitude: %s", parts[1])
+	}
+	lat.Valid, lng.Valid = true, true
+	return lat, lng, nil
+}
+
+func CleanGroupData(db *sqlx.DB, body io.Reader) (Group, error) {
+	var groupJSON GroupJSON
+	err := json.NewDecoder(body).Decode(&groupJSON)
+	if err != nil {
+		return Group{}, err
+	}
+
+	if len(strings.TrimSpace(groupJSON.Name)) == 0 {
+		return Group{}, errors.Errorf("Group name must not be blank")
+	}
+
+	if !strings.Contains(groupJSON.Email, "@") {
+		return Group{}, errors.Errorf("Group email must contain @: %s", groupJSON.Email)
+	}
+
+	if groupJSON.Type == "" {
+		return Group{}, errors.New("Group type can't be empty")
+	}
+	if !isGroupType(groupJSON.Type) {
+		return Group{}, errors.Errorf("Group type doesn't exist: %s", groupJSON.Type)
+	}
+
+	members, err := cleanGroupMembers(db, groupJSON.Members)
+	if err != nil {
+		return Group{}, err
+	}
+
+	return Group{
+		ID:              groupJSON.ID,
+		Name:            strings.TrimSpace(groupJSON.Name),
+		Type:            groupJSON.Type,
+		GroupEmail:      strings.TrimSpace(groupJSON.Email),
+		Members:         members,
+		Visible:         groupJSON.Visible,
+		Description:     groupJSON.Description,
+		MeetingTime:     groupJSON.MeetingTime,
+		MeetingLocation: groupJSON.MeetingLocation,
+		Coords:          groupJSON.Coords,
+		MaxLeads:        groupJSON.MaxLeads,
+	}, nil
+}
+
+func cleanGroupMembers(db *sqlx.DB, membersJSON []GroupMemberJSON) ([]GroupMember, error) {
+	members := make([]GroupMember, 0, len(membersJSON))
+	for _, m := range membersJSON {
+		trimName := strings.TrimSpace(m.Name)
+		if trimName == "" {
+			return nil, errors.New("Member name cannot be empty")
+		}
+		activist, err := GetActivist(db, trimName)
+		if err != nil {
+			return nil, err
+		}
+		role := m.Role
+		if role == "" {
+			role = GroupRoleMember
+		}
+		members = append(members, GroupMember{
+			ActivistName:  activist.Name,
+			ActivistID:    activist.ID,
+			ActivistEmail: activist.Email,
+			Role:          role,
+		})
+	}
+	return members, nil
+}
+
+// GroupMembersJSON is a request to replace the members of a group.
+type GroupMembersJSON struct {
+	GroupID int               `json:"group_id"`
+	Members []GroupMemberJSON `json:"members"`
+}
+
+// DecodeGroupMembersData parses a request to replace the members of a
+// group, without looking up the members.
+func DecodeGroupMembersData(body io.Reader) (GroupMembersJSON, error) {
+	var requestData GroupMembersJSON
+	if err := json.NewDecoder(body).Decode(&requestData); err != nil {
+		return GroupMembersJSON{}, err
+	}
+	if requestData.GroupID == 0 {
+		return GroupMembersJSON{}, errors.New("Group ID can't be 0")
+	}
+	return requestData, nil
+}
+
+// CleanGroupMembersData looks up the members of a request to replace
+// the members of a group.
+func CleanGroupMembersData(db *sqlx.DB, requestData GroupMembersJSON) ([]GroupMember, error) {
+	return cleanGroupMembers(db, requestData.Members)
+}
+
+// ValidateGroupMembersByLead checks that the new members of a group
+// keep its leads and co-leads exactly as they are. Leads can only
+// change the other members.
+func ValidateGroupMembersByLead(existing, members []GroupMember) error {
+	leads := map[int]string{}
+	for _, m := range existing {
+		if m.IsLead() {
+			leads[m.ActivistID] = m.Role
+		}
+	}
+	kept := map[int]bool{}
+	for _, m := range members {
+		role, isLead := leads[m.ActivistID]
+		if isLead && m.Role != role {
+			return errors.Errorf("Only organizers can change the role of %s", m.ActivistName)
+		}
+		if !isLead && m.IsLead() {
+			return errors.Errorf("Only organizers can make %s a lead or co-lead", m.ActivistName)
+		}
+		if isLead {
+			kept[m.ActivistID] = true
+		}
+	}
+	if len(kept) != len(leads) {
+		return errors.New("Only organizers can remove leads and co-leads")
+	}
+	return nil
+}
+
+// validateGroupMembers checks the members' roles against the group's
+// rules.
+func validateGroupMembers(group Group) error {
+	if group.MaxLeads < 0 {
+		return errors.New("The maximum number of leads can't be negative")
+	}
+	leads := 0
+	seen := map[int]bool{}
+	for _, m := range group.Members {
+		if !isGroupMemberRole(m.Role) {
+			return errors.Errorf("Member role must be one of %s", strings.Join(GroupMemberRoles, ", "))
+		}
+		if seen[m.ActivistID] {
+			return errors.Errorf("Cannot have duplicate members: %s", m.ActivistName)
+		}
+		seen[m.ActivistID] = true
+		// Co-leads can edit the group like leads, so they count
+		// towards the limit too.
+		if m.IsLead() {
+			leads++
+		}
+	}
+	if group.MaxLeads != 0 && leads > group.MaxLeads {
+		return errors.Errorf("%s can't have more than %d leads and co-leads", group.Name, group.MaxLeads)
+	}
+	return nil
+}
+
+// UpdateGroupMembers replaces the members of a group, leaving the
+// rest of the group as is.
+func UpdateGroupMembers(db *sqlx.DB, groupID int, members []GroupMember, userEmail string) error {
+	group, err := GetGroup(db, GroupQueryOptions{GroupID: groupID})
+	if err != nil {
+		return err
+	}
+	if group.Archived {
+		return errors.Errorf("%s is archived and can't be edited", group.Name)
+	}
+	group.Members = members
+	_, err = UpdateGroup(db, group, userEmail)
+	return err
+}
+
+// IsGroupLead reports whether the activist with the given email leads
+// the group. Nobody leads an archived group.
+func IsGroupLead(db *sqlx.DB, groupID int, email string) (bool, error) {
+	if strings.TrimSpace(email) == "" {
+		return false, nil
+	}
+	var count int
+	err := db.Get(&count, `
+SELECT count(*)
+FROM activist_group_members gm
+JOIN activists a ON a.id = gm.activist_id
+JOIN activist_groups g ON g.id = gm.group_id
+WHERE gm.group_id = ?
+  AND g.archived = 0
+  AND gm.role IN (?, ?)
+  AND a.hidden = 0
+  AND lower(a.email) = lower(?)`, groupID, GroupRoleLead, GroupRoleCoLead, email)
+	if err != nil {
+		return false, errors.Wrapf(err, "Failed to check leads of group %d", groupID)
+	}
+	return count != 0, nil
+}
+
+func DeleteGroup(db *sqlx.DB, groupID int) error {
+	if groupID == 0 {
+		return errors.New("Group ID can't be 0")
+	}
+
+	// Wrap everything in a transaction because we only want to
+	// delete the group if there are no users associated with it.
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create transaction")
+	}
+
+	txFn := func() error {
+		var activistIDs []int
+		err = tx.Select(&activistIDs, `
+SELECT activist_id
+FROM activist_group_members
+WHERE group_id = ?`, groupID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get activists for group: %d", groupID)
+		}
+
+		if len(activistIDs) > 0 {
+			return errors.New("Cannot delete group because it has members associated with it. Archive it instead to keep its members")
+		}
+		_, err = tx.Exec(`
+DELETE FROM activist_groups
+WHERE id = ?`, groupID)
+		if err != nil {
+			return errors.Wrap(err, "Could not delete group")
+		}
+		_, err = tx.Exec(`
+DELETE FROM activist_group_memberships
+WHERE group_id = ?`, groupID)
+		if err != nil {
+			return errors.Wrap(err, "Could not delete group memberships")
+		}
+		_, err = tx.Exec(`
+UPDATE events
+SET group_id = 0
+WHERE group_id = ?`, groupID)
+		if err != nil {
+			return errors.Wrap(err, "Could not unlink the group's events")
+		}
+		return nil
+	}
+
+	if err = txFn(); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(err, "Error during commit")
+	}
+	return nil
+}
+
+// ArchiveGroup archives a group. Its members and membership history
+// are kept, but it's no longer listed or synced to mailing lists.
+func ArchiveGroup(db *sqlx.DB, groupID int, userEmail string) error {
+	res, err := db.Exec(`
+UPDATE activist_groups
+SET archived = 1, archived_at = NOW(), archived_by_email = ?
+WHERE id = ? AND archived = 0`, userEmail, groupID)
+	if err != nil {
+		return errors.Wrapf(err, "Could not archive group %d", groupID)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Errorf("No active group with ID %d found", groupID)
+	}
+	return nil
+}
+
+// UnarchiveGroup reactivates an archived group.
+func UnarchiveGroup(db *sqlx.DB, groupID int) error {
+	res, err := db.Exec(`
+UPDATE activist_groups
+SET archived = 0, archived_at = NULL, archived_by_email = ''
+WHERE id = ? AND archived = 1`, groupID)
+	if err != nil {
+		return errors.Wrapf(err, "Could not reactivate group %d", groupID)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Errorf("No archived group with ID %d found", groupID)
+	}
+	return nil
+}
+
+func GetGroupJSON(db *sqlx.DB, groupID int) (GroupJSON, error) {
+	groups, err := GetGroupsJSON(db, GroupQueryOptions{
+		GroupID: groupID,
+	})
+	if err != nil {
+		return GroupJSON{}, err
+	}
+	if len(groups) == 0 {
+		return GroupJSON{}, errors.Errorf("Could not find group with id: %d", groupID)
+	} else if len(groups) > 1 {
+		return GroupJSON{}, errors.Errorf("Found too many groups with id: %d", groupID)
+	}
+	return groups[0], nil
+}
+
+func GetGroupsJSON(db *sqlx.DB, options GroupQueryOptions) ([]GroupJSON, error) {
+	groups, err := getGroups(db, options)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsJSON := make([]GroupJSON, 0, len(groups))
+	for _, g := range groups {
+		members := make([]GroupMemberJSON, 0, len(g.Members))
+		for _, member := range g.Members {
+			members = append(members, GroupMemberJSON{
+				Name:  member.ActivistName,
+				Email: member.ActivistEmail,
+				Role:  member.Role,
+			})
+		}
+		groupsJSON = append(groupsJSON, GroupJSON{
+			ID:              g.ID,
+			Name:            g.Name,
+			Type:            g.Type,
+			Email:           g.GroupEmail,
+			Members:         members,
+			Visible:         g.Visible,
+			Description:     g.Description,
+			MeetingTime:     g.MeetingTime,
+			MeetingLocation: g.MeetingLocation,
+			Coords:          g.Coords,
+			Archived:        g.Archived,
+			MaxLeads:        g.MaxLeads,
+		})
+	}
+
+	return groupsJSON, nil
+}
+
+func GetGroups(db *sqlx.DB, options GroupQueryOptions) ([]Group, error) {
+	if options.GroupID != 0 {
+		return nil, errors.New("GetGroups: Cannot include an ID in options")
+	}
+
+	groups, err := getGroups(db, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GetGroups: Unable to retrieve groups")
+	}
+	return groups, nil
+}
+
+func GetGroup(db *sqlx.DB, options GroupQueryOptions) (Group, error) {
+	if options.GroupID == 0 {
+		return Group{}, errors.New("GetGroup: ID required to fetch specific group")
+	}
+
+	groups, err := getGroups(db, options)
+	if err != nil {
+		return Group{}, errors.Wrapf(err, "Error fetching group with ID %d", options.GroupID)
+	}
+	if len(groups) == 0 {
+		return Group{}, errors.Errorf("No group with ID %d found", options.GroupID)
+	}
+	if len(groups) > 1 {
+		return Group{}, errors.Errorf("Duplicate groups with ID %d", options.GroupID)
+	}
+	return groups[0], nil
+}
+
+func getGroups(db *sqlx.DB, options GroupQueryOptions) ([]Group, error) {
+	query := `
+SELECT g.id, g.name, g.type, lower(g.group_email) as group_email, g.visible, g.description, g.meeting_time, g.meeting_location, g.coords, g.lat, g.lng, g.archived, g.max_leads,
+  g.max_removals, g.max_remove_percent, g.allow_empty
+FROM activist_groups g
+`
+
+	var queryArgs []interface{}
+	var whereClause []string
+
+	if options.GroupID != 0 {
+		whereClause = append(whereClause, "g.id = ?")
+		queryArgs = append(queryArgs, options.GroupID)
+	}
+
+	if len(options.Types) != 0 {
+		whereClause = append(whereClause, "g.type IN (?)")
+		queryArgs = append(queryArgs, options.Types)
+	}
+
+	if !options.IncludeArchived && options.GroupID == 0 {
+		whereClause = append(whereClause, "g.archived = 0")
+	}
+
+	if options.LeadEmail != "" {
+		whereClause = append(whereClause, `g.id IN (
+  SELECT gm.group_id
+  FROM activist_group_members gm
+  JOIN activists a ON a.id = gm.activist_id
+  WHERE gm.role IN (?, ?) AND a.hidden = 0 AND lower(a.email) = lower(?))`)
+		queryArgs = append(queryArgs, GroupRoleLead, GroupRoleCoLead, options.LeadEmail)
+	}
+
+	if len(whereClause) > 0 {
+		query += ` WHERE ` + strings.Join(whereClause, " AND ")
+	}
+
+	query += ` ORDER BY g.name`
+
+	query, queryArgs, err := sqlx.In(query, queryArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not create sqlx.In query for fetching groups")
+	}
+
+	var groups []Group
+	if err := db.Select(&groups, db.Rebind(query), queryArgs...); err != nil {
+		return []Group{}, errors.Wrapf(err, "getGroups: Failed retrieving groups from activist_groups table")
+	}
+
+	// TODO(mdempsky): Use a JOIN instead of a second round-trip.
+	if err := fetchGroupMembers(db, groups); err != nil {
+		return []Group{}, errors.Wrapf(err, "Failed to fetch group members for query: %#v", options)
+	}
+
+	return groups, nil
+
+}
+
+func fetchGroupMembers(db *sqlx.DB, groups []Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	groupIDToIndex := map[int]int{}
+	var groupIDs []int
+
+	for i, g := range groups {
+		groupIDs = append(groupIDs, g.ID)
+		groupIDToIndex[g.ID] = i
+	}
+	membersQuery, membersArgs, err := sqlx.In(`
+SELECT
+  gm.group_id,
+  a.name as activist_name,
+  a.email as activist_email,
+  a.id as activist_id,
+  gm.role
+FROM activists a
+JOIN activist_group_members gm
+  on a.id = gm.activist_id
+WHERE
+  gm.group_id IN (?)`, groupIDs)
+	if err != nil {
+		return errors.Wrapf(err, "Could not create sqlx.In query for fetching group members")
+	}
+
+	membersQuery = db.Rebind(membersQuery)
+	var members []struct {
+		GroupID int `db:"group_id"`
+		GroupMember
+	}
+	if err := db.Select(&members, membersQuery, membersArgs...); err != nil {
+		return errors.Wrapf(err, "Unable to fetch group members")
+	}
+
+	for _, m := range members {
+		idx := groupIDToIndex[m.GroupID]
+		groups[idx].Members = append(groups[idx].Members, m.GroupMember)
+	}
+
+	return nil
+
+}
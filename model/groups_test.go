@@ -0,0 +1,427 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateGroup_missingRequiredParameters_returnsError(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "foo",
+	}
+	_, err := CreateGroup(db, workingGroup, "")
+	require.Error(t, err)
+
+	workingGroup.Type = "not_a_type"
+	_, err = CreateGroup(db, workingGroup, "")
+	require.Error(t, err)
+
+	workingGroup.Type = GroupTypeWorkingGroup
+	workingGroup.Name = ""
+	_, err = CreateGroup(db, workingGroup, "")
+	require.Error(t, err)
+
+	workingGroup.ID = 2
+	_, err = CreateGroup(db, workingGroup, "")
+	require.Error(t, err)
+}
+
+func TestCreateGroup_allRequiredParametersPresent_returnsNoError(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "Tech (Best by a longshot)",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	_, err := CreateGroup(db, workingGroup, "")
+	require.NoError(t, err)
+}
+
+func TestCreateGroup_insertAndFetchGroupNoMembers_returnsNoError(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "Tech FTW",
+		Type: GroupTypeCommittee,
+	}
+
+	id, err := CreateGroup(db, workingGroup, "")
+	require.NoError(t, err)
+	workingGroup.ID = id
+	// New groups' lists start with the default guardrails.
+	workingGroup.MailingListGuardrails = DefaultMailingListGuardrails
+
+	fetchedGroup, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	require.Equal(t, fetchedGroup, workingGroup)
+
+	_, err = GetGroups(db, GroupQueryOptions{GroupID: id})
+	require.Error(t, err)
+
+	fetchedGroups, err := GetGroups(db, GroupQueryOptions{})
+	require.NoError(t, err)
+	require.Equal(t, fetchedGroups[0], workingGroup)
+}
+
+func TestCreateGroup_insertAndFetchGroupWithMembersByID(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "Emacs or Vim?",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	activistsToInsert := []string{"A", "B", "C", "D"}
+	workingGroup.Members = insertActivists(t, db, activistsToInsert)
+	id, err := CreateGroup(db, workingGroup, "")
+	require.NoError(t, err)
+	workingGroup.ID = id
+
+	fetchedGroup, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	validateReturnedGroup(t, workingGroup, fetchedGroup)
+
+}
+
+func TestCreateGroup_insertAndFetchGroupWithMembersByNameAndID(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "The Citadel",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	activistsToInsert := []string{"Rick", "And", "Morty"}
+	workingGroup.Members = insertActivists(t, db, activistsToInsert)
+	id, err := CreateGroup(db, workingGroup, "")
+	require.NoError(t, err)
+	workingGroup.ID = id
+
+	fetchedGroup2, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	validateReturnedGroup(t, workingGroup, fetchedGroup2)
+}
+
+func TestUpdateGroup_updateLeadAndGroupEmail(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup := Group{
+		Name: "Sanguine Salesman",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	id, err := CreateGroup(db, workingGroup, "")
+	require.NoError(t, err)
+	workingGroup.ID = id
+
+	fetchedGroup, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	validateReturnedGroup(t, workingGroup, fetchedGroup)
+
+	members := insertActivists(t, db, []string{"Whimsical Winterbottom"})
+	members[0].Role = GroupRoleLead
+	updatedGroupExpected := Group{
+		ID:         id,
+		Name:       "Sanguine Salesman",
+		Type:       GroupTypeWorkingGroup,
+		GroupEmail: "foo@bar.com",
+		Members:    members,
+	}
+
+	_, err = UpdateGroup(db, updatedGroupExpected, "")
+	require.NoError(t, err)
+	updatedGroupActual, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	validateReturnedGroup(t, updatedGroupExpected, updatedGroupActual)
+}
+
+func TestUpdateGroup_updateMultipleGroups(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	workingGroup1 := Group{
+		Name: "WG 1",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	workingGroup2 := Group{
+		Name: "WG 2",
+		Type: GroupTypeWorkingGroup,
+	}
+
+	id1, err := CreateGroup(db, workingGroup1, "")
+	require.NoError(t, err)
+	id2, err := CreateGroup(db, workingGroup2, "")
+	require.NoError(t, err)
+
+	members1 := insertActivists(t, db, []string{"Anthony Abe", "Smithy Smith", "Rick Rickel"})
+	members2 := insertActivists(t, db, []string{"The", "Seven", "Deadly", "Sins"})
+
+	UpdatedExpected1 := Group{
+		ID:         id1,
+		Name:       "WG 1",
+		Type:       GroupTypeWorkingGroup,
+		GroupEmail: "hello@hello.org",
+		Members:    members1,
+	}
+
+	UpdatedExpected2 := Group{
+		ID:      id2,
+		Name:    "WG 2",
+		Type:    GroupTypeWorkingGroup,
+		Members: members2,
+	}
+
+	_, err = UpdateGroup(db, UpdatedExpected1, "")
+	require.NoError(t, err)
+	_, err = UpdateGroup(db, UpdatedExpected2, "")
+	require.NoError(t, err)
+
+	updatedGroups, err := GetGroups(db, GroupQueryOptions{})
+	require.NoError(t, err)
+
+	for _, group := range updatedGroups {
+		if group.ID == UpdatedExpected1.ID {
+			validateReturnedGroup(t, UpdatedExpected1, group)
+		} else {
+			validateReturnedGroup(t, UpdatedExpected2, group)
+		}
+	}
+
+}
+
+func TestGetGroups_filterByType(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	// Groups of different types can share a name.
+	_, err := CreateGroup(db, Group{Name: "Outreach", Type: GroupTypeWorkingGroup}, "")
+	require.NoError(t, err)
+	circleID, err := CreateGroup(db, Group{Name: "Outreach", Type: GroupTypeCircle}, "")
+	require.NoError(t, err)
+	_, err = CreateGroup(db, Group{Name: "Finance", Type: GroupTypeCommittee}, "")
+	require.NoError(t, err)
+
+	circles, err := GetGroups(db, GroupQueryOptions{Types: []string{GroupTypeCircle}})
+	require.NoError(t, err)
+	require.Len(t, circles, 1)
+	require.Equal(t, circleID, circles[0].ID)
+
+	groups, err := GetGroups(db, GroupQueryOptions{Types: []string{GroupTypeWorkingGroup, GroupTypeCommittee}})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	groups, err = GetGroups(db, GroupQueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+}
+
+func validateReturnedGroup(t *testing.T, inserted Group, returned Group) {
+	require.Equal(t, inserted.ID, returned.ID)
+	require.Equal(t, inserted.Name, returned.Name)
+	require.Equal(t, inserted.Type, returned.Type)
+	require.Equal(t, inserted.GroupEmail, returned.GroupEmail)
+	require.Equal(t, len(inserted.Members), len(returned.Members))
+
+	memberMap := make(map[int]GroupMember)
+	for _, member := range inserted.Members {
+		memberMap[member.ActivistID] = member
+	}
+
+	for _, member := range returned.Members {
+		insertedMember, ok := memberMap[member.ActivistID]
+		require.True(t, ok)
+		require.Equal(t, insertedMember.ActivistName, member.ActivistName)
+		require.Equal(t, insertedMember.Role, member.Role)
+	}
+}
+
+func insertActivists(t *testing.T, db *sqlx.DB, names []string) []GroupMember {
+	members := make([]GroupMember, len(names))
+	for idx, a := range names {
+		activist, err := GetOrCreateActivist(db, a)
+		require.NoError(t, err)
+		members[idx] = GroupMember{
+			ActivistName: activist.Name,
+			ActivistID:   activist.ID,
+			Role:         GroupRoleMember,
+		}
+	}
+	return members
+}
+
+func TestArchiveGroup(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	group := Group{Name: "Winding Down", Type: GroupTypeCircle}
+	group.Members = insertActivists(t, db, []string{"Alice"})
+	id, err := CreateGroup(db, group, "")
+	require.NoError(t, err)
+
+	require.NoError(t, ArchiveGroup(db, id, "organizer@example.org"))
+	require.Error(t, ArchiveGroup(db, id, "organizer@example.org"))
+
+	groups, err := GetGroups(db, GroupQueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 0)
+
+	groups, err = GetGroups(db, GroupQueryOptions{IncludeArchived: true})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.True(t, groups[0].Archived)
+
+	// Archived groups keep their members.
+	archived, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	require.Len(t, archived.Members, 1)
+
+	require.NoError(t, UnarchiveGroup(db, id))
+	require.Error(t, UnarchiveGroup(db, id))
+	groups, err = GetGroups(db, GroupQueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	require.False(t, groups[0].Archived)
+}
+
+func TestUpdateGroup_legacyCoords(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	group := Group{Name: "Old Circle", Type: GroupTypeCircle}
+	id, err := CreateGroup(db, group, "")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activist_groups SET coords = 'near the lake' WHERE id = ?`, id)
+	require.NoError(t, err)
+
+	// Saving with the malformed coordinates it already had works.
+	group.ID = id
+	group.Coords = "near the lake"
+	group.Description = "Meets by the lake"
+	_, err = UpdateGroup(db, group, "")
+	require.NoError(t, err)
+
+	// Changing them to other malformed coordinates doesn't.
+	group.Coords = "by the bay"
+	_, err = UpdateGroup(db, group, "")
+	require.Error(t, err)
+}
+
+func TestValidateGroupMembers(t *testing.T) {
+	group := Group{
+		Name:     "Outreach",
+		MaxLeads: 2,
+		Members: []GroupMember{
+			{ActivistID: 1, ActivistName: "Alice", Role: GroupRoleLead},
+			{ActivistID: 2, ActivistName: "Bob", Role: GroupRoleCoLead},
+			{ActivistID: 3, ActivistName: "Carol", Role: GroupRoleObserver},
+		},
+	}
+	require.NoError(t, validateGroupMembers(group))
+
+	// Co-leads count towards the limit.
+	group.Members[2].Role = GroupRoleCoLead
+	require.Error(t, validateGroupMembers(group))
+	group.Members[2].Role = GroupRoleObserver
+	group.MaxLeads = 1
+	require.Error(t, validateGroupMembers(group))
+	group.MaxLeads = 0
+	require.NoError(t, validateGroupMembers(group))
+
+	group.Members[2].Role = "not_a_role"
+	require.Error(t, validateGroupMembers(group))
+	group.Members[2].Role = GroupRoleMember
+
+	group.Members[2].ActivistID = 1
+	require.Error(t, validateGroupMembers(group))
+}
+
+func TestIsGroupLead(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	group := Group{Name: "Outreach", Type: GroupTypeCircle}
+	group.Members = insertActivists(t, db, []string{"Alice", "Bob"})
+	group.Members[0].Role = GroupRoleCoLead
+	_, err := db.Exec(`UPDATE activists SET email = 'alice@example.org' WHERE id = ?`, group.Members[0].ActivistID)
+	require.NoError(t, err)
+	id, err := CreateGroup(db, group, "")
+	require.NoError(t, err)
+
+	lead, err := IsGroupLead(db, id, "Alice@example.org")
+	require.NoError(t, err)
+	require.True(t, lead)
+	lead, err = IsGroupLead(db, id, "bob@example.org")
+	require.NoError(t, err)
+	require.False(t, lead)
+
+	groups, err := GetGroups(db, GroupQueryOptions{LeadEmail: "alice@example.org"})
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	// Nobody leads an archived group.
+	require.NoError(t, ArchiveGroup(db, id, ""))
+	lead, err = IsGroupLead(db, id, "alice@example.org")
+	require.NoError(t, err)
+	require.False(t, lead)
+}
+
+func TestValidateGroupMembersByLead(t *testing.T) {
+	existing := []GroupMember{
+		{ActivistID: 1, ActivistName: "Alice", Role: GroupRoleLead},
+		{ActivistID: 2, ActivistName: "Bob", Role: GroupRoleCoLead},
+		{ActivistID: 3, ActivistName: "Carol", Role: GroupRoleMember},
+	}
+	members := []GroupMember{
+		{ActivistID: 1, ActivistName: "Alice", Role: GroupRoleLead},
+		{ActivistID: 2, ActivistName: "Bob", Role: GroupRoleCoLead},
+		{ActivistID: 3, ActivistName: "Carol", Role: GroupRoleObserver},
+		{ActivistID: 4, ActivistName: "Dan", Role: GroupRoleMailingListOnly},
+	}
+	require.NoError(t, ValidateGroupMembersByLead(existing, members))
+
+	members[3].Role = GroupRoleCoLead
+	require.Error(t, ValidateGroupMembersByLead(existing, members))
+	members[3].Role = GroupRoleMember
+
+	members[1].Role = GroupRoleLead
+	require.Error(t, ValidateGroupMembersByLead(existing, members))
+	members[1].Role = GroupRoleCoLead
+
+	require.Error(t, ValidateGroupMembersByLead(existing, members[1:]))
+}
+
+func TestUpdateGroupGuardrails(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	group := Group{Name: "Tech", Type: GroupTypeWorkingGroup, GroupEmail: "tech@example.org"}
+	id, err := CreateGroup(db, group, "")
+	require.NoError(t, err)
+
+	guardrails := MailingListGuardrails{MaxRemovals: 2, MaxRemovePercent: 0, AllowEmpty: true}
+	require.NoError(t, UpdateGroupGuardrails(db, id, guardrails))
+	// Saving the group keeps its guardrails.
+	group.ID = id
+	group.Name = "Tech WG"
+	_, err = UpdateGroup(db, group, "")
+	require.NoError(t, err)
+	fetched, err := GetGroup(db, GroupQueryOptions{GroupID: id})
+	require.NoError(t, err)
+	require.Equal(t, guardrails, fetched.MailingListGuardrails)
+
+	// Unchanged guardrails aren't an error.
+	require.NoError(t, UpdateGroupGuardrails(db, id, guardrails))
+	require.Error(t, UpdateGroupGuardrails(db, id, MailingListGuardrails{MaxRemovePercent: 101}))
+	require.Error(t, UpdateGroupGuardrails(db, id+1, guardrails))
+}
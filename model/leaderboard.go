@@ -0,0 +1,203 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Leaderboard windows and their length in days.
+var leaderboardWindows = map[string]int{
+	"month":   30,
+	"quarter": 91,
+	"year":    365,
+}
+
+const defaultLeaderboardWindow = "month"
+
+// Points for attending an event of a type without points, and for
+// attending without a role. Used in SQL.
+const (
+	leaderboardTypePointsSQL = "IFNULL(et.points, 1)"
+	leaderboardRolePointsSQL = "IFNULL(ar.points, 0)"
+)
+
+/** Type Definitions */
+
+type LeaderboardOptions struct {
+	// One of month, quarter or year.
+	Window string
+	// Points for attending an event halve every HalfLifeDays days
+	// before the end of the window. 0 means half the window.
+	HalfLifeDays int
+	// Maximum number of entries, or 0 for all of them.
+	Limit int
+}
+
+type LeaderboardEntry struct {
+	ActivistID int     `json:"activist_id"`
+	Name       string  `json:"name"`
+	Rank       int     `json:"rank"`
+	Points     float64 `json:"points"`
+	// How many places the activist moved up since the previous
+	// window (negative if they moved down). Null if they weren't
+	// on the leaderboard in the previous window.
+	RankChange *int `json:"rank_change"`
+	// Points by MPI category of the events.
+	Categories map[string]float64 `json:"categories"`
+}
+
+type leaderboardAttendance struct {
+	ActivistID int       `db:"activist_id"`
+	Name       string    `db:"name"`
+	Date       time.Time `db:"date"`
+	Category   string    `db:"mpi_category"`
+	TypePoints int       `db:"type_points"`
+	RolePoints int       `db:"role_points"`
+}
+
+/** Functions and Methods */
+
+func CleanLeaderboardOptions(window string, halfLifeDays, limit int) (LeaderboardOptions, error) {
+	if window == "" {
+		window = defaultLeaderboardWindow
+	}
+	if _, ok := leaderboardWindows[window]; !ok {
+		return LeaderboardOptions{}, errors.New("Not a valid leaderboard window: " + window)
+	}
+	if halfLifeDays < 0 {
+		return LeaderboardOptions{}, errors.New("Half-life cannot be negative")
+	}
+	if limit < 0 {
+		return LeaderboardOptions{}, errors.New("Limit cannot be negative")
+	}
+	return LeaderboardOptions{Window: window, HalfLifeDays: halfLifeDays, Limit: limit}, nil
+}
+
+// leaderboardHalfLife returns the half-life in days of points in a
+// window of days, given the requested half-life or 0 for the default.
+func leaderboardHalfLife(days, halfLifeDays int) float64 {
+	if halfLifeDays == 0 {
+		return float64(days) / 2
+	}
+	return float64(halfLifeDays)
+}
+
+// activistPointsQuery selects each activist's points on the default
+// leaderboard as of now, as activist_id and totalPoints. It's the SQL
+// version of scoreLeaderboard, so the activists list can sort by
+// points, and has to be kept in step with it.
+func activistPointsQuery() string {
+	days := leaderboardWindows[defaultLeaderboardWindow]
+	return fmt.Sprintf(`
+SELECT
+  ea.activist_id,
+  CAST(ROUND(SUM((%s + %s) * POW(0.5, DATEDIFF(NOW(), e.date) / %g))) AS SIGNED) AS totalPoints
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+LEFT JOIN event_types et ON et.name = e.event_type
+LEFT JOIN attendance_roles ar ON ar.name = ea.role
+WHERE e.date > (NOW() - INTERVAL %d DAY) AND e.date <= NOW()
+GROUP BY ea.activist_id`, leaderboardTypePointsSQL, leaderboardRolePointsSQL, leaderboardHalfLife(days, 0), days)
+}
+
+// scoreLeaderboard adds up the points of attendance between start
+// (exclusive) and end (inclusive), decayed relative to end, and ranks
+// the activists by them.
+func scoreLeaderboard(attendance []leaderboardAttendance, start, end time.Time, halfLife float64) []LeaderboardEntry {
+	entries := map[int]*LeaderboardEntry{}
+	for _, a := range attendance {
+		if !a.Date.After(start) || a.Date.After(end) {
+			continue
+		}
+		e, ok := entries[a.ActivistID]
+		if !ok {
+			e = &LeaderboardEntry{
+				ActivistID: a.ActivistID,
+				Name:       a.Name,
+				Categories: map[string]float64{},
+			}
+			entries[a.ActivistID] = e
+		}
+		age := end.Sub(a.Date).Hours() / 24
+		points := float64(a.TypePoints+a.RolePoints) * math.Pow(0.5, age/halfLife)
+		e.Points += points
+		e.Categories[a.Category] += points
+	}
+
+	ranked := []LeaderboardEntry{}
+	for _, e := range entries {
+		ranked = append(ranked, *e)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Points != ranked[j].Points {
+			return ranked[i].Points > ranked[j].Points
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	// Activists with the same points share a rank.
+	for i := range ranked {
+		if i > 0 && ranked[i].Points == ranked[i-1].Points {
+			ranked[i].Rank = ranked[i-1].Rank
+		} else {
+			ranked[i].Rank = i + 1
+		}
+	}
+	return ranked
+}
+
+// GetLeaderboard scores activists over the window ending now.
+// Attending an event is worth its event type's points plus the points
+// of the attendee's role, decayed by how long ago the event was.
+func GetLeaderboard(db *sqlx.DB, options LeaderboardOptions, now time.Time) ([]LeaderboardEntry, error) {
+	days := leaderboardWindows[options.Window]
+	if days == 0 {
+		return nil, errors.New("Not a valid leaderboard window: " + options.Window)
+	}
+	halfLife := leaderboardHalfLife(days, options.HalfLifeDays)
+	start := now.AddDate(0, 0, -days)
+	previousStart := start.AddDate(0, 0, -days)
+
+	var attendance []leaderboardAttendance
+	err := db.Select(&attendance, `
+SELECT
+  a.id AS activist_id,
+  a.name,
+  e.date,
+  IFNULL(et.mpi_category, 'none') AS mpi_category,
+  `+leaderboardTypePointsSQL+` AS type_points,
+  `+leaderboardRolePointsSQL+` AS role_points
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+JOIN activists a ON a.id = ea.activist_id
+LEFT JOIN event_types et ON et.name = e.event_type
+LEFT JOIN attendance_roles ar ON ar.name = ea.role
+WHERE a.hidden = 0
+  AND e.date > ? AND e.date <= ?`, previousStart.Format(EventDateLayout), now)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select leaderboard attendance")
+	}
+
+	entries := scoreLeaderboard(attendance, start, now, halfLife)
+	previousRanks := map[int]int{}
+	for _, e := range scoreLeaderboard(attendance, previousStart, start, halfLife) {
+		previousRanks[e.ActivistID] = e.Rank
+	}
+	for i := range entries {
+		if rank, ok := previousRanks[entries[i].ActivistID]; ok {
+			change := rank - entries[i].Rank
+			entries[i].RankChange = &change
+		}
+	}
+
+	if options.Limit != 0 && len(entries) > options.Limit {
+		entries = entries[:options.Limit]
+	}
+	return entries, nil
+}
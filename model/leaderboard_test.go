@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreLeaderboard(t *testing.T) {
+	end := time.Date(2020, 5, 31, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -30)
+	attendance := []leaderboardAttendance{
+		{ActivistID: 1, Name: "Hello", Date: end, Category: "direct_action", TypePoints: 1, RolePoints: 2},
+		{ActivistID: 2, Name: "Hi", Date: end.AddDate(0, 0, -15), Category: "community", TypePoints: 2},
+		{ActivistID: 3, Name: "Hey", Date: end, Category: "community", TypePoints: 1},
+		{ActivistID: 4, Name: "Yo", Date: end, Category: "none", TypePoints: 1},
+		// Outside the window.
+		{ActivistID: 3, Name: "Hey", Date: start, Category: "community", TypePoints: 5},
+	}
+
+	entries := scoreLeaderboard(attendance, start, end, 15)
+	require.Len(t, entries, 4)
+	require.Equal(t, "Hello", entries[0].Name)
+	require.Equal(t, 3.0, entries[0].Points)
+	require.Equal(t, map[string]float64{"direct_action": 3}, entries[0].Categories)
+	// Hi's points are halved by the decay and tie with Hey and Yo.
+	require.Equal(t, 1.0, entries[1].Points)
+	require.Equal(t, []int{1, 2, 2, 2}, []int{entries[0].Rank, entries[1].Rank, entries[2].Rank, entries[3].Rank})
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+
+	now := time.Date(2020, 5, 31, 0, 0, 0, 0, time.UTC)
+	// Last month Hi was ahead, this month Hello is.
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "last month",
+		EventDate:      now.AddDate(0, 0, -40),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2},
+		UpdatedRoles:   []AttendeeRole{{Activist: a2, Role: "Host"}},
+	})
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "this month",
+		EventDate:      now.AddDate(0, 0, -1),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2},
+		UpdatedRoles:   []AttendeeRole{{Activist: a1, Role: "Host"}},
+	})
+	require.NoError(t, err)
+
+	options, err := CleanLeaderboardOptions("", 0, 0)
+	require.NoError(t, err)
+	entries, err := GetLeaderboard(db, options, now)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "Hello", entries[0].Name)
+	require.Equal(t, 1, *entries[0].RankChange)
+	require.Equal(t, -1, *entries[1].RankChange)
+
+	_, err = CleanLeaderboardOptions("week", 0, 0)
+	require.Error(t, err)
+}
+
+func TestActivistPointsQuery(t *testing.T) {
+	// The activists list's points use the default window and its
+	// half-life, like GetLeaderboard.
+	query := activistPointsQuery()
+	require.Contains(t, query, "INTERVAL 30 DAY")
+	require.Contains(t, query, "DATEDIFF(NOW(), e.date) / 15)")
+	require.Contains(t, query, leaderboardTypePointsSQL+" + "+leaderboardRolePointsSQL)
+}
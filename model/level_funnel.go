@@ -0,0 +1,323 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Where a recorded level change came from. Backfilled changes are
+// reconstructed from activists_history and date_organizer, so they
+// can miss changes made before history was kept.
+const (
+	LevelChangeSourceUpdate                = "update"
+	LevelChangeSourceBackfillHistory       = "backfill_history"
+	LevelChangeSourceBackfillDateOrganizer = "backfill_date_organizer"
+)
+
+/** Type Definitions */
+
+type LevelChange struct {
+	ActivistID int       `db:"activist_id"`
+	OldLevel   string    `db:"old_level"`
+	NewLevel   string    `db:"new_level"`
+	ChangedAt  time.Time `db:"changed_at"`
+	Source     string    `db:"source"`
+	UserEmail  string    `db:"user_email"`
+}
+
+// LevelFunnelStep is the conversion from one level to the next of
+// the activists who reached the first level in the report's date
+// range. Conversions after the range still count.
+type LevelFunnelStep struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	Entered        int     `json:"entered"`
+	Converted      int     `json:"converted"`
+	ConversionRate float64 `json:"conversion_rate"`
+	// Null if nobody converted.
+	MedianDaysToConvert *float64 `json:"median_days_to_convert"`
+}
+
+// PipelineStep is a step of the chapter member pipeline. DropOff is
+// the number of activists who reached the previous step but not this
+// one.
+type PipelineStep struct {
+	Name      string `json:"name"`
+	Activists int    `json:"activists"`
+	DropOff   int    `json:"drop_off"`
+}
+
+type LevelFunnelReport struct {
+	Steps    []LevelFunnelStep `json:"steps"`
+	Pipeline []PipelineStep    `json:"pipeline"`
+}
+
+/** Functions and Methods */
+
+func insertLevelChange(db sqlx.Execer, change LevelChange) error {
+	_, err := db.Exec(`
+INSERT INTO activist_level_changes (activist_id, old_level, new_level, changed_at, source, user_email)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		change.ActivistID, change.OldLevel, change.NewLevel, change.ChangedAt, change.Source, change.UserEmail)
+	if err != nil {
+		return errors.Wrapf(err, "failed to insert level change for activist %d", change.ActivistID)
+	}
+	return nil
+}
+
+// BackfillLevelChanges reconstructs level changes made before they
+// were recorded, replacing any earlier backfill. Changes are taken
+// from consecutive activists_history revisions with different levels,
+// and organizers with a date_organizer who have no recorded change to
+// Organizer are assumed to have become one on that date. It returns
+// the number of changes backfilled.
+func BackfillLevelChanges(db *sqlx.DB) (int, error) {
+	var history []struct {
+		ActivistID int       `db:"activist_id"`
+		Level      string    `db:"activist_level"`
+		Timestamp  time.Time `db:"timestamp"`
+	}
+	err := db.Select(&history, `
+SELECT activist_id, activist_level, timestamp
+FROM activists_history
+ORDER BY activist_id, revision`)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to select activists history")
+	}
+
+	var organizers []struct {
+		ActivistID    int       `db:"id"`
+		DateOrganizer time.Time `db:"date_organizer"`
+	}
+	err = db.Select(&organizers, `
+SELECT id, date_organizer
+FROM activists
+WHERE activist_level = 'Organizer' AND date_organizer IS NOT NULL`)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to select organizers")
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+	_, err = tx.Exec(`DELETE FROM activist_level_changes WHERE source <> ?`, LevelChangeSourceUpdate)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to delete backfilled level changes")
+	}
+
+	n := 0
+	becameOrganizer := map[int]bool{}
+	for i := 1; i < len(history); i++ {
+		prev, cur := history[i-1], history[i]
+		if prev.ActivistID != cur.ActivistID || prev.Level == cur.Level {
+			continue
+		}
+		err := insertLevelChange(tx, LevelChange{
+			ActivistID: cur.ActivistID,
+			OldLevel:   prev.Level,
+			NewLevel:   cur.Level,
+			ChangedAt:  cur.Timestamp,
+			Source:     LevelChangeSourceBackfillHistory,
+		})
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if cur.Level == "Organizer" {
+			becameOrganizer[cur.ActivistID] = true
+		}
+		n++
+	}
+
+	var recorded []int
+	err = tx.Select(&recorded, `
+SELECT DISTINCT activist_id
+FROM activist_level_changes
+WHERE new_level = 'Organizer' AND source = ?`, LevelChangeSourceUpdate)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to select recorded organizer changes")
+	}
+	for _, id := range recorded {
+		becameOrganizer[id] = true
+	}
+	for _, o := range organizers {
+		if becameOrganizer[o.ActivistID] {
+			continue
+		}
+		err := insertLevelChange(tx, LevelChange{
+			ActivistID: o.ActivistID,
+			NewLevel:   "Organizer",
+			ChangedAt:  o.DateOrganizer,
+			Source:     LevelChangeSourceBackfillDateOrganizer,
+		})
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		n++
+	}
+
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to commit backfilled level changes")
+	}
+	return n, nil
+}
+
+func medianDays(durations []time.Duration) *float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mid := len(durations) / 2
+	median := durations[mid]
+	if len(durations)%2 == 0 {
+		median = (durations[mid-1] + durations[mid]) / 2
+	}
+	days := median.Hours() / 24
+	return &days
+}
+
+func newLevelFunnelStep(from, to string, entered int, durations []time.Duration) LevelFunnelStep {
+	step := LevelFunnelStep{
+		From:                from,
+		To:                  to,
+		Entered:             entered,
+		Converted:           len(durations),
+		MedianDaysToConvert: medianDays(durations),
+	}
+	if entered != 0 {
+		step.ConversionRate = float64(step.Converted) / float64(entered)
+	}
+	return step
+}
+
+// GetLevelFunnel reports how activists move from Supporter to Chapter
+// Member to Organizer. Activists become Supporters at their first
+// event and reach a level at their first recorded change to it (or
+// above it). Either date may be empty.
+func GetLevelFunnel(db *sqlx.DB, dateFrom, dateTo string) (LevelFunnelReport, error) {
+	var activists []struct {
+		ID                    int            `db:"id"`
+		FirstEvent            time.Time      `db:"first_event"`
+		ProspectChapterMember bool           `db:"prospect_chapter_member"`
+		CMFirstEmail          sql.NullString `db:"cm_first_email"`
+		CMApprovalEmail       sql.NullString `db:"cm_approval_email"`
+	}
+	err := db.Select(&activists, `
+SELECT
+  a.id,
+  first.first_event,
+  a.prospect_chapter_member,
+  a.cm_first_email,
+  a.cm_approval_email
+FROM activists a
+JOIN (
+  SELECT ea.activist_id, min(e.date) AS first_event
+  FROM event_attendance ea
+  JOIN events e ON e.id = ea.event_id
+  GROUP BY ea.activist_id
+) first ON first.activist_id = a.id
+WHERE a.hidden = 0`)
+	if err != nil {
+		return LevelFunnelReport{}, errors.Wrap(err, "failed to select funnel activists")
+	}
+
+	var changes []LevelChange
+	err = db.Select(&changes, `
+SELECT activist_id, old_level, new_level, changed_at, source, user_email
+FROM activist_level_changes
+ORDER BY changed_at`)
+	if err != nil {
+		return LevelFunnelReport{}, errors.Wrap(err, "failed to select level changes")
+	}
+	becameCM := map[int]time.Time{}
+	becameOrganizer := map[int]time.Time{}
+	for _, c := range changes {
+		if c.NewLevel == ACTIVIST_LEVEL_CHAPTER_MEMBER || c.NewLevel == "Organizer" {
+			if _, ok := becameCM[c.ActivistID]; !ok {
+				becameCM[c.ActivistID] = c.ChangedAt
+			}
+		}
+		if c.NewLevel == "Organizer" {
+			if _, ok := becameOrganizer[c.ActivistID]; !ok {
+				becameOrganizer[c.ActivistID] = c.ChangedAt
+			}
+		}
+	}
+
+	inRange := func(t time.Time) bool {
+		d := t.Format(EventDateLayout)
+		return (dateFrom == "" || d >= dateFrom) && (dateTo == "" || d <= dateTo)
+	}
+
+	supporters := 0
+	var toCM []time.Duration
+	// Reached each pipeline step, in order. Reaching a step implies
+	// reaching the ones before it, since flags like
+	// prospect_chapter_member are cleared once they're done with.
+	pipeline := make([]int, 4)
+	for _, a := range activists {
+		if !inRange(a.FirstEvent) {
+			continue
+		}
+		supporters++
+		cm, isCM := becameCM[a.ID]
+		if isCM && !cm.Before(a.FirstEvent) {
+			toCM = append(toCM, cm.Sub(a.FirstEvent))
+		}
+
+		reached := []bool{
+			a.ProspectChapterMember,
+			a.CMFirstEmail.Valid && a.CMFirstEmail.String != "",
+			a.CMApprovalEmail.Valid && a.CMApprovalEmail.String != "",
+			isCM,
+		}
+		for i := range reached {
+			for _, later := range reached[i:] {
+				if later {
+					pipeline[i]++
+					break
+				}
+			}
+		}
+	}
+
+	chapterMembers := 0
+	var toOrganizer []time.Duration
+	for id, cm := range becameCM {
+		if !inRange(cm) {
+			continue
+		}
+		chapterMembers++
+		if o, ok := becameOrganizer[id]; ok && !o.Before(cm) {
+			toOrganizer = append(toOrganizer, o.Sub(cm))
+		}
+	}
+
+	report := LevelFunnelReport{
+		Steps: []LevelFunnelStep{
+			newLevelFunnelStep("Supporter", ACTIVIST_LEVEL_CHAPTER_MEMBER, supporters, toCM),
+			newLevelFunnelStep(ACTIVIST_LEVEL_CHAPTER_MEMBER, "Organizer", chapterMembers, toOrganizer),
+		},
+	}
+	previous := supporters
+	for i, name := range []string{"prospect_chapter_member", "cm_first_email", "cm_approval_email", "chapter_member"} {
+		report.Pipeline = append(report.Pipeline, PipelineStep{
+			Name:      name,
+			Activists: pipeline[i],
+			DropOff:   previous - pipeline[i],
+		})
+		previous = pipeline[i]
+	}
+	return report, nil
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelFunnel(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+	a3, err := GetOrCreateActivist(db, "Hey")
+	require.NoError(t, err)
+
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "event",
+		EventDate:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2, a3},
+	})
+	require.NoError(t, err)
+
+	// Hello became a chapter member, then an organizer.
+	for _, h := range []struct {
+		level string
+		date  time.Time
+	}{
+		{"Supporter", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Chapter Member", time.Date(2020, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"Organizer", time.Date(2020, 3, 11, 0, 0, 0, 0, time.UTC)},
+	} {
+		_, err := db.Exec(`
+INSERT INTO activists_history (activist_id, action, timestamp, user_email, name, email, facebook, activist_level)
+VALUES (?, 'UPDATE', ?, '', 'Hello', '', '', ?)`, a1.ID, h.date, h.level)
+		require.NoError(t, err)
+	}
+	// Hi is an organizer with only a date_organizer.
+	_, err = db.Exec(`
+UPDATE activists SET activist_level = 'Organizer', date_organizer = '2020-02-01'
+WHERE id = ?`, a2.ID)
+	require.NoError(t, err)
+	// Hey got the first email but never became a chapter member.
+	_, err = db.Exec(`
+UPDATE activists SET prospect_chapter_member = 1, cm_first_email = '2020-01-05'
+WHERE id = ?`, a3.ID)
+	require.NoError(t, err)
+
+	n, err := BackfillLevelChanges(db)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	// Backfilling again replaces the earlier backfill.
+	n, err = BackfillLevelChanges(db)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	report, err := GetLevelFunnel(db, "2020-01-01", "2020-01-31")
+	require.NoError(t, err)
+	require.Len(t, report.Steps, 2)
+
+	toCM := report.Steps[0]
+	require.Equal(t, 3, toCM.Entered)
+	require.Equal(t, 2, toCM.Converted)
+	require.NotNil(t, toCM.MedianDaysToConvert)
+	require.Equal(t, 20.5, *toCM.MedianDaysToConvert)
+
+	// Only Hello became a chapter member in January.
+	toOrganizer := report.Steps[1]
+	require.Equal(t, 1, toOrganizer.Entered)
+	require.Equal(t, 1, toOrganizer.Converted)
+	require.Equal(t, 60.0, *toOrganizer.MedianDaysToConvert)
+
+	require.Equal(t, []PipelineStep{
+		{Name: "prospect_chapter_member", Activists: 3, DropOff: 0},
+		{Name: "cm_first_email", Activists: 3, DropOff: 0},
+		{Name: "cm_approval_email", Activists: 2, DropOff: 1},
+		{Name: "chapter_member", Activists: 2, DropOff: 0},
+	}, report.Pipeline)
+}
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// What an admin decided about changes held by a guardrail.
+const (
+	MailingListHoldPending  = "pending"
+	MailingListHoldApproved = "approved"
+	MailingListHoldRejected = "rejected"
+)
+
+// Removing this many members never trips the percentage guardrail, so
+// small lists can still lose a member or two.
+const mailingListMinGuardedRemovals = 3
+
+// DefaultMailingListGuardrails are the guardrails lists and groups
+// start with. The column defaults in db.go match them.
+var DefaultMailingListGuardrails = MailingListGuardrails{
+	MaxRemovals:      10,
+	MaxRemovePercent: 50,
+	AllowEmpty:       false,
+}
+
+/** Type Definitions */
+
+// MailingListGuardrails limit how much a sync may change a list
+// before the changes are held for an admin to approve. Zero limits
+// aren't enforced.
+type MailingListGuardrails struct {
+	// The most members a sync may remove.
+	MaxRemovals int `db:"max_removals"`
+	// The most a sync may remove, as a percentage of the list's
+	// current members.
+	MaxRemovePercent int `db:"max_remove_percent"`
+	// Whether a sync may remove every member of the list.
+	AllowEmpty bool `db:"allow_empty"`
+}
+
+// MailingListHold is a set of changes to a mailing list that tripped
+// one of its guardrails. Lists have at most one hold.
+type MailingListHold struct {
+	ListEmail string    `db:"list_email"`
+	Held      time.Time `db:"held"`
+	// Which guardrail tripped.
+	Reason string `db:"reason"`
+	// pending, approved or rejected.
+	Status    string   `db:"status"`
+	Additions []string `db:"-"`
+	Removals  []string `db:"-"`
+}
+
+/** Functions and Methods */
+
+// Check returns why changing a list of current members by adding and
+// removing members trips a guardrail, or "" if it doesn't.
+func (g MailingListGuardrails) Check(current, additions, removals int) string {
+	if !g.AllowEmpty && current > 0 && removals >= current && additions == 0 {
+		return fmt.Sprintf("Would remove all %d members", current)
+	}
+	if g.MaxRemovals > 0 && removals > g.MaxRemovals {
+		return fmt.Sprintf("Would remove %d members, more than the limit of %d", removals, g.MaxRemovals)
+	}
+	if g.MaxRemovePercent > 0 && current > 0 && removals > mailingListMinGuardedRemovals &&
+		removals*100 > g.MaxRemovePercent*current {
+		return fmt.Sprintf("Would remove %d of %d members, more than the limit of %d%%",
+			removals, current, g.MaxRemovePercent)
+	}
+	return ""
+}
+
+func cleanMailingListGuardrails(g MailingListGuardrails) (MailingListGuardrails, error) {
+	if g.MaxRemovals < 0 {
+		return MailingListGuardrails{}, errors.New("Max removals can't be negative")
+	}
+	if g.MaxRemovePercent < 0 || g.MaxRemovePercent > 100 {
+		return MailingListGuardrails{}, errors.New("Max removed percent must be between 0 and 100")
+	}
+	return g, nil
+}
+
+// UpdateGroupGuardrails sets the guardrails of a group's mailing list.
+func UpdateGroupGuardrails(db *sqlx.DB, groupID int, guardrails MailingListGuardrails) error {
+	guardrails, err := cleanMailingListGuardrails(guardrails)
+	if err != nil {
+		return err
+	}
+	res, err := db.Exec(`
+UPDATE activist_groups
+SET max_removals = ?, max_remove_percent = ?, allow_empty = ?
+WHERE id = ?`, guardrails.MaxRemovals, guardrails.MaxRemovePercent, guardrails.AllowEmpty, groupID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to update guardrails of group %d", groupID)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return errors.Wrap(err, "failed to get rows affected")
+	} else if n == 0 {
+		if _, err := GetGroup(db, GroupQueryOptions{GroupID: groupID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedEmails(emails []string) []string {
+	out := append([]string{}, emails...)
+	sort.Strings(out)
+	return out
+}
+
+// SameChanges returns whether the hold is for exactly these additions
+// and removals.
+func (h MailingListHold) SameChanges(additions, removals []string) bool {
+	equal := func(a, b []string) bool {
+		a, b = sortedEmails(a), sortedEmails(b)
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return equal(h.Additions, additions) && equal(h.Removals, removals)
+}
+
+type mailingListHoldRow struct {
+	MailingListHold
+	AdditionsText string `db:"additions"`
+	RemovalsText  string `db:"removals"`
+}
+
+func (r mailingListHoldRow) hold() MailingListHold {
+	h := r.MailingListHold
+	h.Additions = splitEmails(r.AdditionsText)
+	h.Removals = splitEmails(r.RemovalsText)
+	return h
+}
+
+// GetMailingListHolds returns the held changes of all lists, oldest
+// first.
+func GetMailingListHolds(db *sqlx.DB) ([]MailingListHold, error) {
+	var rows []mailingListHoldRow
+	err := db.Select(&rows, `
+SELECT list_email, held, reason, status, additions, removals
+FROM mailing_list_holds
+ORDER BY held, list_email`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing list holds")
+	}
+	holds := []MailingListHold{}
+	for _, r := range rows {
+		holds = append(holds, r.hold())
+	}
+	return holds, nil
+}
+
+// SaveMailingListHolds replaces the holds of the lists in saved and
+// deletes the holds of the lists in released.
+func SaveMailingListHolds(db *sqlx.DB, saved []MailingListHold, released []string) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	for _, h := range saved {
+		_, err := tx.Exec(`
+REPLACE INTO mailing_list_holds (list_email, held, reason, status, additions, removals)
+VALUES (?, ?, ?, ?, ?, ?)`, h.ListEmail, h.Held, h.Reason, h.Status,
+			strings.Join(sortedEmails(h.Additions), "\n"), strings.Join(sortedEmails(h.Removals), "\n"))
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to save mailing list hold of %s", h.ListEmail)
+		}
+	}
+	for _, listEmail := range released {
+		if _, err := tx.Exec(`DELETE FROM mailing_list_holds WHERE list_email = ?`, listEmail); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to delete mailing list hold of %s", listEmail)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit mailing list holds")
+	}
+	return nil
+}
+
+// SetMailingListHoldStatus records an admin's decision about a list's
+// held changes. Approved changes are made by the next sync, as long as
+// they haven't changed in the meantime.
+func SetMailingListHoldStatus(db *sqlx.DB, listEmail, status string) error {
+	if status != MailingListHoldApproved && status != MailingListHoldRejected {
+		return errors.Errorf("Invalid hold status: %s", status)
+	}
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM mailing_list_holds WHERE list_email = ?`, listEmail); err != nil {
+		return errors.Wrapf(err, "failed to select mailing list hold of %s", listEmail)
+	}
+	if count == 0 {
+		return errors.Errorf("No held changes for %s", listEmail)
+	}
+	if _, err := db.Exec(`UPDATE mailing_list_holds SET status = ? WHERE list_email = ?`, status, listEmail); err != nil {
+		return errors.Wrapf(err, "failed to update mailing list hold of %s", listEmail)
+	}
+	return nil
+}
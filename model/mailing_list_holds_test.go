@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailingListGuardrailsCheck(t *testing.T) {
+	g := DefaultMailingListGuardrails
+	require.Equal(t, "", g.Check(0, 5, 0))
+	require.Equal(t, "", g.Check(4, 1, 2))
+	// Small lists can lose a few members.
+	require.Equal(t, "", g.Check(4, 0, 3))
+	require.Equal(t, "Would remove all 3 members", g.Check(3, 0, 3))
+	require.Equal(t, "Would remove 11 members, more than the limit of 10", g.Check(100, 0, 11))
+	require.Equal(t, "Would remove 5 of 8 members, more than the limit of 50%", g.Check(8, 0, 5))
+
+	// Zero limits aren't enforced.
+	require.Equal(t, "", MailingListGuardrails{AllowEmpty: true}.Check(100, 0, 100))
+}
+
+func TestMailingListHoldSameChanges(t *testing.T) {
+	h := MailingListHold{
+		Additions: []string{"a@example.org"},
+		Removals:  []string{"b@example.org", "c@example.org"},
+	}
+	require.True(t, h.SameChanges([]string{"a@example.org"}, []string{"c@example.org", "b@example.org"}))
+	require.False(t, h.SameChanges(nil, []string{"c@example.org", "b@example.org"}))
+	require.False(t, h.SameChanges([]string{"a@example.org"}, []string{"c@example.org"}))
+}
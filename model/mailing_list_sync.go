@@ -0,0 +1,275 @@
+package model
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// What happened to a member of a mailing list during a sync.
+const (
+	MailingListSyncAdded   = "add"
+	MailingListSyncRemoved = "remove"
+	// Listing the members, or adding or removing one, failed.
+	MailingListSyncFailed = "fail"
+	// The activist should be on the list but couldn't be synced,
+	// usually because they have no email. The same activists are
+	// skipped on every run, so only the latest run keeps these;
+	// older runs only keep how many were skipped.
+	MailingListSyncSkipped = "skip"
+	// The list's changes tripped a guardrail and weren't made.
+	MailingListSyncHeld = "hold"
+)
+
+// How long sync runs are kept. The sync runs every few minutes, so
+// this keeps enough to see when a list started failing.
+const mailingListSyncRetention = 14 * 24 * time.Hour
+
+/** Type Definitions */
+
+// MailingListSyncRun is a pass of the mailing lists sync.
+type MailingListSyncRun struct {
+	ID       int       `db:"id" json:"id"`
+	Started  time.Time `db:"started" json:"started"`
+	Finished time.Time `db:"finished" json:"finished"`
+	// Empty for scheduled runs.
+	TriggeredByEmail string                `db:"triggered_by_email" json:"triggered_by_email"`
+	Failed           bool                  `db:"failed" json:"failed"`
+	Lists            []MailingListSyncList `json:"lists"`
+}
+
+// MailingListSyncList is what a sync run did to one mailing list.
+type MailingListSyncList struct {
+	RunID     int                   `db:"run_id" json:"run_id"`
+	ListEmail string                `db:"list_email" json:"list_email"`
+	Failed    bool                  `db:"failed" json:"failed"`
+	Skipped   int                   `db:"skipped" json:"skipped"`
+	Items     []MailingListSyncItem `json:"items"`
+}
+
+type MailingListSyncItem struct {
+	RunID     int    `db:"run_id" json:"run_id"`
+	ListEmail string `db:"list_email" json:"list_email"`
+	// add, remove, fail, skip or hold.
+	Action string `db:"action" json:"action"`
+	// Empty if listing the members failed, or if the activist was
+	// skipped for not having an email.
+	MemberEmail  string `db:"member_email" json:"member_email"`
+	ActivistName string `db:"activist_name" json:"activist_name"`
+	// Why the action failed, the activist was skipped or the
+	// changes were held.
+	Reason string `db:"reason" json:"reason"`
+}
+
+// FailingMailingList is a mailing list that failed to sync in the
+// most recent runs.
+type FailingMailingList struct {
+	ListEmail string `json:"list_email"`
+	// Consecutive runs, up to the latest one, that failed.
+	FailedRuns int `json:"failed_runs"`
+	// When the first of those runs started.
+	FailingSince time.Time `json:"failing_since"`
+	// The reason the latest run failed.
+	LastReason string `json:"last_reason"`
+}
+
+/** Functions and Methods */
+
+// Count returns how many items of the list have the action.
+func (l MailingListSyncList) Count(action string) int {
+	count := 0
+	for _, item := range l.Items {
+		if item.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// ItemsWithAction returns the list's items with the action.
+func (l MailingListSyncList) ItemsWithAction(action string) []MailingListSyncItem {
+	var items []MailingListSyncItem
+	for _, item := range l.Items {
+		if item.Action == action {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RecordMailingListSyncRun stores a finished sync run and deletes
+// runs that are past retention. It returns the ID of the run.
+func RecordMailingListSyncRun(db *sqlx.DB, run MailingListSyncRun) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+	res, err := tx.Exec(`
+INSERT INTO mailing_list_sync_runs (started, finished, triggered_by_email, failed)
+VALUES (?, ?, ?, ?)`, run.Started, run.Finished, run.TriggeredByEmail, run.Failed)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to insert mailing list sync run")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to get mailing list sync run id")
+	}
+	for _, l := range run.Lists {
+		_, err := tx.Exec(`
+INSERT INTO mailing_list_sync_lists (run_id, list_email, failed, skipped)
+VALUES (?, ?, ?, ?)`, id, l.ListEmail, l.Failed, l.Skipped)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "failed to insert mailing list sync of %s", l.ListEmail)
+		}
+		for _, item := range l.Items {
+			_, err := tx.Exec(`
+INSERT INTO mailing_list_sync_items (run_id, list_email, action, member_email, activist_name, reason)
+VALUES (?, ?, ?, ?, ?, ?)`, id, l.ListEmail, item.Action, item.MemberEmail, item.ActivistName, item.Reason)
+			if err != nil {
+				tx.Rollback()
+				return 0, errors.Wrapf(err, "failed to insert mailing list sync item of %s", l.ListEmail)
+			}
+		}
+	}
+
+	_, err = tx.Exec(`DELETE FROM mailing_list_sync_items WHERE action = ? AND run_id <> ?`, MailingListSyncSkipped, id)
+	if err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to delete skipped activists of previous runs")
+	}
+
+	cutoff := run.Started.Add(-mailingListSyncRetention)
+	for _, table := range []string{"mailing_list_sync_items", "mailing_list_sync_lists"} {
+		_, err := tx.Exec(`
+DELETE t FROM `+table+` t
+JOIN mailing_list_sync_runs r ON r.id = t.run_id
+WHERE r.started < ?`, cutoff)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "failed to delete old rows from %s", table)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM mailing_list_sync_runs WHERE started < ?`, cutoff); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(err, "failed to delete old mailing list sync runs")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrap(err, "failed to commit mailing list sync run")
+	}
+	return int(id), nil
+}
+
+// GetMailingListSyncRuns returns the most recent sync runs, newest
+// first.
+func GetMailingListSyncRuns(db *sqlx.DB, limit int) ([]MailingListSyncRun, error) {
+	var runs []MailingListSyncRun
+	err := db.Select(&runs, `
+SELECT id, started, finished, triggered_by_email, failed
+FROM mailing_list_sync_runs
+ORDER BY started DESC, id DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing list sync runs")
+	}
+	if len(runs) == 0 {
+		return runs, nil
+	}
+
+	ids := make([]int, len(runs))
+	for i, r := range runs {
+		ids[i] = r.ID
+	}
+	var lists []MailingListSyncList
+	query, args, err := sqlx.In(`
+SELECT run_id, list_email, failed, skipped
+FROM mailing_list_sync_lists
+WHERE run_id IN (?)
+ORDER BY list_email`, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build mailing list sync lists query")
+	}
+	if err := db.Select(&lists, db.Rebind(query), args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing list sync lists")
+	}
+	var items []MailingListSyncItem
+	query, args, err = sqlx.In(`
+SELECT run_id, list_email, action, member_email, activist_name, reason
+FROM mailing_list_sync_items
+WHERE run_id IN (?)
+ORDER BY id`, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build mailing list sync items query")
+	}
+	if err := db.Select(&items, db.Rebind(query), args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing list sync items")
+	}
+
+	type listKey struct {
+		runID     int
+		listEmail string
+	}
+	listItems := map[listKey][]MailingListSyncItem{}
+	for _, item := range items {
+		k := listKey{item.RunID, item.ListEmail}
+		listItems[k] = append(listItems[k], item)
+	}
+	runLists := map[int][]MailingListSyncList{}
+	for _, l := range lists {
+		l.Items = listItems[listKey{l.RunID, l.ListEmail}]
+		runLists[l.RunID] = append(runLists[l.RunID], l)
+	}
+	for i := range runs {
+		runs[i].Lists = runLists[runs[i].ID]
+	}
+	return runs, nil
+}
+
+// FailingMailingLists returns the lists that failed in the latest run
+// that synced them, given runs newest first. Lists that have been
+// failing the longest come first.
+func FailingMailingLists(runs []MailingListSyncRun) []FailingMailingList {
+	failing := map[string]*FailingMailingList{}
+	// Lists whose streak of failures has ended, going back in time.
+	done := map[string]bool{}
+	for _, run := range runs {
+		for _, l := range run.Lists {
+			if done[l.ListEmail] {
+				continue
+			}
+			if !l.Failed {
+				done[l.ListEmail] = true
+				continue
+			}
+			f := failing[l.ListEmail]
+			if f == nil {
+				f = &FailingMailingList{ListEmail: l.ListEmail}
+				if items := l.ItemsWithAction(MailingListSyncFailed); len(items) != 0 {
+					f.LastReason = items[0].Reason
+				}
+				failing[l.ListEmail] = f
+			}
+			f.FailedRuns++
+			f.FailingSince = run.Started
+		}
+	}
+
+	out := []FailingMailingList{}
+	for _, f := range failing {
+		out = append(out, *f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].FailedRuns != out[j].FailedRuns {
+			return out[i].FailedRuns > out[j].FailedRuns
+		}
+		return out[i].ListEmail < out[j].ListEmail
+	})
+	return out
+}
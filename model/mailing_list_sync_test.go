@@ -0,0 +1,88 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailingMailingLists(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return time.Date(2020, 3, 1, 12, minutes, 0, 0, time.UTC)
+	}
+	failed := func(list, reason string) MailingListSyncList {
+		return MailingListSyncList{
+			ListEmail: list,
+			Failed:    true,
+			Items:     []MailingListSyncItem{{Action: MailingListSyncFailed, Reason: reason}},
+		}
+	}
+	ok := func(list string) MailingListSyncList {
+		return MailingListSyncList{ListEmail: list}
+	}
+	// Newest first.
+	runs := []MailingListSyncRun{
+		{Started: at(15), Lists: []MailingListSyncList{failed("a@example.org", "quota"), failed("b@example.org", "not found"), ok("c@example.org")}},
+		{Started: at(10), Lists: []MailingListSyncList{failed("a@example.org", "timeout"), ok("b@example.org"), failed("c@example.org", "timeout")}},
+		{Started: at(5), Lists: []MailingListSyncList{failed("a@example.org", "timeout")}},
+	}
+
+	require.Equal(t, []FailingMailingList{
+		{ListEmail: "a@example.org", FailedRuns: 3, FailingSince: at(5), LastReason: "quota"},
+		{ListEmail: "b@example.org", FailedRuns: 1, FailingSince: at(15), LastReason: "not found"},
+	}, FailingMailingLists(runs))
+}
+
+func TestRecordMailingListSyncRun(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	old := MailingListSyncRun{Started: now.Add(-30 * 24 * time.Hour), Finished: now.Add(-30 * 24 * time.Hour)}
+	_, err := RecordMailingListSyncRun(db, old)
+	require.NoError(t, err)
+
+	run := MailingListSyncRun{
+		Started:          now,
+		Finished:         now,
+		TriggeredByEmail: "admin@example.org",
+		Failed:           true,
+		Lists: []MailingListSyncList{
+			{ListEmail: "a@example.org", Failed: true, Items: []MailingListSyncItem{
+				{Action: MailingListSyncFailed, MemberEmail: "x@example.org", Reason: "quota"},
+			}},
+			{ListEmail: "b@example.org", Skipped: 1, Items: []MailingListSyncItem{
+				{Action: MailingListSyncAdded, MemberEmail: "y@example.org"},
+				{Action: MailingListSyncSkipped, ActivistName: "Zed", Reason: "Activist has no email"},
+			}},
+		},
+	}
+	id, err := RecordMailingListSyncRun(db, run)
+	require.NoError(t, err)
+
+	// The old run is past retention.
+	runs, err := GetMailingListSyncRuns(db, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, id, runs[0].ID)
+	require.Equal(t, "admin@example.org", runs[0].TriggeredByEmail)
+	require.True(t, runs[0].Failed)
+	require.Len(t, runs[0].Lists, 2)
+	require.True(t, runs[0].Lists[0].Failed)
+	require.Equal(t, "quota", runs[0].Lists[0].Items[0].Reason)
+	require.Equal(t, 1, runs[0].Lists[1].Count(MailingListSyncAdded))
+	require.Equal(t, "Zed", runs[0].Lists[1].ItemsWithAction(MailingListSyncSkipped)[0].ActivistName)
+
+	// Only the latest run keeps who was skipped. Earlier ones keep
+	// how many.
+	run.Started, run.Finished = now.Add(time.Minute), now.Add(time.Minute)
+	_, err = RecordMailingListSyncRun(db, run)
+	require.NoError(t, err)
+	runs, err = GetMailingListSyncRuns(db, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.Len(t, runs[0].Lists[1].ItemsWithAction(MailingListSyncSkipped), 1)
+	require.Len(t, runs[1].Lists[1].ItemsWithAction(MailingListSyncSkipped), 0)
+	require.Equal(t, 1, runs[1].Lists[1].Skipped)
+}
@@ -0,0 +1,439 @@
+package model
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+// Rules for who is on a mailing list. Working groups and committees
+// don't need one; their own lists are synced from their members.
+// There are no tag or segment rules, since activists don't have tags
+// and the ADB has no saved segments to refer to.
+const (
+	// Activists at any of the comma-separated levels in the rule
+	// value.
+	MailingListRuleActivistLevel = "activist_level"
+	// Members of the group whose ID is the rule value, other than
+	// observers.
+	MailingListRuleGroupMembers = "group_members"
+	// Leads and co-leads of all groups of the type in the rule value.
+	// Groups without leads are represented by their group email.
+	MailingListRuleGroupLeads = "group_leads"
+	// The group emails of all groups of the comma-separated types in
+	// the rule value.
+	MailingListRuleGroupEmails = "group_emails"
+	// Only the always-included emails.
+	MailingListRuleManual = "manual"
+)
+
+var MailingListRules = []string{
+	MailingListRuleActivistLevel,
+	MailingListRuleGroupMembers,
+	MailingListRuleGroupLeads,
+	MailingListRuleGroupEmails,
+	MailingListRuleManual,
+}
+
+/** Type Definitions */
+
+// MailingList is a mailing list that's synced from the ADB.
+type MailingList struct {
+	ID          int    `db:"id"`
+	Email       string `db:"email"`
+	Description string `db:"description"`
+	RuleType    string `db:"rule_type"`
+	RuleValue   string `db:"rule_value"`
+	// Emails that are on the list whatever the rule says, and emails
+	// that never are. Exclusions win.
+	AlwaysInclude []string
+	AlwaysExclude []string
+	MailingListGuardrails
+}
+
+// MailingListTarget is someone who should be on a mailing list. Email
+// is empty if the activist has none, so they can't be synced.
+type MailingListTarget struct {
+	Email string
+	Name  string
+}
+
+/** Functions and Methods */
+
+func isMailingListRule(rule string) bool {
+	for _, r := range MailingListRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRuleValue splits a comma-separated rule value, dropping empty
+// entries.
+func splitRuleValue(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// splitEmails splits emails separated by whitespace or commas,
+// normalizing them and dropping duplicates.
+func splitEmails(s string) []string {
+	seen := map[string]bool{}
+	emails := []string{}
+	for _, e := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	}) {
+		e = strings.ToLower(e)
+		if !seen[e] {
+			seen[e] = true
+			emails = append(emails, e)
+		}
+	}
+	return emails
+}
+
+// CleanMailingListData validates a mailing list submitted by an admin.
+// include and exclude hold emails separated by commas or whitespace.
+func CleanMailingListData(db *sqlx.DB, id int, email, description, ruleType, ruleValue, include, exclude string, guardrails MailingListGuardrails) (MailingList, error) {
+	guardrails, err := cleanMailingListGuardrails(guardrails)
+	if err != nil {
+		return MailingList{}, err
+	}
+	list := MailingList{
+		ID:            id,
+		Email:         strings.ToLower(strings.TrimSpace(email)),
+		Description:   strings.TrimSpace(description),
+		RuleType:      strings.TrimSpace(ruleType),
+		RuleValue:     strings.TrimSpace(ruleValue),
+		AlwaysInclude: splitEmails(include),
+		AlwaysExclude: splitEmails(exclude),
+
+		MailingListGuardrails: guardrails,
+	}
+	if list.Email == "" || !strings.Contains(list.Email, "@") {
+		return MailingList{}, errors.New("A valid list email is required")
+	}
+	for _, e := range append(append([]string{}, list.AlwaysInclude...), list.AlwaysExclude...) {
+		if !strings.Contains(e, "@") {
+			return MailingList{}, errors.Errorf("Not a valid email: %s", e)
+		}
+	}
+
+	switch list.RuleType {
+	case MailingListRuleActivistLevel:
+		levels := splitRuleValue(list.RuleValue)
+		if len(levels) == 0 {
+			return MailingList{}, errors.New("At least one activist level is required")
+		}
+		list.RuleValue = strings.Join(levels, ",")
+	case MailingListRuleGroupMembers:
+		groupID, err := strconv.Atoi(list.RuleValue)
+		if err != nil {
+			return MailingList{}, errors.New("The rule value must be a group ID")
+		}
+		if _, err := GetGroup(db, GroupQueryOptions{GroupID: groupID}); err != nil {
+			return MailingList{}, err
+		}
+	case MailingListRuleGroupLeads:
+		if !isGroupType(list.RuleValue) {
+			return MailingList{}, errors.Errorf("Group type doesn't exist: %s", list.RuleValue)
+		}
+	case MailingListRuleGroupEmails:
+		types := splitRuleValue(list.RuleValue)
+		if len(types) == 0 {
+			return MailingList{}, errors.New("At least one group type is required")
+		}
+		for _, t := range types {
+			if !isGroupType(t) {
+				return MailingList{}, errors.Errorf("Group type doesn't exist: %s", t)
+			}
+		}
+		list.RuleValue = strings.Join(types, ",")
+	case MailingListRuleManual:
+		list.RuleValue = ""
+	default:
+		return MailingList{}, errors.Errorf("Rule must be one of %s", strings.Join(MailingListRules, ", "))
+	}
+
+	// Working groups' lists are already synced from their members.
+	var groupName string
+	err = db.Get(&groupName, `SELECT name FROM activist_groups WHERE lower(group_email) = ? LIMIT 1`, list.Email)
+	if err != nil && err != sql.ErrNoRows {
+		return MailingList{}, errors.Wrap(err, "failed to check group emails")
+	}
+	if err == nil {
+		return MailingList{}, errors.Errorf("%s is already synced as the list of %s", list.Email, groupName)
+	}
+
+	return list, nil
+}
+
+func GetMailingLists(db *sqlx.DB) ([]MailingList, error) {
+	var lists []MailingList
+	err := db.Select(&lists, `
+SELECT id, email, description, rule_type, rule_value, max_removals, max_remove_percent, allow_empty
+FROM mailing_lists
+ORDER BY email`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing lists")
+	}
+
+	var overrides []struct {
+		ListID  int    `db:"list_id"`
+		Email   string `db:"email"`
+		Include bool   `db:"include"`
+	}
+	err = db.Select(&overrides, `
+SELECT list_id, email, include
+FROM mailing_list_overrides
+ORDER BY email`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select mailing list overrides")
+	}
+	byID := map[int]*MailingList{}
+	for i := range lists {
+		lists[i].AlwaysInclude = []string{}
+		lists[i].AlwaysExclude = []string{}
+		byID[lists[i].ID] = &lists[i]
+	}
+	for _, o := range overrides {
+		l := byID[o.ListID]
+		if l == nil {
+			continue
+		}
+		if o.Include {
+			l.AlwaysInclude = append(l.AlwaysInclude, o.Email)
+		} else {
+			l.AlwaysExclude = append(l.AlwaysExclude, o.Email)
+		}
+	}
+	return lists, nil
+}
+
+func CreateMailingList(db *sqlx.DB, list MailingList) (int, error) {
+	if list.ID != 0 {
+		return 0, errors.New("CreateMailingList: Mailing list ID must be 0")
+	}
+	return saveMailingList(db, list)
+}
+
+func UpdateMailingList(db *sqlx.DB, list MailingList) (int, error) {
+	if list.ID == 0 {
+		return 0, errors.New("UpdateMailingList: Mailing list ID can't be 0")
+	}
+	return saveMailingList(db, list)
+}
+
+func saveMailingList(db *sqlx.DB, list MailingList) (int, error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create transaction")
+	}
+
+	if list.ID == 0 {
+		res, err := tx.NamedExec(`
+INSERT INTO mailing_lists (email, description, rule_type, rule_value, max_removals, max_remove_percent, allow_empty)
+VALUES (:email, :description, :rule_type, :rule_value, :max_removals, :max_remove_percent, :allow_empty)`, list)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "failed to insert mailing list %s", list.Email)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrap(err, "failed to get mailing list id")
+		}
+		list.ID = int(id)
+	} else {
+		_, err := tx.NamedExec(`
+UPDATE mailing_lists
+SET email = :email,
+  description = :description,
+  rule_type = :rule_type,
+  rule_value = :rule_value,
+  max_removals = :max_removals,
+  max_remove_percent = :max_remove_percent,
+  allow_empty = :allow_empty
+WHERE id = :id`, list)
+		if err != nil {
+			tx.Rollback()
+			return 0, errors.Wrapf(err, "failed to update mailing list %s", list.Email)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM mailing_list_overrides WHERE list_id = ?`, list.ID); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrapf(err, "failed to delete overrides of mailing list %s", list.Email)
+	}
+	for _, o := range []struct {
+		emails  []string
+		include bool
+	}{{list.AlwaysInclude, true}, {list.AlwaysExclude, false}} {
+		for _, e := range o.emails {
+			_, err := tx.Exec(`
+INSERT INTO mailing_list_overrides (list_id, email, include)
+VALUES (?, ?, ?)`, list.ID, e, o.include)
+			if err != nil {
+				tx.Rollback()
+				return 0, errors.Wrapf(err, "failed to insert override %s of mailing list %s", e, list.Email)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, errors.Wrapf(err, "failed to commit mailing list %s", list.Email)
+	}
+	return list.ID, nil
+}
+
+// DeleteMailingList stops syncing a list. The list itself and its
+// members are left as they are.
+func DeleteMailingList(db *sqlx.DB, id int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	if _, err := tx.Exec(`DELETE FROM mailing_list_overrides WHERE list_id = ?`, id); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to delete overrides of mailing list %d", id)
+	}
+	if _, err := tx.Exec(`DELETE FROM mailing_lists WHERE id = ?`, id); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to delete mailing list %d", id)
+	}
+	return errors.Wrap(tx.Commit(), "failed to commit deleting mailing list")
+}
+
+// GetMailingListTargets returns who should be on a list according to
+// its rule, before the always-include and always-exclude entries are
+// applied.
+func GetMailingListTargets(db *sqlx.DB, list MailingList) ([]MailingListTarget, error) {
+	switch list.RuleType {
+	case MailingListRuleActivistLevel:
+		var activists []struct {
+			Name  string `db:"name"`
+			Email string `db:"email"`
+		}
+		query, args, err := sqlx.In(`
+SELECT name, email
+FROM activists
+WHERE hidden = 0 AND activist_level IN (?)
+ORDER BY name`, splitRuleValue(list.RuleValue))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to build activist level query")
+		}
+		if err := db.Select(&activists, db.Rebind(query), args...); err != nil {
+			return nil, errors.Wrapf(err, "failed to select activists for %s", list.Email)
+		}
+		targets := []MailingListTarget{}
+		for _, a := range activists {
+			targets = append(targets, MailingListTarget{Email: a.Email, Name: a.Name})
+		}
+		return targets, nil
+	case MailingListRuleGroupMembers:
+		groupID, err := strconv.Atoi(list.RuleValue)
+		if err != nil {
+			return nil, errors.Errorf("Not a group ID: %s", list.RuleValue)
+		}
+		group, err := GetGroup(db, GroupQueryOptions{GroupID: groupID})
+		if err != nil {
+			return nil, err
+		}
+		return groupMemberTargets(group), nil
+	case MailingListRuleGroupLeads:
+		groups, err := GetGroups(db, GroupQueryOptions{Types: []string{list.RuleValue}})
+		if err != nil {
+			return nil, err
+		}
+		return groupLeadTargets(groups), nil
+	case MailingListRuleGroupEmails:
+		groups, err := GetGroups(db, GroupQueryOptions{Types: splitRuleValue(list.RuleValue)})
+		if err != nil {
+			return nil, err
+		}
+		targets := []MailingListTarget{}
+		for _, g := range groups {
+			targets = append(targets, MailingListTarget{Email: g.GroupEmail, Name: g.Name})
+		}
+		return targets, nil
+	case MailingListRuleManual:
+		return []MailingListTarget{}, nil
+	}
+	return nil, errors.Errorf("Unknown rule for %s: %s", list.Email, list.RuleType)
+}
+
+func groupMemberTargets(group Group) []MailingListTarget {
+	targets := []MailingListTarget{}
+	// Archived groups aren't synced to mailing lists.
+	if group.Archived {
+		return targets
+	}
+	for _, m := range group.Members {
+		if m.OnMailingList() {
+			targets = append(targets, MailingListTarget{Email: m.ActivistEmail, Name: m.ActivistName})
+		}
+	}
+	return targets
+}
+
+// groupLeadTargets returns the leads and co-leads of groups. Groups
+// without any leads that have an email fall back to the group email,
+// which used to be the circle host's email.
+func groupLeadTargets(groups []Group) []MailingListTarget {
+	targets := []MailingListTarget{}
+	for _, g := range groups {
+		var leads []MailingListTarget
+		hasEmail := false
+		for _, m := range g.Members {
+			if m.IsLead() {
+				leads = append(leads, MailingListTarget{Email: m.ActivistEmail, Name: m.ActivistName})
+				hasEmail = hasEmail || strings.TrimSpace(m.ActivistEmail) != ""
+			}
+		}
+		if !hasEmail {
+			leads = append(leads, MailingListTarget{Email: g.GroupEmail, Name: g.Name})
+		}
+		targets = append(targets, leads...)
+	}
+	return targets
+}
+
+// ApplyMailingListOverrides returns the emails that should be on the
+// list: the targets' emails plus the always-included ones, minus the
+// always-excluded ones, sorted. Targets without emails are left out.
+func ApplyMailingListOverrides(list MailingList, targets []MailingListTarget) []string {
+	excluded := map[string]bool{}
+	for _, e := range list.AlwaysExclude {
+		excluded[strings.ToLower(strings.TrimSpace(e))] = true
+	}
+	seen := map[string]bool{}
+	emails := []string{}
+	add := func(e string) {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || excluded[e] || seen[e] {
+			return
+		}
+		seen[e] = true
+		emails = append(emails, e)
+	}
+	for _, t := range targets {
+		add(t.Email)
+	}
+	for _, e := range list.AlwaysInclude {
+		add(e)
+	}
+	sort.Strings(emails)
+	return emails
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupLeadTargets(t *testing.T) {
+	groups := []Group{
+		{Name: "Oakland", GroupEmail: "oakland@example.org", Members: []GroupMember{
+			{ActivistName: "Alice", ActivistEmail: "alice@example.org", Role: GroupRoleLead},
+			{ActivistName: "Bob", ActivistEmail: "bob@example.org", Role: GroupRoleCoLead},
+			{ActivistName: "Carol", ActivistEmail: "carol@example.org", Role: GroupRoleMember},
+		}},
+		// No leads with an email, so the group email stands in.
+		{Name: "Berkeley", GroupEmail: "berkeley@example.org", Members: []GroupMember{
+			{ActivistName: "Dan", Role: GroupRoleLead},
+		}},
+	}
+	require.Equal(t, []MailingListTarget{
+		{Email: "alice@example.org", Name: "Alice"},
+		{Email: "bob@example.org", Name: "Bob"},
+		{Name: "Dan"},
+		{Email: "berkeley@example.org", Name: "Berkeley"},
+	}, groupLeadTargets(groups))
+}
+
+func TestApplyMailingListOverrides(t *testing.T) {
+	list := MailingList{
+		AlwaysInclude: []string{"owner@example.org", "alice@example.org"},
+		AlwaysExclude: []string{"bob@example.org"},
+	}
+	targets := []MailingListTarget{
+		{Email: "Alice@example.org", Name: "Alice"},
+		{Email: "bob@example.org", Name: "Bob"},
+		{Name: "Carol"},
+	}
+	require.Equal(t, []string{"alice@example.org", "owner@example.org"}, ApplyMailingListOverrides(list, targets))
+}
+
+func TestMailingLists(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	_, err := db.Exec(`
+INSERT INTO activists (name, email, activist_level) VALUES
+  ('Alice', 'alice@example.org', 'Organizer'),
+  ('Bob', 'bob@example.org', 'Chapter Member'),
+  ('Carol', 'carol@example.org', 'Supporter')`)
+	require.NoError(t, err)
+
+	_, err = CleanMailingListData(db, 0, "members@example.org", "", "tag", "", "", "", DefaultMailingListGuardrails)
+	require.Error(t, err)
+
+	list, err := CleanMailingListData(db, 0, " Members@example.org ", "Chapter members", MailingListRuleActivistLevel,
+		"Organizer, Chapter Member", "owner@example.org", "bob@example.org\nBOB@example.org", MailingListGuardrails{MaxRemovals: 5})
+	require.NoError(t, err)
+	require.Equal(t, "members@example.org", list.Email)
+	require.Equal(t, []string{"bob@example.org"}, list.AlwaysExclude)
+	id, err := CreateMailingList(db, list)
+	require.NoError(t, err)
+
+	lists, err := GetMailingLists(db)
+	require.NoError(t, err)
+	require.Len(t, lists, 1)
+	require.Equal(t, id, lists[0].ID)
+	require.Equal(t, []string{"owner@example.org"}, lists[0].AlwaysInclude)
+	require.Equal(t, MailingListGuardrails{MaxRemovals: 5}, lists[0].MailingListGuardrails)
+
+	targets, err := GetMailingListTargets(db, lists[0])
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice@example.org", "owner@example.org"}, ApplyMailingListOverrides(lists[0], targets))
+
+	// Working groups' lists are synced from their members.
+	_, err = CreateGroup(db, Group{Name: "Tech", Type: GroupTypeWorkingGroup, GroupEmail: "tech@example.org"}, "")
+	require.NoError(t, err)
+	_, err = CleanMailingListData(db, 0, "tech@example.org", "", MailingListRuleManual, "", "", "", DefaultMailingListGuardrails)
+	require.Error(t, err)
+
+	require.NoError(t, DeleteMailingList(db, id))
+	lists, err = GetMailingLists(db)
+	require.NoError(t, err)
+	require.Len(t, lists, 0)
+}
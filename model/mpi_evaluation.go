@@ -0,0 +1,137 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dxe/adb/mpi"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Constant and Variable Definitions */
+
+const maxMPIEvaluationActivists = 5000
+
+/** Type Definitions */
+
+type MPIEvaluationRequest struct {
+	ActivistIDs []int `json:"activist_ids"`
+	// Formatted as YYYY-MM. Defaults to the current month.
+	Month string `json:"month"`
+}
+
+/** Functions and Methods */
+
+// CleanMPIEvaluationRequest parses a batch evaluation request and
+// returns the activist IDs and month, formatted as YYYYMM.
+func CleanMPIEvaluationRequest(body io.Reader, now time.Time) ([]int, int, error) {
+	var req MPIEvaluationRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, 0, err
+	}
+	if len(req.ActivistIDs) == 0 {
+		return nil, 0, errors.New("No activists to evaluate")
+	}
+	if len(req.ActivistIDs) > maxMPIEvaluationActivists {
+		return nil, 0, errors.Errorf("Cannot evaluate more than %d activists at once", maxMPIEvaluationActivists)
+	}
+	month := mpi.MonthOf(now)
+	if req.Month != "" {
+		m, err := parseMonth(req.Month)
+		if err != nil {
+			return nil, 0, err
+		}
+		month = m
+	}
+	return req.ActivistIDs, month, nil
+}
+
+// getMPIActivists loads what the MPI rules need to evaluate the
+// activists matching filter as of month.
+func getMPIActivists(db *sqlx.DB, filter string, filterArgs []interface{}, month int) ([]mpi.Activist, error) {
+	var rows []struct {
+		ID              int            `db:"id"`
+		Level           string         `db:"activist_level"`
+		CMApprovalEmail sql.NullString `db:"cm_approval_email"`
+		VotingAgreement bool           `db:"voting_agreement"`
+	}
+	query, args, err := sqlx.In(`
+SELECT id, activist_level, cm_approval_email, voting_agreement
+FROM activists a
+WHERE a.hidden = 0 AND `+filter+`
+ORDER BY a.id`, filterArgs...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build MPI activists query")
+	}
+	if err := db.Select(&rows, db.Rebind(query), args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select MPI activists")
+	}
+
+	var attendance []struct {
+		ActivistID   int  `db:"activist_id"`
+		Month        int  `db:"month"`
+		DirectAction bool `db:"direct_action"`
+		Community    bool `db:"community"`
+	}
+	// The rules look back at most 12 months.
+	query, args, err = sqlx.In(`
+SELECT
+  ea.activist_id,
+  extract(year_month from e.date) AS month,
+  max(IFNULL(et.mpi_category = ?, 0)) AS direct_action,
+  max(IFNULL(et.mpi_category = ?, 0)) AS community
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+JOIN activists a ON a.id = ea.activist_id
+LEFT JOIN event_types et ON et.name = e.event_type
+WHERE a.hidden = 0 AND `+filter+`
+  AND extract(year_month from e.date) BETWEEN ? AND ?
+GROUP BY ea.activist_id, month`,
+		append(append([]interface{}{MPICategoryDirectAction, MPICategoryCommunity}, filterArgs...),
+			mpi.AddMonths(month, -mpi.ChapterMemberVotingWindow), month)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build MPI attendance query")
+	}
+	if err := db.Select(&attendance, db.Rebind(query), args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select MPI attendance")
+	}
+
+	activists := make([]mpi.Activist, len(rows))
+	index := map[int]int{}
+	for i, r := range rows {
+		activists[i] = mpi.Activist{
+			ID:              r.ID,
+			Level:           r.Level,
+			CMApprovalEmail: r.CMApprovalEmail.String,
+			VotingAgreement: r.VotingAgreement,
+		}
+		index[r.ID] = i
+	}
+	for _, att := range attendance {
+		a := &activists[index[att.ActivistID]]
+		a.Attendance = append(a.Attendance, mpi.Month{
+			Month:        att.Month,
+			DirectAction: att.DirectAction,
+			Community:    att.Community,
+		})
+	}
+	return activists, nil
+}
+
+// EvaluateMPI applies the MPI and chapter member rules to the given
+// activists as of the start of month. Hidden and unknown activists
+// are left out.
+func EvaluateMPI(db *sqlx.DB, activistIDs []int, month int) ([]mpi.Evaluation, error) {
+	activists, err := getMPIActivists(db, "a.id IN (?)", []interface{}{activistIDs}, month)
+	if err != nil {
+		return nil, err
+	}
+	evaluations := []mpi.Evaluation{}
+	for _, a := range activists {
+		evaluations = append(evaluations, mpi.Evaluate(a, month))
+	}
+	return evaluations, nil
+}
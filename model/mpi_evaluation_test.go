@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateMPI(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activists SET activist_level = 'Organizer' WHERE id = ?`, a1.ID)
+	require.NoError(t, err)
+
+	for _, date := range []time.Time{
+		time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		_, err := InsertUpdateEvent(db, Event{
+			EventName:      "action",
+			EventDate:      date,
+			EventType:      "Action",
+			AddedAttendees: []Activist{a1},
+		})
+		require.NoError(t, err)
+	}
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "community",
+		EventDate:      time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC),
+		EventType:      "Community",
+		AddedAttendees: []Activist{a2},
+	})
+	require.NoError(t, err)
+
+	ids, month, err := CleanMPIEvaluationRequest(
+		strings.NewReader(fmt.Sprintf(`{"activist_ids": [%d, %d], "month": "2020-05"}`, a1.ID, a2.ID)), time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 202005, month)
+
+	evaluations, err := EvaluateMPI(db, ids, month)
+	require.NoError(t, err)
+	require.Len(t, evaluations, 2)
+
+	require.Equal(t, a1.ID, evaluations[0].ActivistID)
+	require.True(t, evaluations[0].MPI)
+	require.Equal(t, 2, evaluations[0].Past3)
+	require.True(t, evaluations[0].VotingEligible)
+
+	require.Equal(t, a2.ID, evaluations[1].ActivistID)
+	require.False(t, evaluations[1].MPI)
+	require.Equal(t, "Missing DA event", evaluations[1].Missing)
+
+	_, _, err = CleanMPIEvaluationRequest(strings.NewReader(`{"activist_ids": []}`), time.Now())
+	require.Error(t, err)
+}
@@ -1,43 +1,374 @@
 package model
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/dxe/adb/mpi"
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
 )
 
 /** Type Definitions */
 
-type PowerHist struct {
-	Month int
-	Year  int
-	Power int
+// MPISnapshot is the movement power index and related counts for a
+// month, recorded when the month closed. Backfilled snapshots were
+// reconstructed later from event attendance and activists_history,
+// so their level counts are approximate.
+type MPISnapshot struct {
+	// Month is formatted as YYYYMM, like MySQL's
+	// EXTRACT(YEAR_MONTH ...).
+	Month                int  `db:"month"`
+	MPI                  int  `db:"mpi"`
+	ActiveChapterMembers int  `db:"active_chapter_members"`
+	Backfilled           bool `db:"backfilled"`
+	// Number of activists at each level who had attended at least
+	// one event by the end of the month.
+	Levels map[string]int
+}
+
+type MPISnapshotJSON struct {
+	Month                string         `json:"month"`
+	MPI                  int            `json:"mpi"`
+	ActiveChapterMembers int            `json:"active_chapter_members"`
+	Backfilled           bool           `json:"backfilled"`
+	Levels               map[string]int `json:"levels"`
 }
 
 /** Functions and Methods */
 
-func GetPower(db *sqlx.DB) (string, error) {
-	query := `
-SELECT COUNT(id) AS movement_power_index
-FROM activists
-where mpi = 1
-`
-	var power string
-	if err := db.Get(&power, query); err != nil {
-		return "error", err
+// currentMPIEvaluations evaluates the MPI rules for the activists who
+// attended an event this month. Nobody else can be MPI for it.
+func currentMPIEvaluations(db *sqlx.DB) ([]mpi.Activist, []mpi.Evaluation, error) {
+	month := mpi.MonthOf(time.Now())
+	activists, err := getMPIActivists(db, `a.id IN (
+    SELECT ea.activist_id
+    FROM event_attendance ea
+    JOIN events e ON e.id = ea.event_id
+    WHERE extract(year_month from e.date) = ?)`, []interface{}{month}, month)
+	if err != nil {
+		return nil, nil, err
+	}
+	evaluations := make([]mpi.Evaluation, len(activists))
+	for i, a := range activists {
+		evaluations[i] = mpi.Evaluate(a, month)
+	}
+	return activists, evaluations, nil
+}
+
+// GetPower returns the number of activists who are MPI this month.
+func GetPower(db *sqlx.DB) (int, error) {
+	_, evaluations, err := currentMPIEvaluations(db)
+	if err != nil {
+		return 0, err
+	}
+	power := 0
+	for _, e := range evaluations {
+		if e.MPI {
+			power++
+		}
 	}
 	return power, nil
 }
 
-func GetActiveChapterMembers(db *sqlx.DB) (string, error) {
-	query := `
-SELECT
-	count(id) active_chapter_members
-FROM activists
-where mpi = 1
-and activist_level in ('chapter member','organizer')
-`
-	var members string
-	if err := db.Get(&members, query); err != nil {
-		return "error", err
+// GetActiveChapterMembers returns the number of chapter members and
+// organizers who are MPI this month.
+func GetActiveChapterMembers(db *sqlx.DB) (int, error) {
+	activists, evaluations, err := currentMPIEvaluations(db)
+	if err != nil {
+		return 0, err
+	}
+	members := 0
+	for i, e := range evaluations {
+		level := activists[i].Level
+		if e.MPI && (level == ACTIVIST_LEVEL_CHAPTER_MEMBER || level == "Organizer") {
+			members++
+		}
 	}
 	return members, nil
 }
+
+// GetActivistLevelCounts returns the number of activists at each
+// level.
+func GetActivistLevelCounts(db *sqlx.DB) (map[string]int, error) {
+	var rows []struct {
+		Level     string `db:"activist_level"`
+		Activists int    `db:"activists"`
+	}
+	err := db.Select(&rows, `
+SELECT activist_level, count(*) AS activists
+FROM activists
+WHERE hidden = 0
+GROUP BY activist_level`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count activists by level")
+	}
+	counts := map[string]int{}
+	for _, r := range rows {
+		counts[r.Level] = r.Activists
+	}
+	return counts, nil
+}
+
+// PreviousMonth returns the last month that has closed as of t,
+// formatted as YYYYMM.
+func PreviousMonth(t time.Time) int {
+	return mpi.AddMonths(mpi.MonthOf(t), -1)
+}
+
+func monthStart(month int) time.Time {
+	return time.Date(month/100, time.Month(month%100), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func nextMonth(month int) int {
+	return mpi.AddMonths(month, 1)
+}
+
+func formatMonth(month int) string {
+	return fmt.Sprintf("%04d-%02d", month/100, month%100)
+}
+
+func parseMonth(s string) (int, error) {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return 0, errors.Errorf("Not a valid month: %s", s)
+	}
+	return mpi.MonthOf(t), nil
+}
+
+// computeMPISnapshot computes a month's snapshot from event
+// attendance. Activist levels are the current ones, unless
+// reconstruct is set, in which case they're taken from the last
+// activists_history revision before the end of the month.
+func computeMPISnapshot(db *sqlx.DB, month int, reconstruct bool) (MPISnapshot, error) {
+	var attendance []struct {
+		ActivistID   int  `db:"activist_id"`
+		DirectAction bool `db:"direct_action"`
+		Community    bool `db:"community"`
+	}
+	err := db.Select(&attendance, `
+SELECT
+  ea.activist_id,
+  max(et.mpi_category = ?) AS direct_action,
+  max(et.mpi_category = ?) AS community
+FROM event_attendance ea
+JOIN events e ON e.id = ea.event_id
+JOIN event_types et ON et.name = e.event_type
+JOIN activists a ON a.id = ea.activist_id
+WHERE a.hidden = 0
+  AND extract(year_month from e.date) = ?
+GROUP BY ea.activist_id`, MPICategoryDirectAction, MPICategoryCommunity, month)
+	if err != nil {
+		return MPISnapshot{}, errors.Wrapf(err, "failed to select MPI attendance for %d", month)
+	}
+	var mpiIDs []int
+	for _, a := range attendance {
+		if mpi.Met(mpi.Month{Month: month, DirectAction: a.DirectAction, Community: a.Community}) {
+			mpiIDs = append(mpiIDs, a.ActivistID)
+		}
+	}
+
+	end := monthStart(nextMonth(month)).Format(EventDateLayout)
+	levelExpr := "a.activist_level"
+	var levelArgs []interface{}
+	if reconstruct {
+		levelExpr = `IFNULL((
+    SELECT h.activist_level
+    FROM activists_history h
+    WHERE h.activist_id = a.id AND h.timestamp < ?
+    ORDER BY h.timestamp DESC, h.revision DESC
+    LIMIT 1), a.activist_level)`
+		levelArgs = append(levelArgs, end)
+	}
+	var levels []struct {
+		ID    int    `db:"id"`
+		Level string `db:"activist_level"`
+	}
+	err = db.Select(&levels, `
+SELECT a.id, `+levelExpr+` AS activist_level
+FROM activists a
+WHERE a.hidden = 0
+  AND EXISTS (
+    SELECT 1
+    FROM event_attendance ea
+    JOIN events e ON e.id = ea.event_id
+    WHERE ea.activist_id = a.id AND e.date < ?)`, append(levelArgs, end)...)
+	if err != nil {
+		return MPISnapshot{}, errors.Wrapf(err, "failed to select activist levels for %d", month)
+	}
+
+	isMPI := map[int]bool{}
+	for _, id := range mpiIDs {
+		isMPI[id] = true
+	}
+	snapshot := MPISnapshot{
+		Month:      month,
+		MPI:        len(mpiIDs),
+		Backfilled: reconstruct,
+		Levels:     map[string]int{},
+	}
+	for _, l := range levels {
+		snapshot.Levels[l.Level]++
+		if isMPI[l.ID] && (l.Level == ACTIVIST_LEVEL_CHAPTER_MEMBER || l.Level == "Organizer") {
+			snapshot.ActiveChapterMembers++
+		}
+	}
+	return snapshot, nil
+}
+
+func saveMPISnapshot(db *sqlx.DB, snapshot MPISnapshot) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.Wrap(err, "failed to create transaction")
+	}
+	_, err = tx.NamedExec(`
+REPLACE INTO mpi_snapshots (month, mpi, active_chapter_members, backfilled)
+VALUES (:month, :mpi, :active_chapter_members, :backfilled)`, snapshot)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to save MPI snapshot for %d", snapshot.Month)
+	}
+	if _, err := tx.Exec(`DELETE FROM mpi_snapshot_levels WHERE month = ?`, snapshot.Month); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to delete level counts for %d", snapshot.Month)
+	}
+	for level, count := range snapshot.Levels {
+		_, err := tx.Exec(`
+INSERT INTO mpi_snapshot_levels (month, activist_level, activists)
+VALUES (?, ?, ?)`, snapshot.Month, level, count)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "failed to save level counts for %d", snapshot.Month)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrapf(err, "failed to commit MPI snapshot for %d", snapshot.Month)
+	}
+	return nil
+}
+
+func hasMPISnapshot(db *sqlx.DB, month int) (bool, error) {
+	var count int
+	if err := db.Get(&count, `SELECT count(*) FROM mpi_snapshots WHERE month = ?`, month); err != nil {
+		return false, errors.Wrapf(err, "failed to check for MPI snapshot %d", month)
+	}
+	return count != 0, nil
+}
+
+// RecordMPISnapshot records the snapshot for a month that just closed,
+// unless it has already been recorded.
+func RecordMPISnapshot(db *sqlx.DB, month int) (bool, error) {
+	exists, err := hasMPISnapshot(db, month)
+	if err != nil || exists {
+		return false, err
+	}
+	snapshot, err := computeMPISnapshot(db, month, false)
+	if err != nil {
+		return false, err
+	}
+	if err := saveMPISnapshot(db, snapshot); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BackfillMPISnapshots reconstructs the snapshots of every month from
+// the first recorded event up to and including the given month that
+// doesn't have one yet. It returns the number of months backfilled.
+func BackfillMPISnapshots(db *sqlx.DB, through int) (int, error) {
+	var first int
+	err := db.Get(&first, `SELECT IFNULL(min(extract(year_month from date)), 0) FROM events`)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get first event month")
+	}
+	if first == 0 {
+		return 0, nil
+	}
+
+	var existing []int
+	if err := db.Select(&existing, `SELECT month FROM mpi_snapshots`); err != nil {
+		return 0, errors.Wrap(err, "failed to select MPI snapshot months")
+	}
+	recorded := map[int]bool{}
+	for _, m := range existing {
+		recorded[m] = true
+	}
+
+	n := 0
+	for month := first; month <= through; month = nextMonth(month) {
+		if recorded[month] {
+			continue
+		}
+		snapshot, err := computeMPISnapshot(db, month, true)
+		if err != nil {
+			return n, err
+		}
+		if err := saveMPISnapshot(db, snapshot); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// GetMPISnapshotsJSON returns the snapshots between two months,
+// formatted as YYYY-MM, in order. Either month may be empty.
+func GetMPISnapshotsJSON(db *sqlx.DB, from, to string) ([]MPISnapshotJSON, error) {
+	query := `SELECT month, mpi, active_chapter_members, backfilled FROM mpi_snapshots WHERE 1 = 1`
+	var args []interface{}
+	if from != "" {
+		m, err := parseMonth(from)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND month >= ?`
+		args = append(args, m)
+	}
+	if to != "" {
+		m, err := parseMonth(to)
+		if err != nil {
+			return nil, err
+		}
+		query += ` AND month <= ?`
+		args = append(args, m)
+	}
+	query += ` ORDER BY month`
+
+	var snapshots []MPISnapshot
+	if err := db.Select(&snapshots, query, args...); err != nil {
+		return nil, errors.Wrap(err, "failed to select MPI snapshots")
+	}
+
+	var levels []struct {
+		Month     int    `db:"month"`
+		Level     string `db:"activist_level"`
+		Activists int    `db:"activists"`
+	}
+	if err := db.Select(&levels, `SELECT month, activist_level, activists FROM mpi_snapshot_levels`); err != nil {
+		return nil, errors.Wrap(err, "failed to select MPI snapshot levels")
+	}
+	levelsByMonth := map[int]map[string]int{}
+	for _, l := range levels {
+		if levelsByMonth[l.Month] == nil {
+			levelsByMonth[l.Month] = map[string]int{}
+		}
+		levelsByMonth[l.Month][l.Level] = l.Activists
+	}
+
+	snapshotsJSON := []MPISnapshotJSON{}
+	for _, s := range snapshots {
+		monthLevels := levelsByMonth[s.Month]
+		if monthLevels == nil {
+			monthLevels = map[string]int{}
+		}
+		snapshotsJSON = append(snapshotsJSON, MPISnapshotJSON{
+			Month:                formatMonth(s.Month),
+			MPI:                  s.MPI,
+			ActiveChapterMembers: s.ActiveChapterMembers,
+			Backfilled:           s.Backfilled,
+			Levels:               monthLevels,
+		})
+	}
+	return snapshotsJSON, nil
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviousMonth(t *testing.T) {
+	require.Equal(t, 202012, PreviousMonth(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, 202002, PreviousMonth(time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestMPISnapshots(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE activists SET activist_level = 'Organizer' WHERE id = ?`, a1.ID)
+	require.NoError(t, err)
+
+	// In 2019, both a direct action and a community event were
+	// needed. Only Hello went to both.
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "action",
+		EventDate:      time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a1, a2},
+	})
+	require.NoError(t, err)
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "community",
+		EventDate:      time.Date(2019, 12, 8, 0, 0, 0, 0, time.UTC),
+		EventType:      "Community",
+		AddedAttendees: []Activist{a1},
+	})
+	require.NoError(t, err)
+	// Since 2020, a direct action is enough.
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "action",
+		EventDate:      time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC),
+		EventType:      "Action",
+		AddedAttendees: []Activist{a2},
+	})
+	require.NoError(t, err)
+
+	recorded, err := RecordMPISnapshot(db, 202002)
+	require.NoError(t, err)
+	require.True(t, recorded)
+	recorded, err = RecordMPISnapshot(db, 202002)
+	require.NoError(t, err)
+	require.False(t, recorded)
+
+	n, err := BackfillMPISnapshots(db, 202002)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	snapshots, err := GetMPISnapshotsJSON(db, "", "")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 3)
+
+	require.Equal(t, "2019-12", snapshots[0].Month)
+	require.Equal(t, 1, snapshots[0].MPI)
+	require.Equal(t, 1, snapshots[0].ActiveChapterMembers)
+	require.True(t, snapshots[0].Backfilled)
+	require.Equal(t, map[string]int{"Organizer": 1, "Supporter": 1}, snapshots[0].Levels)
+
+	require.Equal(t, "2020-01", snapshots[1].Month)
+	require.Equal(t, 0, snapshots[1].MPI)
+
+	require.Equal(t, "2020-02", snapshots[2].Month)
+	require.Equal(t, 1, snapshots[2].MPI)
+	require.Equal(t, 0, snapshots[2].ActiveChapterMembers)
+	require.False(t, snapshots[2].Backfilled)
+
+	snapshots, err = GetMPISnapshotsJSON(db, "2020-01", "2020-01")
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// PublicGroup is the information about a visible group that's
+// published for our website.
+type PublicGroup struct {
+	ID              int      `json:"id"`
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Description     string   `json:"description"`
+	MeetingTime     string   `json:"meeting_time"`
+	MeetingLocation string   `json:"meeting_location"`
+	Lat             *float64 `json:"lat"`
+	Lng             *float64 `json:"lng"`
+	// First names of the group's leads and co-leads.
+	Leads []string `json:"leads"`
+	// In miles, if the groups were filtered by distance.
+	Distance *float64 `json:"distance,omitempty"`
+}
+
+type PublicGroupOptions struct {
+	// Limits the groups to these types. Empty means all types.
+	Types []string
+	// If Near is set, only groups within RadiusMiles of Lat and Lng
+	// are returned, closest first.
+	Near        bool
+	Lat         float64
+	Lng         float64
+	RadiusMiles float64
+}
+
+/** Functions and Methods */
+
+// GetPublicGroups returns the visible groups that aren't archived.
+func GetPublicGroups(db *sqlx.DB, options PublicGroupOptions) ([]PublicGroup, error) {
+	for _, t := range options.Types {
+		if !isGroupType(t) {
+			return nil, errors.Errorf("Group type doesn't exist: %s", t)
+		}
+	}
+	if options.Near && options.RadiusMiles <= 0 {
+		return nil, errors.New("Radius must be positive")
+	}
+	groups, err := GetGroups(db, GroupQueryOptions{Types: options.Types})
+	if err != nil {
+		return nil, err
+	}
+	return publicGroups(groups, options), nil
+}
+
+func publicGroups(groups []Group, options PublicGroupOptions) []PublicGroup {
+	out := []PublicGroup{}
+	for _, g := range groups {
+		if !g.Visible {
+			continue
+		}
+		pg := PublicGroup{
+			ID:              g.ID,
+			Name:            g.Name,
+			Type:            g.Type,
+			Description:     g.Description,
+			MeetingTime:     g.MeetingTime,
+			MeetingLocation: g.MeetingLocation,
+			Leads:           []string{},
+		}
+		if g.Lat.Valid && g.Lng.Valid {
+			lat, lng := g.Lat.Float64, g.Lng.Float64
+			pg.Lat, pg.Lng = &lat, &lng
+		}
+		if options.Near {
+			if pg.Lat == nil {
+				continue
+			}
+			d := distanceMiles(options.Lat, options.Lng, *pg.Lat, *pg.Lng)
+			if d > options.RadiusMiles {
+				continue
+			}
+			pg.Distance = &d
+		}
+		for _, m := range g.Members {
+			if !m.IsLead() {
+				continue
+			}
+			if fields := strings.Fields(m.ActivistName); len(fields) != 0 {
+				pg.Leads = append(pg.Leads, fields[0])
+			}
+		}
+		out = append(out, pg)
+	}
+	if options.Near {
+		sort.SliceStable(out, func(i, j int) bool {
+			return *out[i].Distance < *out[j].Distance
+		})
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicGroups(t *testing.T) {
+	coord := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	groups := []Group{
+		{
+			ID:      1,
+			Name:    "San Francisco",
+			Type:    GroupTypeCircle,
+			Visible: true,
+			Lat:     coord(37.7749),
+			Lng:     coord(-122.4194),
+			Members: []GroupMember{
+				{ActivistName: "Alice Smith", Role: GroupRoleLead},
+				{ActivistName: "Bob Jones", Role: GroupRoleMember},
+			},
+		},
+		{ID: 2, Name: "Hidden", Type: GroupTypeCircle, Lat: coord(37.8), Lng: coord(-122.3)},
+		{ID: 3, Name: "Berkeley", Type: GroupTypeCircle, Visible: true, Lat: coord(37.8716), Lng: coord(-122.2727)},
+		{ID: 4, Name: "Tech", Type: GroupTypeWorkingGroup, Visible: true},
+		{ID: 5, Name: "Los Angeles", Type: GroupTypeCircle, Visible: true, Lat: coord(34.0522), Lng: coord(-118.2437)},
+	}
+	ids := func(groups []PublicGroup) []int {
+		var out []int
+		for _, g := range groups {
+			out = append(out, g.ID)
+		}
+		return out
+	}
+
+	all := publicGroups(groups, PublicGroupOptions{})
+	require.Equal(t, []int{1, 3, 4, 5}, ids(all))
+	require.Equal(t, []string{"Alice"}, all[0].Leads)
+	require.Nil(t, all[0].Distance)
+	require.Nil(t, all[2].Lat)
+
+	// From Oakland.
+	near := publicGroups(groups, PublicGroupOptions{Near: true, Lat: 37.8044, Lng: -122.2712, RadiusMiles: 25})
+	require.Equal(t, []int{3, 1}, ids(near))
+	require.InDelta(t, 4.6, *near[0].Distance, 0.1)
+}
@@ -0,0 +1,171 @@
+package model
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+/** Type Definitions */
+
+// WallboardToken lets a display, like the TV in the office, read the
+// wallboard without logging in. Tokens don't expire; they're deleted
+// when the display is retired.
+type WallboardToken struct {
+	Token string `db:"token"`
+	Name  string `db:"name"`
+	// Chapter whose upcoming Facebook events are shown. 0 shows
+	// online events.
+	PageID  int       `db:"page_id"`
+	Created time.Time `db:"created"`
+}
+
+type WallboardLeader struct {
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+}
+
+type WallboardEvent struct {
+	Name         string `json:"name"`
+	StartTime    string `json:"start_time"`
+	LocationName string `json:"location_name"`
+}
+
+type WallboardData struct {
+	MPI                  int               `json:"mpi"`
+	ActiveChapterMembers int               `json:"active_chapter_members"`
+	EventsThisWeek       int               `json:"events_this_week"`
+	Leaderboard          []WallboardLeader `json:"leaderboard"`
+	UpcomingEvents       []WallboardEvent  `json:"upcoming_events"`
+}
+
+const (
+	wallboardLeaderboardSize = 10
+	wallboardUpcomingEvents  = 5
+)
+
+/** Functions and Methods */
+
+func GetWallboardTokens(db *sqlx.DB) ([]WallboardToken, error) {
+	var tokens []WallboardToken
+	err := db.Select(&tokens, `
+SELECT token, name, page_id, created
+FROM wallboard_tokens
+ORDER BY name`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select wallboard tokens")
+	}
+	return tokens, nil
+}
+
+func GetWallboardToken(db *sqlx.DB, token string) (WallboardToken, error) {
+	var t WallboardToken
+	err := db.Get(&t, `
+SELECT token, name, page_id, created
+FROM wallboard_tokens
+WHERE token = ?`, token)
+	if err == sql.ErrNoRows {
+		return WallboardToken{}, errors.New("Wallboard token not found")
+	}
+	if err != nil {
+		return WallboardToken{}, errors.Wrap(err, "failed to select wallboard token")
+	}
+	return t, nil
+}
+
+func CreateWallboardToken(db *sqlx.DB, name string, pageID int) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("Name cannot be empty")
+	}
+	if err := checkForDangerousChars(name); err != nil {
+		return "", err
+	}
+	token, err := newAccessToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.Exec(`
+INSERT INTO wallboard_tokens (token, name, page_id)
+VALUES (?, ?, ?)`, token, name, pageID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to insert wallboard token")
+	}
+	return token, nil
+}
+
+func DeleteWallboardToken(db *sqlx.DB, token string) error {
+	if _, err := db.Exec(`DELETE FROM wallboard_tokens WHERE token = ?`, token); err != nil {
+		return errors.Wrap(err, "failed to delete wallboard token")
+	}
+	return nil
+}
+
+// GetWallboardData returns the numbers shown on the wallboard as of
+// now. Upcoming events are those of the given chapter, or online
+// events if pageID is 0.
+func GetWallboardData(db *sqlx.DB, pageID int, now time.Time) (WallboardData, error) {
+	var data WallboardData
+	var err error
+	if data.MPI, err = GetPower(db); err != nil {
+		return WallboardData{}, errors.Wrap(err, "failed to get MPI")
+	}
+	if data.ActiveChapterMembers, err = GetActiveChapterMembers(db); err != nil {
+		return WallboardData{}, errors.Wrap(err, "failed to get active chapter members")
+	}
+
+	// Weeks start on Monday.
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	err = db.Get(&data.EventsThisWeek, `
+SELECT count(*)
+FROM events
+WHERE date >= ? AND date < ?`,
+		weekStart.Format(EventDateLayout), weekStart.AddDate(0, 0, 7).Format(EventDateLayout))
+	if err != nil {
+		return WallboardData{}, errors.Wrap(err, "failed to count events this week")
+	}
+
+	leaderboard, err := GetLeaderboard(db, LeaderboardOptions{
+		Window: defaultLeaderboardWindow,
+		Limit:  wallboardLeaderboardSize,
+	}, now)
+	if err != nil {
+		return WallboardData{}, err
+	}
+	data.Leaderboard = []WallboardLeader{}
+	for _, e := range leaderboard {
+		data.Leaderboard = append(data.Leaderboard, WallboardLeader{
+			Name:   e.Name,
+			Points: int(math.Round(e.Points)),
+		})
+	}
+
+	startTime := now.UTC().Format("2006-01-02T15:04:05")
+	var events []FacebookEventOutput
+	if pageID != 0 {
+		events, err = GetFacebookEvents(db, pageID, startTime, "", false)
+	} else {
+		events, err = GetOnlineFacebookEvents(db, startTime, "", false)
+	}
+	if err != nil {
+		return WallboardData{}, errors.Wrap(err, "failed to select upcoming events")
+	}
+	data.UpcomingEvents = []WallboardEvent{}
+	for _, e := range events {
+		if len(data.UpcomingEvents) == wallboardUpcomingEvents {
+			break
+		}
+		data.UpcomingEvents = append(data.UpcomingEvents, WallboardEvent{
+			Name:         e.Name,
+			StartTime:    e.StartTime.Format(time.RFC3339),
+			LocationName: e.LocationName,
+		})
+	}
+
+	return data, nil
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWallboardTokens(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	token, err := CreateWallboardToken(db, "Office TV", 0)
+	require.NoError(t, err)
+	_, err = CreateWallboardToken(db, " ", 0)
+	require.Error(t, err)
+
+	wallboard, err := GetWallboardToken(db, token)
+	require.NoError(t, err)
+	require.Equal(t, "Office TV", wallboard.Name)
+
+	require.NoError(t, DeleteWallboardToken(db, token))
+	_, err = GetWallboardToken(db, token)
+	require.Error(t, err)
+}
+
+func TestGetWallboardData(t *testing.T) {
+	db := newTestDB()
+	defer db.Close()
+
+	a1, err := GetOrCreateActivist(db, "Hello")
+	require.NoError(t, err)
+	a2, err := GetOrCreateActivist(db, "Hi")
+	require.NoError(t, err)
+
+	// A Wednesday.
+	now := time.Date(2020, 5, 6, 12, 0, 0, 0, time.UTC)
+	for _, date := range []time.Time{
+		time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 5, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC),
+	} {
+		_, err := InsertUpdateEvent(db, Event{
+			EventName:      "event",
+			EventDate:      date,
+			EventType:      "Action",
+			AddedAttendees: []Activist{a1},
+		})
+		require.NoError(t, err)
+	}
+	_, err = InsertUpdateEvent(db, Event{
+		EventName:      "event",
+		EventDate:      time.Date(2020, 5, 5, 0, 0, 0, 0, time.UTC),
+		EventType:      "Community",
+		AddedAttendees: []Activist{a2},
+	})
+	require.NoError(t, err)
+
+	data, err := GetWallboardData(db, 0, now)
+	require.NoError(t, err)
+	require.Equal(t, 3, data.EventsThisWeek)
+	require.Equal(t, []WallboardLeader{{Name: "Hello", Points: 3}, {Name: "Hi", Points: 1}}, data.Leaderboard)
+	require.Len(t, data.UpcomingEvents, 0)
+}
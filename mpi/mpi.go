@@ -0,0 +1,178 @@
+// Package mpi implements the Movement Power Index (MPI) and voting
+// rules. It doesn't access the database; callers load attendance and
+// pass it in.
+//
+// Months are formatted as YYYYMM, like MySQL's EXTRACT(YEAR_MONTH ...).
+package mpi
+
+import (
+	"time"
+)
+
+// MPI categories of event types.
+const (
+	CategoryDirectAction = "direct_action"
+	CategoryCommunity    = "community"
+	CategoryNone         = "none"
+)
+
+// Since January 2020, attending a direct action is enough to be MPI
+// for the month. Before that, a community event was also required.
+const CommunityOptionalFrom = 202001
+
+// What's missing for a month to count towards MPI. These are shown
+// in the activist list as is.
+const (
+	Fulfilled           = "Fulfilling requirements"
+	MissingCommunity    = "Missing Community event"
+	MissingDirectAction = "Missing DA event"
+	MissingBoth         = "Missing Community & DA events"
+)
+
+// Voting rules. Organizers need MPI in OrganizerVotingMinMonths of the
+// past OrganizerVotingWindow full months. Chapter members need to have
+// been approved for ChapterMemberVotingApprovedMonths full months,
+// MPI in ChapterMemberVotingMinMonths of the past
+// ChapterMemberVotingWindow full months, and a signed voting
+// agreement.
+const (
+	OrganizerVotingMinMonths          = 2
+	OrganizerVotingWindow             = 3
+	ChapterMemberVotingApprovedMonths = 6
+	ChapterMemberVotingMinMonths      = 8
+	ChapterMemberVotingWindow         = 12
+)
+
+const (
+	levelChapterMember = "Chapter Member"
+	levelOrganizer     = "Organizer"
+	approvalDateLayout = "2006-01-02"
+)
+
+// Month is which kinds of events an activist attended in a month.
+type Month struct {
+	Month        int  `json:"month"`
+	DirectAction bool `json:"direct_action"`
+	Community    bool `json:"community"`
+}
+
+// Activist is what the rules need to know about an activist.
+type Activist struct {
+	ID    int
+	Level string
+	// Date the chapter member approval email was sent, formatted as
+	// YYYY-MM-DD, or empty if it wasn't.
+	CMApprovalEmail string
+	VotingAgreement bool
+	// Months with attendance, in any order. Months without any
+	// attendance can be left out.
+	Attendance []Month
+}
+
+// Evaluation is the result of the rules for an activist as of the
+// start of a month. MPI and Missing are about the month itself (which
+// may still be in progress); the rest are about the full months
+// before it.
+type Evaluation struct {
+	ActivistID int    `json:"activist_id"`
+	Month      int    `json:"month"`
+	MPI        bool   `json:"mpi"`
+	Missing    string `json:"missing"`
+	// Months with MPI among the past 3 and 12 full months.
+	Past3  int `json:"past_3"`
+	Past12 int `json:"past_12"`
+	// Whether the activist was an approved chapter member for the
+	// past 6 full months.
+	Approved6      bool `json:"approved_6"`
+	VotingEligible bool `json:"voting_eligible"`
+}
+
+// MonthOf returns t's month.
+func MonthOf(t time.Time) int {
+	return t.Year()*100 + int(t.Month())
+}
+
+// AddMonths returns the month n months after month. n may be
+// negative.
+func AddMonths(month, n int) int {
+	t := time.Date(month/100, time.Month(month%100), 1, 0, 0, 0, 0, time.UTC)
+	return MonthOf(t.AddDate(0, n, 0))
+}
+
+// Met returns whether attendance in a month counts towards MPI.
+func Met(m Month) bool {
+	return m.DirectAction && (m.Community || m.Month >= CommunityOptionalFrom)
+}
+
+// Requirements returns what's missing for a month to count towards
+// MPI, or Fulfilled.
+func Requirements(m Month) string {
+	if Met(m) {
+		return Fulfilled
+	}
+	if m.Month >= CommunityOptionalFrom || m.Community {
+		return MissingDirectAction
+	}
+	if m.DirectAction {
+		return MissingCommunity
+	}
+	return MissingBoth
+}
+
+// countMet returns the number of months with MPI among the n months
+// before month.
+func countMet(byMonth map[int]Month, month, n int) int {
+	count := 0
+	for m := AddMonths(month, -n); m < month; m = AddMonths(m, 1) {
+		if Met(byMonth[m]) {
+			count++
+		}
+	}
+	return count
+}
+
+// MetMonths returns the number of months with MPI among the n full
+// months before month.
+func MetMonths(a Activist, month, n int) int {
+	byMonth := map[int]Month{}
+	for _, m := range a.Attendance {
+		byMonth[m.Month] = m
+	}
+	return countMet(byMonth, month, n)
+}
+
+// Evaluate applies the rules to an activist as of the start of month.
+func Evaluate(a Activist, month int) Evaluation {
+	byMonth := map[int]Month{}
+	for _, m := range a.Attendance {
+		byMonth[m.Month] = m
+	}
+	current, ok := byMonth[month]
+	if !ok {
+		current = Month{Month: month}
+	}
+
+	e := Evaluation{
+		ActivistID: a.ID,
+		Month:      month,
+		MPI:        Met(current),
+		Missing:    Requirements(current),
+		Past3:      countMet(byMonth, month, 3),
+		Past12:     countMet(byMonth, month, 12),
+	}
+
+	if approved, err := time.Parse(approvalDateLayout, a.CMApprovalEmail); err == nil {
+		// Approved before the first of the month 6 months ago.
+		e.Approved6 = MonthOf(approved) < AddMonths(month, -ChapterMemberVotingApprovedMonths)
+	}
+
+	switch a.Level {
+	case levelOrganizer:
+		e.VotingEligible = countMet(byMonth, month, OrganizerVotingWindow) >= OrganizerVotingMinMonths
+	case levelChapterMember:
+		e.VotingEligible = e.Approved6 &&
+			countMet(byMonth, month, ChapterMemberVotingWindow) >= ChapterMemberVotingMinMonths &&
+			a.VotingAgreement
+	}
+	return e
+}
@@ -0,0 +1,137 @@
+package mpi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequirements(t *testing.T) {
+	for _, tc := range []struct {
+		month Month
+		met   bool
+		want  string
+	}{
+		{Month{Month: 201912, DirectAction: true, Community: true}, true, Fulfilled},
+		{Month{Month: 201912, DirectAction: true}, false, MissingCommunity},
+		{Month{Month: 201912, Community: true}, false, MissingDirectAction},
+		{Month{Month: 201912}, false, MissingBoth},
+		{Month{Month: 202001, DirectAction: true}, true, Fulfilled},
+		{Month{Month: 202001, Community: true}, false, MissingDirectAction},
+		{Month{Month: 202001}, false, MissingDirectAction},
+	} {
+		require.Equal(t, tc.met, Met(tc.month), "%+v", tc.month)
+		require.Equal(t, tc.want, Requirements(tc.month), "%+v", tc.month)
+	}
+}
+
+func TestAddMonths(t *testing.T) {
+	require.Equal(t, 202101, AddMonths(202012, 1))
+	require.Equal(t, 201912, AddMonths(202012, -12))
+	require.Equal(t, 202003, AddMonths(202003, 0))
+}
+
+// mpiMonths returns attendance with MPI in each of the given months.
+func mpiMonths(months ...int) []Month {
+	var attendance []Month
+	for _, m := range months {
+		attendance = append(attendance, Month{Month: m, DirectAction: true, Community: true})
+	}
+	return attendance
+}
+
+func TestEvaluate(t *testing.T) {
+	twelveMonths := mpiMonths(202001, 202002, 202003, 202004, 202005, 202006, 202007, 202008, 202009, 202010, 202011, 202012)
+
+	for _, tc := range []struct {
+		name     string
+		activist Activist
+		month    int
+		want     Evaluation
+	}{{
+		name:     "supporter never votes",
+		activist: Activist{Level: "Supporter", Attendance: twelveMonths},
+		month:    202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 3, Past12: 12,
+		},
+	}, {
+		name:     "organizer with 2 of the past 3 months",
+		activist: Activist{Level: "Organizer", Attendance: mpiMonths(202010, 202012, 202101)},
+		month:    202101,
+		want: Evaluation{
+			Month: 202101, MPI: true, Missing: Fulfilled,
+			Past3: 2, Past12: 2, VotingEligible: true,
+		},
+	}, {
+		name:     "organizer with 1 of the past 3 months",
+		activist: Activist{Level: "Organizer", Attendance: mpiMonths(202009, 202012)},
+		month:    202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 1, Past12: 2,
+		},
+	}, {
+		name: "chapter member meeting every voting rule",
+		activist: Activist{
+			Level: "Chapter Member", CMApprovalEmail: "2020-06-30",
+			VotingAgreement: true, Attendance: twelveMonths,
+		},
+		month: 202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 3, Past12: 12, Approved6: true,
+			VotingEligible: true,
+		},
+	}, {
+		name: "chapter member approved less than 6 months ago",
+		activist: Activist{
+			Level: "Chapter Member", CMApprovalEmail: "2020-07-01",
+			VotingAgreement: true, Attendance: twelveMonths,
+		},
+		month: 202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 3, Past12: 12,
+		},
+	}, {
+		name: "chapter member without a voting agreement",
+		activist: Activist{
+			Level: "Chapter Member", CMApprovalEmail: "2020-01-01",
+			Attendance: twelveMonths,
+		},
+		month: 202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 3, Past12: 12, Approved6: true,
+		},
+	}, {
+		name: "chapter member with 7 of the past 12 months",
+		activist: Activist{
+			Level: "Chapter Member", CMApprovalEmail: "2020-01-01", VotingAgreement: true,
+			Attendance: mpiMonths(202006, 202007, 202008, 202009, 202010, 202011, 202012),
+		},
+		month: 202101,
+		want: Evaluation{
+			Month: 202101, Missing: MissingDirectAction,
+			Past3: 3, Past12: 7, Approved6: true,
+		},
+	}, {
+		name: "direct action alone wasn't enough before 2020",
+		activist: Activist{Level: "Chapter Member", Attendance: []Month{
+			{Month: 201910, DirectAction: true},
+			{Month: 201911, DirectAction: true},
+			{Month: 201912, DirectAction: true, Community: true},
+		}},
+		month: 202001,
+		want: Evaluation{
+			Month: 202001, Missing: MissingDirectAction,
+			Past3: 1, Past12: 1,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, Evaluate(tc.activist, tc.month))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package mpi_snapshots
+
+import (
+	"log"
+	"time"
+
+	"github.com/dxe/adb/model"
+	"github.com/jmoiron/sqlx"
+)
+
+func recordPreviousMonth(db *sqlx.DB) {
+	month := model.PreviousMonth(time.Now())
+	recorded, err := model.RecordMPISnapshot(db, month)
+	if err != nil {
+		log.Println("ERROR: failed to record MPI snapshot:", err)
+		return
+	}
+	if recorded {
+		log.Printf("Recorded MPI snapshot for %d", month)
+	}
+}
+
+// Records the MPI snapshot of the previous month once it has closed,
+// checking every hour. Should be run in a goroutine.
+func StartMPISnapshots(db *sqlx.DB) {
+	for {
+		log.Println("Starting MPI snapshot")
+		recordPreviousMonth(db)
+		log.Println("Finished MPI snapshot")
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,13 @@
+-- After running this, backfill past changes with POST /level_changes/backfill.
+CREATE TABLE activist_level_changes (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  activist_id INTEGER NOT NULL,
+  -- Empty if the previous level is unknown.
+  old_level VARCHAR(40) NOT NULL DEFAULT '',
+  new_level VARCHAR(40) NOT NULL,
+  changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- update, backfill_history or backfill_date_organizer.
+  source VARCHAR(30) NOT NULL,
+  user_email VARCHAR(80) NOT NULL DEFAULT '',
+  INDEX (activist_id)
+);
@@ -0,0 +1,21 @@
+CREATE TABLE archived_events (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  -- The id the event had in the events table.
+  event_id INTEGER NOT NULL,
+  name VARCHAR(60) NOT NULL,
+  date DATE NOT NULL,
+  event_type VARCHAR(60) NOT NULL,
+  survey_sent TINYINT(1) NOT NULL DEFAULT '0',
+  archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  archived_by_user_id INTEGER NOT NULL,
+  archived_by_email VARCHAR(80) NOT NULL,
+  INDEX (event_id),
+  INDEX (archived_at)
+);
+
+CREATE TABLE archived_event_attendance (
+  archived_event_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  UNIQUE (archived_event_id, activist_id),
+  INDEX (activist_id)
+);
@@ -0,0 +1,4 @@
+-- Logs role changes along with attendance changes.
+
+ALTER TABLE event_attendance_log
+  ADD COLUMN role VARCHAR(40) NOT NULL DEFAULT '' AFTER action;
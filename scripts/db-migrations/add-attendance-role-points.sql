@@ -0,0 +1,5 @@
+ALTER TABLE attendance_roles
+  ADD COLUMN points INTEGER NOT NULL DEFAULT '0';
+
+UPDATE attendance_roles SET points = 2 WHERE name = 'Host';
+UPDATE attendance_roles SET points = 1 WHERE name IN ('Marshal', 'Police Liaison', 'Speaker');
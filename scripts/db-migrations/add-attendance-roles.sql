@@ -0,0 +1,18 @@
+ALTER TABLE event_attendance
+  ADD COLUMN role VARCHAR(40) NOT NULL DEFAULT '';
+
+ALTER TABLE archived_event_attendance
+  ADD COLUMN role VARCHAR(40) NOT NULL DEFAULT '';
+
+CREATE TABLE attendance_roles (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(40) NOT NULL,
+  UNIQUE (name)
+);
+
+INSERT INTO attendance_roles (name) VALUES
+  ('Host'),
+  ('Marshal'),
+  ('Police Liaison'),
+  ('Speaker'),
+  ('Volunteer');
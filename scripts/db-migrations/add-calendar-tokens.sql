@@ -0,0 +1,8 @@
+CREATE TABLE calendar_tokens (
+  token VARCHAR(64) PRIMARY KEY,
+  -- Exactly one of adb_user_id and activist_id is set.
+  adb_user_id INTEGER NOT NULL DEFAULT '0',
+  activist_id INTEGER NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (adb_user_id, activist_id)
+);
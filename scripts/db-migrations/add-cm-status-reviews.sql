@@ -0,0 +1,26 @@
+CREATE TABLE cm_status_reviews (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  -- Formatted as YYYYMM.
+  month INTEGER NOT NULL,
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (month)
+);
+
+CREATE TABLE cm_status_review_items (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  review_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  -- at_risk, warn or demote.
+  status VARCHAR(20) NOT NULL,
+  past_3 INTEGER NOT NULL,
+  current_level VARCHAR(40) NOT NULL,
+  -- Set for demote; only applied once an organizer approves it.
+  proposed_level VARCHAR(40) NOT NULL DEFAULT '',
+  warning_sent TINYINT(1) NOT NULL DEFAULT '0',
+  -- '', approved or rejected.
+  decision VARCHAR(20) NOT NULL DEFAULT '',
+  decided_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  decided_at TIMESTAMP NULL DEFAULT NULL,
+  UNIQUE (review_id, activist_id),
+  INDEX (activist_id)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE event_attendance_log (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  event_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  -- 'add' or 'remove'.
+  action VARCHAR(10) NOT NULL,
+  -- 'editor', 'import', 'merge', 'delete' or 'restore'.
+  source VARCHAR(20) NOT NULL,
+  user_id INTEGER NOT NULL,
+  user_email VARCHAR(80) NOT NULL,
+  timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX (event_id),
+  INDEX (activist_id)
+);
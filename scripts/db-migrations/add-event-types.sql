@@ -0,0 +1,26 @@
+CREATE TABLE event_types (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(60) NOT NULL,
+  -- One of 'direct_action', 'community' or 'none'.
+  mpi_category VARCHAR(20) NOT NULL DEFAULT 'none',
+  -- Leaderboard points for attending an event of this type.
+  points INTEGER NOT NULL DEFAULT '1',
+  UNIQUE (name)
+);
+
+INSERT INTO event_types (name, mpi_category) VALUES
+  ('Action', 'direct_action'),
+  ('Campaign Action', 'direct_action'),
+  ('Circle', 'community'),
+  ('Community', 'community'),
+  ('Connection', 'none'),
+  ('Frontline Surveillance', 'direct_action'),
+  ('Meeting', 'none'),
+  ('Outreach', 'direct_action'),
+  ('Sanctuary', 'direct_action'),
+  ('Training', 'community');
+
+-- Add any other types that existing events use, so that admins can
+-- see and classify them.
+INSERT IGNORE INTO event_types (name)
+SELECT DISTINCT event_type FROM events;
@@ -0,0 +1,8 @@
+-- Lets events be linked to the group whose meeting they were.
+
+ALTER TABLE events
+  ADD COLUMN group_id INTEGER NOT NULL DEFAULT '0' AFTER survey_sent,
+  ADD INDEX (group_id, date);
+
+ALTER TABLE archived_events
+  ADD COLUMN group_id INTEGER NOT NULL DEFAULT '0' AFTER survey_sent;
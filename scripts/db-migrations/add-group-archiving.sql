@@ -0,0 +1,6 @@
+-- Lets groups be archived instead of deleted.
+
+ALTER TABLE activist_groups
+  ADD COLUMN archived TINYINT(1) NOT NULL DEFAULT '0' AFTER lng,
+  ADD COLUMN archived_at TIMESTAMP NULL DEFAULT NULL AFTER archived,
+  ADD COLUMN archived_by_email VARCHAR(80) NOT NULL DEFAULT '' AFTER archived_at;
@@ -0,0 +1,21 @@
+-- Stores group coordinates as numbers so groups can be matched to
+-- activists by distance, and caches geocoded activist addresses.
+
+ALTER TABLE activist_groups
+  ADD COLUMN lat DOUBLE NULL DEFAULT NULL AFTER coords,
+  ADD COLUMN lng DOUBLE NULL DEFAULT NULL AFTER lat;
+
+-- Coordinates are formatted as "lat, lng".
+UPDATE activist_groups
+SET
+  lat = CAST(TRIM(SUBSTRING_INDEX(coords, ',', 1)) AS DECIMAL(10, 7)),
+  lng = CAST(TRIM(SUBSTRING_INDEX(coords, ',', -1)) AS DECIMAL(10, 7))
+WHERE coords REGEXP '^ *-?[0-9.]+ *, *-?[0-9.]+ *$';
+
+CREATE TABLE geocoded_addresses (
+  address VARCHAR(255) PRIMARY KEY,
+  found TINYINT(1) NOT NULL,
+  lat DOUBLE NOT NULL DEFAULT '0',
+  lng DOUBLE NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
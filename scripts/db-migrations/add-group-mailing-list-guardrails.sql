@@ -0,0 +1,7 @@
+-- Lets admins set the mailing list guardrails of each working group
+-- and committee, like they can for the lists in mailing_lists.
+
+ALTER TABLE activist_groups
+  ADD COLUMN max_removals INTEGER NOT NULL DEFAULT '10' AFTER max_leads,
+  ADD COLUMN max_remove_percent INTEGER NOT NULL DEFAULT '50' AFTER max_removals,
+  ADD COLUMN allow_empty TINYINT(1) NOT NULL DEFAULT '0' AFTER max_remove_percent;
@@ -0,0 +1,39 @@
+-- Replaces the point_person and non_member_on_mailing_list flags with
+-- a role, and limits how many leads a group can have.
+
+ALTER TABLE activist_group_members
+  ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member' AFTER activist_id;
+
+UPDATE activist_group_members SET role = 'lead' WHERE point_person = 1;
+UPDATE activist_group_members SET role = 'mailing_list_only'
+WHERE point_person = 0 AND non_member_on_mailing_list = 1;
+
+ALTER TABLE activist_group_members
+  DROP COLUMN point_person,
+  DROP COLUMN non_member_on_mailing_list;
+
+-- Existing groups keep however many point people they have.
+ALTER TABLE activist_groups
+  ADD COLUMN max_leads INTEGER NOT NULL DEFAULT '1' AFTER archived_by_email;
+
+UPDATE activist_groups g
+SET max_leads = GREATEST(1, (
+  SELECT count(*)
+  FROM activist_group_members gm
+  WHERE gm.group_id = g.id AND gm.role = 'lead'));
+
+-- Activists only on the mailing list were recorded as members when
+-- membership history was backfilled.
+DELETE h FROM activist_group_memberships h
+JOIN activist_group_members gm
+  ON gm.group_id = h.group_id AND gm.activist_id = h.activist_id
+WHERE gm.role = 'mailing_list_only'
+  AND h.started IS NULL
+  AND h.ended IS NULL;
+
+UPDATE activist_group_memberships h
+JOIN activist_group_members gm
+  ON gm.group_id = h.group_id AND gm.activist_id = h.activist_id
+SET h.ended = now()
+WHERE gm.role = 'mailing_list_only'
+  AND h.ended IS NULL;
@@ -0,0 +1,20 @@
+-- Records when activists joined and left groups.
+
+CREATE TABLE activist_group_memberships (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  group_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  -- NULL if the activist joined before memberships were recorded.
+  started TIMESTAMP NULL DEFAULT NULL,
+  -- NULL while the activist is still a member.
+  ended TIMESTAMP NULL DEFAULT NULL,
+  added_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  removed_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  INDEX (group_id, activist_id),
+  INDEX (activist_id)
+);
+
+-- Current members joined at an unknown time.
+INSERT INTO activist_group_memberships (group_id, activist_id)
+SELECT group_id, activist_id
+FROM activist_group_members;
@@ -0,0 +1,18 @@
+-- Guardrails that hold mailing list changes for admin approval when a
+-- sync would remove too many members.
+
+ALTER TABLE mailing_lists
+  ADD COLUMN max_removals INTEGER NOT NULL DEFAULT '10',
+  ADD COLUMN max_remove_percent INTEGER NOT NULL DEFAULT '50',
+  ADD COLUMN allow_empty TINYINT(1) NOT NULL DEFAULT '0';
+
+CREATE TABLE mailing_list_holds (
+  list_email VARCHAR(100) PRIMARY KEY,
+  held DATETIME NOT NULL,
+  reason VARCHAR(200) NOT NULL,
+  -- pending, approved or rejected.
+  status VARCHAR(20) NOT NULL,
+  -- Newline-separated emails.
+  additions TEXT NOT NULL,
+  removals TEXT NOT NULL
+);
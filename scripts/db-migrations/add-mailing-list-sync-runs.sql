@@ -0,0 +1,30 @@
+-- Records what each pass of the mailing lists sync did.
+
+CREATE TABLE mailing_list_sync_runs (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  started TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished TIMESTAMP NULL DEFAULT NULL,
+  -- Empty for scheduled runs.
+  triggered_by_email VARCHAR(80) NOT NULL DEFAULT '',
+  failed TINYINT(1) NOT NULL DEFAULT '0',
+  INDEX (started)
+);
+
+CREATE TABLE mailing_list_sync_lists (
+  run_id INTEGER NOT NULL,
+  list_email VARCHAR(100) NOT NULL,
+  failed TINYINT(1) NOT NULL DEFAULT '0',
+  UNIQUE (run_id, list_email)
+);
+
+CREATE TABLE mailing_list_sync_items (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  run_id INTEGER NOT NULL,
+  list_email VARCHAR(100) NOT NULL,
+  -- add, remove, fail or skip.
+  action VARCHAR(10) NOT NULL,
+  member_email VARCHAR(100) NOT NULL DEFAULT '',
+  activist_name VARCHAR(80) NOT NULL DEFAULT '',
+  reason TEXT NOT NULL,
+  INDEX (run_id, list_email)
+);
@@ -0,0 +1,15 @@
+-- Stores how many activists each sync run skipped, instead of keeping
+-- every skipped activist of every run.
+
+ALTER TABLE mailing_list_sync_lists
+  ADD COLUMN skipped INTEGER NOT NULL DEFAULT '0' AFTER failed;
+
+UPDATE mailing_list_sync_lists l
+SET skipped = (
+  SELECT count(*)
+  FROM mailing_list_sync_items i
+  WHERE i.run_id = l.run_id AND i.list_email = l.list_email AND i.action = 'skip');
+
+DELETE FROM mailing_list_sync_items
+WHERE action = 'skip'
+  AND run_id <> (SELECT id FROM (SELECT max(id) AS id FROM mailing_list_sync_runs) latest);
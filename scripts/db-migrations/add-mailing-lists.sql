@@ -0,0 +1,33 @@
+-- Moves the mailing lists that were hard-coded in the sync into the
+-- database.
+
+CREATE TABLE mailing_lists (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  email VARCHAR(100) NOT NULL,
+  description TEXT NOT NULL,
+  -- activist_level, group_members, group_leads, group_emails or
+  -- manual. See model/mailing_lists.go for what rule_value holds.
+  rule_type VARCHAR(20) NOT NULL,
+  rule_value TEXT NOT NULL,
+  UNIQUE (email)
+);
+
+CREATE TABLE mailing_list_overrides (
+  list_id INTEGER NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  -- 1 if the email is always on the list, 0 if it never is.
+  include TINYINT(1) NOT NULL,
+  UNIQUE (list_id, email)
+);
+
+INSERT INTO mailing_lists (email, description, rule_type, rule_value) VALUES
+  ('chaptermembers@directactioneverywhere.com', 'Chapter members and organizers', 'activist_level', 'Organizer,Chapter Member'),
+  ('sfbay-organizers@directactioneverywhere.com', 'Organizers', 'activist_level', 'Organizer'),
+  ('circlehosts@directactioneverywhere.com', 'Leads of all circles', 'group_leads', 'circle'),
+  ('all-working-groups@directactioneverywhere.com', 'The lists of all working groups and committees', 'group_emails', 'working_group,committee');
+
+-- The owner of the list, who approves messages.
+INSERT INTO mailing_list_overrides (list_id, email, include)
+SELECT id, 'almira@directactioneverywhere.com', 1
+FROM mailing_lists
+WHERE email = 'all-working-groups@directactioneverywhere.com';
@@ -0,0 +1,16 @@
+CREATE TABLE mpi_snapshots (
+  -- Formatted as YYYYMM.
+  month INTEGER PRIMARY KEY,
+  mpi INTEGER NOT NULL,
+  active_chapter_members INTEGER NOT NULL,
+  -- Whether the snapshot was reconstructed after the month closed.
+  backfilled TINYINT(1) NOT NULL DEFAULT '0',
+  created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE mpi_snapshot_levels (
+  month INTEGER NOT NULL,
+  activist_level VARCHAR(40) NOT NULL,
+  activists INTEGER NOT NULL,
+  PRIMARY KEY (month, activist_level)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE wallboard_tokens (
+  token VARCHAR(64) PRIMARY KEY,
+  name VARCHAR(80) NOT NULL,
+  -- Chapter whose upcoming events are shown, or 0 for online events.
+  page_id BIGINT NOT NULL DEFAULT '0',
+  created TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
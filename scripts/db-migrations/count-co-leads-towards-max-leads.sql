@@ -0,0 +1,13 @@
+-- Co-leads now count towards a group's maximum number of leads.
+-- Existing groups keep however many leads and co-leads they have.
+
+UPDATE activist_groups g
+SET max_leads = (
+  SELECT count(*)
+  FROM activist_group_members gm
+  WHERE gm.group_id = g.id AND gm.role IN ('lead', 'co_lead'))
+WHERE g.max_leads != 0
+  AND g.max_leads < (
+    SELECT count(*)
+    FROM activist_group_members gm
+    WHERE gm.group_id = g.id AND gm.role IN ('lead', 'co_lead'));
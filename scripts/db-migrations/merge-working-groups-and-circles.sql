@@ -0,0 +1,50 @@
+-- Moves working groups, committees and circles into one set of group
+-- tables.
+
+CREATE TABLE activist_groups (
+  id INTEGER PRIMARY KEY AUTO_INCREMENT,
+  name VARCHAR(60) NOT NULL,
+  -- working_group, committee or circle.
+  type VARCHAR(20) NOT NULL,
+  group_email VARCHAR(100) NOT NULL,
+  visible TINYINT(1) NOT NULL DEFAULT '0',
+  description TEXT NOT NULL,
+  meeting_time TEXT NOT NULL,
+  meeting_location TEXT NOT NULL,
+  coords TEXT NOT NULL,
+  UNIQUE (type, name)
+);
+
+CREATE TABLE activist_group_members (
+  group_id INTEGER NOT NULL,
+  activist_id INTEGER NOT NULL,
+  point_person TINYINT NOT NULL DEFAULT '0',
+  non_member_on_mailing_list TINYINT NOT NULL DEFAULT '0',
+  UNIQUE (group_id, activist_id),
+  INDEX (activist_id)
+);
+
+-- Working groups keep their IDs, since they're used in links like
+-- /event/list?working_group_id=.
+INSERT INTO activist_groups (id, name, type, group_email, visible, description, meeting_time, meeting_location, coords)
+SELECT id, name, IF(type = 2, 'committee', 'working_group'), group_email, visible, description, meeting_time, meeting_location, coords
+FROM working_groups;
+
+INSERT INTO activist_group_members (group_id, activist_id, point_person, non_member_on_mailing_list)
+SELECT working_group_id, activist_id, point_person, non_member_on_mailing_list
+FROM working_group_members;
+
+INSERT INTO activist_groups (name, type, group_email, visible, description, meeting_time, meeting_location, coords)
+SELECT name, 'circle', group_email, visible, description, meeting_time, meeting_location, coords
+FROM circles;
+
+INSERT INTO activist_group_members (group_id, activist_id, point_person, non_member_on_mailing_list)
+SELECT g.id, m.activist_id, m.point_person, m.non_member_on_mailing_list
+FROM circle_members m
+JOIN circles c ON c.id = m.circle_id
+JOIN activist_groups g ON g.type = 'circle' AND g.name = c.name;
+
+DROP TABLE working_group_members;
+DROP TABLE working_groups;
+DROP TABLE circle_members;
+DROP TABLE circles;
@@ -0,0 +1,63 @@
+// Runs one pass of the mailing lists sync, so that changes can be
+// previewed before they go live:
+//
+//	go run ./scripts/sync_mailing_lists --provider=dry-run
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/dxe/adb/config"
+	"github.com/dxe/adb/mailinglist_sync"
+	"github.com/dxe/adb/model"
+)
+
+func main() {
+	providerName := flag.String("provider", "dry-run", "google to change the Google Groups, dry-run to only log the changes to them, or fake to sync to empty in-memory lists")
+	flag.Parse()
+
+	var provider mailinglist_sync.MailingListProvider
+	var fake *mailinglist_sync.FakeProvider
+	switch *providerName {
+	case "google", "dry-run":
+		google, err := mailinglist_sync.NewGoogleProvider()
+		if err != nil {
+			log.Fatal(err)
+		}
+		provider = google
+		if *providerName == "dry-run" {
+			provider = mailinglist_sync.NewDryRunProvider(google)
+		}
+	case "fake":
+		fake = mailinglist_sync.NewFakeProvider(nil)
+		provider = fake
+	default:
+		log.Fatalf("Unknown provider: %s", *providerName)
+	}
+
+	db := model.NewDB(config.DBDataSource())
+	defer db.Close()
+
+	// Only live runs are recorded, so that previews don't show up
+	// as runs on the admin page.
+	err := mailinglist_sync.SyncMailingLists(db, provider, mailinglist_sync.SyncOptions{
+		Record: *providerName == "google",
+	})
+
+	if fake != nil {
+		for _, list := range fake.Lists() {
+			fmt.Println(list)
+			for _, m := range fake.Members(list) {
+				fmt.Println("  " + m)
+			}
+		}
+	}
+
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+}
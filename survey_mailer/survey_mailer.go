@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dxe/adb/config"
+	"github.com/dxe/adb/metrics"
 	"github.com/dxe/adb/model"
 	"github.com/jmoiron/sqlx"
 	"github.com/sourcegraph/go-ses"
@@ -64,7 +65,7 @@ func sendEmail(to string, subject string, bodyText string, bodyHtml string) erro
 	return nil
 }
 
-func bulkSendEmails(event model.Event, subject string, bodyText string, bodyHtml string) {
+func bulkSendEmails(run *metrics.JobRun, event model.Event, subject string, bodyText string, bodyHtml string) {
 	var missingEmails []string
 	var sendingErrors []string
 	for i, recipient := range event.Attendees {
@@ -87,7 +88,10 @@ func bulkSendEmails(event model.Event, subject string, bodyText string, bodyHtml
 		err := sendEmail(receipientEmail, subject, bodyText, bodyHtml)
 		if err != nil {
 			sendingErrors = append(sendingErrors, fmt.Sprintf("%v [%v] %v", recipient, event.AttendeeEmails[i], err))
+			run.Fail()
+			continue
 		}
+		run.AddItems(1)
 	}
 	sendMissingEmail(event.EventName, missingEmails, sendingErrors)
 }
@@ -103,7 +107,7 @@ func updateSurveyStatus(db *sqlx.DB, eventId int) {
 	}
 }
 
-func survey(db *sqlx.DB, surveyOptions SurveyOptions) {
+func survey(run *metrics.JobRun, db *sqlx.DB, surveyOptions SurveyOptions) {
 	log.Println("Looking for", surveyOptions.SurveyType, "events on", surveyOptions.QueryDate)
 
 	// Get events matching query that that haven't had surveys sent yet
@@ -116,6 +120,7 @@ func survey(db *sqlx.DB, surveyOptions SurveyOptions) {
 	})
 	if err != nil {
 		log.Printf("Failed to get events: %v", err)
+		run.Fail()
 		return
 	}
 
@@ -138,7 +143,7 @@ func survey(db *sqlx.DB, surveyOptions SurveyOptions) {
 		log.Println("Sending", surveyOptions.SurveyType, "survey for event:", event.EventName)
 
 		// send all emails, including "missing" email
-		bulkSendEmails(event, subject, bodyText, bodyHtml)
+		bulkSendEmails(run, event, subject, bodyText, bodyHtml)
 
 		// update survey sent status to 1 (true)
 		updateSurveyStatus(db, event.ID)
@@ -146,9 +151,12 @@ func survey(db *sqlx.DB, surveyOptions SurveyOptions) {
 }
 
 func surveyMailerWrapper(db *sqlx.DB) {
+	run := metrics.StartJobRun("survey_mailer")
+	defer run.Finish()
 	defer func() {
 		if r := recover(); r != nil {
 			log.Println("Recovered from panic in survey mailer", r)
+			run.Fail()
 		}
 	}()
 
@@ -168,7 +176,7 @@ func surveyMailerWrapper(db *sqlx.DB) {
 	}
 
 	// send protest, sanctuary, & training surveys daily
-	survey(db, SurveyOptions{
+	survey(run, db, SurveyOptions{
 		SurveyType:     "protest",
 		QueryDate:      yesterday,
 		QueryEventType: "%Action",
@@ -177,7 +185,7 @@ func surveyMailerWrapper(db *sqlx.DB) {
 		BodyHtml:       `<p>Thank you for taking part in direct action! Please <a href="https://docs.google.com/forms/d/e/1FAIpQLScfrPtPxmYAroODhBkwUGq753JPykYKNdosg4gUR_SRng8BRQ/viewform?usp=pp_url&entry.466557185=LINK_PARAM">click here</a> to take a quick survey.</p><p>If you captured any photos or videos, please upload them <a href="http://dxe.io/upload">here</a>.</p>`,
 		LinkParam:      "name",
 	})
-	survey(db, SurveyOptions{
+	survey(run, db, SurveyOptions{
 		SurveyType:     "sanctuary",
 		QueryDate:      yesterday,
 		QueryEventType: "Sanctuary",
@@ -199,7 +207,7 @@ func surveyMailerWrapper(db *sqlx.DB) {
 
 	// only send meetup & popup surveys on sunday
 	if weekday == 0 {
-		survey(db, SurveyOptions{
+		survey(run, db, SurveyOptions{
 			SurveyType:     "meetup",
 			QueryDate:      yesterday,
 			QueryEventType: "Community",
@@ -208,7 +216,7 @@ func surveyMailerWrapper(db *sqlx.DB) {
 			BodyHtml:       `<p>Thank you for attending the meetup! Please <a href="https://docs.google.com/forms/d/e/1FAIpQLSfV0smO8sQo1ch-rlX7g9Oz4t_2d3fjGytwrE_yJ8Ez9uLSZQ/viewform?usp=pp_url&entry.1369832182=LINK_PARAM">click here</a> to provide feedback which will help us in planning future events.</p>`,
 			LinkParam:      "date",
 		})
-		survey(db, SurveyOptions{
+		survey(run, db, SurveyOptions{
 			SurveyType:     "popup",
 			QueryDate:      yesterday,
 			QueryEventType: "Community",
@@ -221,7 +229,7 @@ func surveyMailerWrapper(db *sqlx.DB) {
 
 	// only send chapter mtg surveys on monday
 	if weekday == 1 {
-		survey(db, SurveyOptions{
+		survey(run, db, SurveyOptions{
 			SurveyType:     "chapter meeting",
 			QueryDate:      yesterday,
 			QueryEventType: "",